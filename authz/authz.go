@@ -0,0 +1,49 @@
+package authz
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OwnerField is the bson field that stores the owner of a document, in every collection.
+const (
+	OwnerField       = "userId"
+	contextUserIDKey = "userID"
+)
+
+// GroupMemberField stores the IDs of the users that belong to a group besides its owner.
+const GroupMemberField = "members.userId"
+
+// UserID returns the ID of the authenticated user that the authorization middleware put in the context.
+func UserID(ctx context.Context) primitive.ObjectID {
+	userID, _ := ctx.Value(contextUserIDKey).(primitive.ObjectID)
+	return userID
+}
+
+// OwnedBy scopes the filter to documents owned by the user in the context.
+// Every mutating DAL call goes through it, so a document of another user is simply not found,
+// which the handlers report as 404 without revealing that the document exists.
+func OwnedBy(ctx context.Context, ownerField string, filter bson.M) bson.M {
+	scoped := bson.M{}
+	for key, value := range filter {
+		scoped[key] = value
+	}
+	scoped[ownerField] = UserID(ctx)
+	return scoped
+}
+
+// OwnedByID returns a filter matching the document with the given ID only if the user in the context owns it.
+func OwnedByID(ctx context.Context, ownerField string, id primitive.ObjectID) bson.M {
+	return OwnedBy(ctx, ownerField, bson.M{"_id": id})
+}
+
+// MemberOfGroups returns a filter matching the groups the user in the context owns or is a member of.
+// Members can see what is shared with a group, only the owner can change it.
+func MemberOfGroups(ctx context.Context) bson.M {
+	userID := UserID(ctx)
+	return bson.M{"$or": bson.A{
+		bson.M{OwnerField: userID},
+		bson.M{GroupMemberField: userID},
+	}}
+}
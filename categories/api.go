@@ -1,14 +1,19 @@
 package categories
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"gifmanager-backend/authz"
 	"gifmanager-backend/dal"
 	"gifmanager-backend/httputil"
+	"gifmanager-backend/shares"
 	"github.com/gorilla/mux"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"net/http"
+	"net/url"
+	"strconv"
 )
 
 type Api struct {
@@ -32,6 +37,10 @@ func (api Api) InitializeEndpoints(route *mux.Router) {
 		Path("/categories/{id}").
 		Methods(http.MethodPut).
 		Handler(http.HandlerFunc(api.UpdateCategoryByIdHandler))
+	route.
+		Path("/categories/{id}").
+		Methods(http.MethodPatch).
+		Handler(http.HandlerFunc(api.PatchCategoryByIdHandler))
 	route.
 		Path("/categories/{id}").
 		Methods(http.MethodDelete).
@@ -40,41 +49,63 @@ func (api Api) InitializeEndpoints(route *mux.Router) {
 		Path("/categories").
 		Methods(http.MethodGet).
 		Handler(http.HandlerFunc(api.GetCategoriesHandler))
+	// registered before /categories/{id}, otherwise "gifs" would be taken for an id
 	route.
 		Path("/categories/gifs").
 		Methods(http.MethodGet).
 		Handler(http.HandlerFunc(api.GetGifsByCategory))
+	route.
+		Path("/categories/{id}").
+		Methods(http.MethodGet).
+		Handler(http.HandlerFunc(api.GetCategoryByIdHandler))
 }
 
+// query parameters of DELETE /categories/{id}
+const (
+	DeleteModeParam = "mode"
+	ReassignToParam = "reassignTo"
+)
+
+// query parameter of GET /categories/gifs, the number of gifs embedded in every category
+const (
+	GifsPerCategoryParam   = "gifsPerCategory"
+	DefaultGifsPerCategory = 10
+	MaxGifsPerCategory     = 100
+)
+
+// what happens to the gifs of a deleted category
+const (
+	DeleteModeForbid   = "forbid"
+	DeleteModeCascade  = "cascade"
+	DeleteModeReassign = "reassign"
+)
+
 func (api Api) CreateCategoryHandler(writer http.ResponseWriter, request *http.Request) {
 	ctx := request.Context()
 	userID := ctx.Value("userID")
 
 	var categoryRequest CategoryRequest
-	if decodeErr := json.NewDecoder(request.Body).
-		Decode(&categoryRequest); decodeErr != nil {
-
-		fmt.Println(decodeErr.Error())
-		httputil.WriteHttpError(writer, http.StatusBadRequest, fmt.Sprintf("error while decoding the request: %s", decodeErr.Error()))
+	if decodeErr := httputil.DecodeJSON(writer, request, &categoryRequest); decodeErr != nil {
+		httputil.WriteError(writer, decodeErr)
 		return
 	}
 
 	category := categoryRequest.ToModel()
 	category.ID = primitive.NewObjectID()
 	category.UserId = userID.(primitive.ObjectID)
+	category.Version = 1
 
 	if _, errInsert := api.Dal.Insert(ctx, dal.CollCategories, []any{category}); errInsert != nil {
 		fmt.Println(errInsert.Error())
-		httputil.WriteHttpError(writer, http.StatusInternalServerError, "error encountered on inserting the category")
+		httputil.WriteError(writer, ErrInsertingCategory)
 		return
 	}
 
 	dto := category.ToDto()
+	writer.Header().Set("ETag", httputil.VersionETag(category.Version))
 	if errEncode := json.NewEncoder(writer).Encode(&dto); errEncode != nil {
-		writer.WriteHeader(http.StatusInternalServerError)
-
 		fmt.Println(errEncode.Error())
-		httputil.WriteHttpError(writer, http.StatusInternalServerError, "error encountered on encoding the category")
+		httputil.WriteError(writer, ErrEncodingCategories)
 		return
 	}
 
@@ -86,140 +117,418 @@ func (api Api) UpdateCategoryByIdHandler(writer http.ResponseWriter, request *ht
 
 	id := mux.Vars(request)["id"]
 
-	if _, errObjId := primitive.ObjectIDFromHex(id); errObjId != nil {
-		httputil.WriteHttpError(writer, http.StatusBadRequest, fmt.Sprintf("invalid id specified: %s", id))
+	categoryID, errObjId := primitive.ObjectIDFromHex(id)
+	if errObjId != nil {
+		httputil.WriteError(writer, httputil.ErrInvalidID.WithMessagef(ErrInvalidIDFmt, id))
 		return
 	}
 
 	var categoryRequest CategoryRequest
-	if decodeErr := json.NewDecoder(request.Body).Decode(&categoryRequest); decodeErr != nil {
-		httputil.WriteHttpError(writer, http.StatusInternalServerError, fmt.Sprintf("error while decoding the request: %s", decodeErr.Error()))
+	if decodeErr := httputil.DecodeJSON(writer, request, &categoryRequest); decodeErr != nil {
+		httputil.WriteError(writer, decodeErr)
 		return
 	}
 
 	category := categoryRequest.ToModel()
 
-	update := bson.M{"$set": category}
-	result, errUpdating := api.Dal.UpdateByID(ctx, dal.CollCategories, id, update)
+	// only the requested fields are set, the owner and the gif count are kept
+	update := bson.M{"$set": bson.M{"name": category.Name}}
+	updated, err := api.updateCategory(ctx, categoryID, httputil.ParseIfMatch(request), update)
+	if err != nil {
+		httputil.WriteError(writer, err)
+		return
+	}
 
-	if errUpdating != nil {
-		fmt.Println(errUpdating)
-		httputil.WriteHttpError(writer, http.StatusInternalServerError, fmt.Sprintf("error while updating category"))
+	writer.Header().Set("ETag", httputil.VersionETag(updated.Version))
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// PatchCategoryByIdHandler applies a JSON merge patch to the category and returns the updated category.
+func (api Api) PatchCategoryByIdHandler(writer http.ResponseWriter, request *http.Request) {
+	ctx := request.Context()
+	id := mux.Vars(request)["id"]
+
+	categoryID, errObjId := primitive.ObjectIDFromHex(id)
+	if errObjId != nil {
+		httputil.WriteError(writer, httputil.ErrInvalidID.WithMessagef(ErrInvalidIDFmt, id))
 		return
 	}
 
-	if result.MatchedCount == 0 {
-		httputil.WriteHttpError(writer, http.StatusNotFound, fmt.Sprintf("category with id %s does not exist", id))
+	var patch CategoryPatch
+	if decodeErr := httputil.DecodeMergePatch(writer, request, &patch); decodeErr != nil {
+		httputil.WriteError(writer, decodeErr)
 		return
 	}
-	writer.WriteHeader(http.StatusNoContent)
+
+	patched, err := api.updateCategory(ctx, categoryID, httputil.ParseIfMatch(request), patch.ToUpdate())
+	if err != nil {
+		httputil.WriteError(writer, err)
+		return
+	}
+
+	dto := patched.ToDto()
+	writer.Header().Set("ETag", httputil.VersionETag(patched.Version))
+	writer.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(writer).Encode(&dto); err != nil {
+		fmt.Println(err)
+		httputil.WriteError(writer, ErrEncodingCategories)
+	}
+}
+
+// GetCategoryByIdHandler returns the category with the ETag of its version.
+func (api Api) GetCategoryByIdHandler(writer http.ResponseWriter, request *http.Request) {
+	ctx := request.Context()
+	id := mux.Vars(request)["id"]
+
+	categoryID, errObjId := primitive.ObjectIDFromHex(id)
+	if errObjId != nil {
+		httputil.WriteError(writer, httputil.ErrInvalidID.WithMessagef(ErrInvalidIDFmt, id))
+		return
+	}
+
+	category, err := api.findOwnedCategory(ctx, categoryID)
+	if err != nil {
+		httputil.WriteError(writer, err)
+		return
+	}
+
+	dto := category.ToDto()
+	if err := httputil.WriteVersionedJSON(writer, request, category.Version, &dto); err != nil {
+		fmt.Println(err)
+		httputil.WriteError(writer, ErrEncodingCategories)
+	}
 }
 
 func (api Api) GetCategoriesHandler(writer http.ResponseWriter, request *http.Request) {
 	ctx := request.Context()
 	userID := ctx.Value("userID").(primitive.ObjectID)
 
-
 	userIdFilter := bson.M{
 		"userId": userID,
 	}
 	findArgs := dal.NewFindArguments().
 		WithFilter(userIdFilter)
 
-	var categories []Category
+	categories := make(Categories, 0)
 	if err := api.Dal.Find(ctx, dal.CollCategories, *findArgs, &categories); err != nil {
 		fmt.Println(err)
-		httputil.WriteHttpError(writer, http.StatusInternalServerError, "error encountered while retrieving categories")
+		httputil.WriteError(writer, ErrFindingCategories)
 		return
 	}
 
-	writer.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(writer).Encode(categories); err != nil {
+	// the list has the same shape as a single category, without the owner and the version
+	if err := httputil.WriteJSONWithETag(writer, request, categories.ToDto()); err != nil {
 		fmt.Println(err)
-		httputil.WriteHttpError(writer, http.StatusInternalServerError, "error encountered on encoding categories")
-		return
+		httputil.WriteError(writer, ErrEncodingCategories)
 	}
-
 }
 
+// DeleteCategoryByIdHandler deletes the category and handles its gifs according to the mode query parameter:
+// forbid (the default) refuses to delete a category that still has gifs, cascade deletes the gifs with the category
+// and reassign moves them to the category given by reassignTo.
 func (api Api) DeleteCategoryByIdHandler(writer http.ResponseWriter, request *http.Request) {
 	ctx := request.Context()
 	id := mux.Vars(request)["id"]
 
 	categoryID, errObjId := primitive.ObjectIDFromHex(id)
 	if errObjId != nil {
-		httputil.WriteHttpError(writer, http.StatusBadRequest, fmt.Sprintf("invalid id specified: %s", id))
+		httputil.WriteError(writer, httputil.ErrInvalidID.WithMessagef(ErrInvalidIDFmt, id))
 		return
 	}
 
-	filter := bson.M{
-		"_id": categoryID,
+	mode, targetID, err := parseDeleteMode(request.URL.Query(), categoryID)
+	if err != nil {
+		httputil.WriteError(writer, err)
+		return
 	}
 
-	if _, err := api.Dal.Delete(ctx, dal.CollCategories, filter); err != nil {
-		fmt.Println(err)
-		httputil.WriteHttpError(writer, http.StatusInternalServerError, fmt.Sprintf("error encountered deleting the category"))
+	ifMatch := httputil.ParseIfMatch(request)
+	errTransaction := api.Dal.WithTransaction(ctx, func(txCtx context.Context) error {
+		// the gifs are only touched once it's known that the user owns the category
+		category, err := api.findOwnedCategory(txCtx, categoryID)
+		if err != nil {
+			return err
+		}
+		if !ifMatch.Matches(category.Version) {
+			return httputil.ErrPreconditionFailed
+		}
+
+		if err := api.releaseGifs(txCtx, categoryID, mode, targetID); err != nil {
+			return err
+		}
+
+		filter := ifMatch.AddToFilter(authz.OwnedByID(txCtx, authz.OwnerField, categoryID), "version")
+		result, err := api.Dal.Delete(txCtx, dal.CollCategories, filter)
+		if err != nil {
+			fmt.Println(err)
+			return ErrDeletingCategory
+		}
+		if result.DeletedCount == 0 {
+			return httputil.ErrPreconditionFailed
+		}
+
+		if err := shares.Unshare(txCtx, api.Dal, bson.M{shares.CategoryIDField: categoryID}); err != nil {
+			fmt.Println(err)
+			return ErrDeletingCategory
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		httputil.WriteError(writer, errTransaction)
 		return
 	}
 
 	writer.WriteHeader(http.StatusNoContent)
 }
 
+// updateCategory applies the update if the user owns the category and its version matches the precondition,
+// and returns the updated category. Every update increments the version, an empty update only checks the precondition.
+func (api Api) updateCategory(ctx context.Context, categoryID primitive.ObjectID, ifMatch httputil.IfMatch, update bson.M) (Category, error) {
+	if len(update) > 0 {
+		update["$inc"] = bson.M{"version": 1}
+		filter := ifMatch.AddToFilter(authz.OwnedByID(ctx, authz.OwnerField, categoryID), "version")
+		result, err := api.Dal.Update(ctx, dal.CollCategories, filter, update)
+		if err != nil {
+			fmt.Println(err)
+			return Category{}, ErrUpdatingCategory
+		}
+		if result.MatchedCount == 0 {
+			if _, err := api.findOwnedCategory(ctx, categoryID); err != nil {
+				return Category{}, err
+			}
+			return Category{}, httputil.ErrPreconditionFailed
+		}
+	}
+
+	updated, err := api.findOwnedCategory(ctx, categoryID)
+	if err != nil {
+		return Category{}, err
+	}
+	if len(update) == 0 && !ifMatch.Matches(updated.Version) {
+		return Category{}, httputil.ErrPreconditionFailed
+	}
+	return updated, nil
+}
+
+// findOwnedCategory returns the category if the user in the context owns it, otherwise ErrCategoryNotFound.
+func (api Api) findOwnedCategory(ctx context.Context, categoryID primitive.ObjectID) (Category, error) {
+	filter := authz.OwnedByID(ctx, authz.OwnerField, categoryID)
+	found := make(Categories, 0, 1)
+	if err := api.Dal.Find(ctx, dal.CollCategories, *dal.NewFindArguments().WithFilter(filter).WithLimit(1), &found); err != nil {
+		fmt.Println(err)
+		return Category{}, ErrFindingCategories
+	}
+	if len(found) == 0 {
+		return Category{}, ErrCategoryNotFound.WithMessagef(ErrCategoryNotFoundFmt, categoryID.Hex())
+	}
+	return found[0], nil
+}
+
+// releaseGifs removes the gifs from the category that is about to be deleted.
+func (api Api) releaseGifs(ctx context.Context, categoryID primitive.ObjectID, mode string, targetID primitive.ObjectID) error {
+	gifsFilter := authz.OwnedBy(ctx, authz.OwnerField, bson.M{"categoryId": categoryID})
+	gifCount, err := api.Dal.Count(ctx, dal.CollGifs, gifsFilter)
+	if err != nil {
+		fmt.Println(err)
+		return ErrDeletingCategory
+	}
+	if gifCount == 0 {
+		return nil
+	}
+
+	switch mode {
+	case DeleteModeCascade:
+		// the shares of the deleted gifs are removed with them, like when a single gif is deleted
+		gifIDs, err := api.findGifIDs(ctx, gifsFilter)
+		if err != nil {
+			fmt.Println(err)
+			return ErrDeletingCategory
+		}
+		if _, err := api.Dal.Delete(ctx, dal.CollGifs, gifsFilter); err != nil {
+			fmt.Println(err)
+			return ErrDeletingCategory
+		}
+		if err := shares.Unshare(ctx, api.Dal, bson.M{shares.GifIDField: bson.M{"$in": gifIDs}}); err != nil {
+			fmt.Println(err)
+			return ErrDeletingCategory
+		}
+	case DeleteModeReassign:
+		update := bson.M{"$inc": bson.M{"gifCount": gifCount}}
+		result, err := api.Dal.Update(ctx, dal.CollCategories, authz.OwnedByID(ctx, authz.OwnerField, targetID), update)
+		if err != nil {
+			fmt.Println(err)
+			return ErrDeletingCategory
+		}
+		if result.MatchedCount == 0 {
+			return ErrReassignCategoryNotFound.WithMessagef(ErrCategoryNotFoundFmt, targetID.Hex())
+		}
+
+		// moving the gifs changes them, so their ETags change too
+		moveGifs := bson.M{"$set": bson.M{"categoryId": targetID}, "$inc": bson.M{"version": 1}}
+		if _, err := api.Dal.Update(ctx, dal.CollGifs, gifsFilter, moveGifs, dal.UpdateAllMatching); err != nil {
+			fmt.Println(err)
+			return ErrDeletingCategory
+		}
+	default:
+		return ErrCategoryNotEmpty.WithMessagef(ErrCategoryNotEmptyFmt, categoryID.Hex(), gifCount)
+	}
+	return nil
+}
+
+// findGifIDs returns the IDs of the gifs matching the filter.
+func (api Api) findGifIDs(ctx context.Context, filter bson.M) (bson.A, error) {
+	var gifs []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	findArgs := dal.NewFindArguments().
+		WithFilter(filter).
+		WithProjection(dal.Projections{{FieldName: "_id"}})
+	if err := api.Dal.Find(ctx, dal.CollGifs, *findArgs, &gifs); err != nil {
+		return nil, err
+	}
+
+	ids := make(bson.A, 0, len(gifs))
+	for _, gif := range gifs {
+		ids = append(ids, gif.ID)
+	}
+	return ids, nil
+}
+
+// parseDeleteMode reads the mode and, for reassign, the category the gifs are moved to.
+func parseDeleteMode(query url.Values, categoryID primitive.ObjectID) (string, primitive.ObjectID, error) {
+	mode := query.Get(DeleteModeParam)
+	switch mode {
+	case "":
+		return DeleteModeForbid, primitive.NilObjectID, nil
+	case DeleteModeForbid, DeleteModeCascade:
+		return mode, primitive.NilObjectID, nil
+	case DeleteModeReassign:
+		targetID, err := primitive.ObjectIDFromHex(query.Get(ReassignToParam))
+		if err != nil {
+			return "", primitive.NilObjectID, httputil.ErrInvalidQuery.WithMessagef("%s must be the id of another category", ReassignToParam)
+		}
+		if targetID == categoryID {
+			return "", primitive.NilObjectID, httputil.ErrInvalidQuery.WithMessagef("the gifs can't be reassigned to the deleted category")
+		}
+		return mode, targetID, nil
+	}
+	return "", primitive.NilObjectID, httputil.ErrInvalidQuery.WithMessagef("%s must be one of %s, %s or %s", DeleteModeParam, DeleteModeForbid, DeleteModeCascade, DeleteModeReassign)
+}
+
+// GetGifsByCategory groups the user's gifs by category, the gifs without a category are grouped in an uncategorised bucket.
+// The groups are paged with page and pageSize and each group embeds at most gifsPerCategory of its gifs.
+// The filter query param filters the gifs before they are grouped.
 func (api Api) GetGifsByCategory(writer http.ResponseWriter, request *http.Request) {
 	ctx := request.Context()
 	userID := ctx.Value("userID").(primitive.ObjectID)
 
-	api.QueryParamsParser.LoadValues(request.URL.Query())
+	query, err := api.QueryParamsParser.Parse(request.URL.Query())
+	if err != nil {
+		httputil.WriteError(writer, err)
+		return
+	}
 
-	pipeline := make([]any, 0)
-	filter := []interface{}{bson.M{"userId": userID}}
-	if api.QueryParamsParser.HasFilter() {
-		queryFilter, err := api.QueryParamsParser.GetFilter()
-		if err != nil {
-			httputil.WriteHttpError(writer, http.StatusBadRequest, err.Error())
-			return
-		}
+	match := bson.M{"userId": userID}
+	if query.HasFilter() {
+		match = bson.M{"$and": bson.A{match, query.Filter}}
+	}
+	pagination := query.Pagination
 
-		filter = append(filter, queryFilter)
-		pipeline = append(pipeline, bson.M{
-			"$match": filter,
-		})
+	gifsPerCategory, err := parseGifsPerCategory(request.URL.Query().Get(GifsPerCategoryParam))
+	if err != nil {
+		httputil.WriteError(writer, err)
+		return
 	}
 
+	pipeline := append(getGifsByCategoriesPipeline(match, gifsPerCategory),
+		// the uncategorised bucket has no name, so it comes first
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}}}},
+		bson.M{"$skip": pagination.Skip()},
+		bson.M{"$limit": pagination.PageSize},
+	)
 	var gifsByCategory []GifsByCategory
-	pipeline = append(pipeline, getGifsByCategoriesPipeline(userID)...)
-
 	if err := api.Dal.Aggregate(ctx, dal.CollGifs, pipeline, &gifsByCategory); err != nil {
 		fmt.Println(err.Error())
-		httputil.WriteHttpError(writer, http.StatusInternalServerError, "error encountered while retrieving gifs by category")
+		httputil.WriteError(writer, ErrFindingGifsByCategory)
 		return
 	}
 
-	if err := json.NewEncoder(writer).Encode(gifsByCategory); err != nil {
+	var counted []struct {
+		Total int64 `bson:"total"`
+	}
+	if err := api.Dal.Aggregate(ctx, dal.CollGifs, countGifsByCategoriesPipeline(match), &counted); err != nil {
 		fmt.Println(err.Error())
-		httputil.WriteHttpError(writer, http.StatusInternalServerError, fmt.Sprintf("erron on encoding gifs"))
+		httputil.WriteError(writer, ErrFindingGifsByCategory)
 		return
 	}
-	writer.WriteHeader(http.StatusOK)
+	var total int64
+	if len(counted) > 0 {
+		total = counted[0].Total
+	}
+	httputil.WritePaginationHeaders(writer, request.URL, pagination, total)
+
+	dtos := make([]GifsByCategoryDto, 0, len(gifsByCategory))
+	for _, group := range gifsByCategory {
+		dtos = append(dtos, group.ToDto())
+	}
+	if err := httputil.WriteJSONWithETag(writer, request, dtos); err != nil {
+		fmt.Println(err.Error())
+		httputil.WriteError(writer, ErrEncodingGifsByCategory)
+	}
+}
+
+// parseGifsPerCategory reads how many gifs are embedded in every category.
+func parseGifsPerCategory(value string) (int, error) {
+	if value == "" {
+		return DefaultGifsPerCategory, nil
+	}
+	gifsPerCategory, err := strconv.Atoi(value)
+	if err != nil || gifsPerCategory < 1 || gifsPerCategory > MaxGifsPerCategory {
+		return 0, httputil.ErrInvalidQuery.WithMessagef("invalid %s: %s, it should be a number between 1 and %d", GifsPerCategoryParam, value, MaxGifsPerCategory)
+	}
+	return gifsPerCategory, nil
 }
 
-func getGifsByCategoriesPipeline(userID primitive.ObjectID) []any {
+// getCategorisedGifsPipeline pairs every gif matching the filter with the id and name of its category. The gifs
+// without a category or with a category that doesn't exist anymore get no categoryId, so they end up in the group
+// with a null _id. The looked up categories are projected away before anything is grouped.
+// The field names are the bson names of gifs.Gif and Category, the integration tests check that they still match.
+func getCategorisedGifsPipeline(match bson.M) []any {
 	return []any{
-		bson.M{"$match": bson.M{"user_id": userID}},
+		bson.M{"$match": match},
+		bson.M{"$project": bson.M{"_id": 0, "gif": "$$ROOT"}},
 		bson.M{"$lookup": bson.M{
-			"from":         "categories",
-			"localField":   "category_id",
+			"from":         dal.CollCategories,
+			"localField":   "gif.categoryId",
 			"foreignField": "_id",
 			"as":           "categories",
 		}},
-		bson.M{"$addFields": bson.M{
-			"category": bson.M{"$arrayElemAt": bson.A{"$categories", 0}},
-		}},
-		bson.M{"$group": bson.M{"_id": "$category_id", "gifs": bson.M{"$push": "$$ROOT"}, "name": bson.M{"$first": "$category.name"}}},
 		bson.M{"$project": bson.M{
-			"category_id": "$_id",
-			"gifs":        1,
-			"name":        1,
+			"gif":        1,
+			"categoryId": bson.M{"$arrayElemAt": bson.A{"$categories._id", 0}},
+			"name":       bson.M{"$arrayElemAt": bson.A{"$categories.name", 0}},
 		}},
 	}
 }
+
+// getGifsByCategoriesPipeline groups the categorised gifs, every group keeps only the first gifsPerCategory of its
+// gifs by _id.
+func getGifsByCategoriesPipeline(match bson.M, gifsPerCategory int) []any {
+	return append(getCategorisedGifsPipeline(match),
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "gif._id", Value: 1}}}},
+		bson.M{"$group": bson.M{
+			"_id":      "$categoryId",
+			"name":     bson.M{"$first": "$name"},
+			"gifCount": bson.M{"$sum": 1},
+			"gifs":     bson.M{"$firstN": bson.M{"input": "$gif", "n": gifsPerCategory}},
+		}},
+	)
+}
+
+// countGifsByCategoriesPipeline counts the groups of getGifsByCategoriesPipeline without collecting their gifs.
+func countGifsByCategoriesPipeline(match bson.M) []any {
+	return append(getCategorisedGifsPipeline(match),
+		bson.M{"$group": bson.M{"_id": "$categoryId"}},
+		bson.M{"$count": "total"},
+	)
+}
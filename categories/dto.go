@@ -1,25 +1,63 @@
 package categories
 
-import "gifmanager-backend/gifs"
+import (
+	"gifmanager-backend/gifs"
+	"gifmanager-backend/httputil"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const MaxCategoryNameLength = 50
 
 type CategoryRequest struct {
 	Name string `json:"name"`
 }
 
+func (c CategoryRequest) Validate() httputil.ValidationErrors {
+	var errs httputil.ValidationErrors
+	errs.Length("name", c.Name, 1, MaxCategoryNameLength)
+	return errs
+}
+
 func (c CategoryRequest) ToModel() Category {
 	return Category{
 		Name: c.Name,
 	}
 }
 
+// CategoryPatch is a JSON merge patch of a category, the name can be changed but not removed.
+type CategoryPatch struct {
+	Name httputil.Optional[string] `json:"name"`
+}
+
+func (p CategoryPatch) Validate() httputil.ValidationErrors {
+	var errs httputil.ValidationErrors
+	if p.Name.Set {
+		errs.Length("name", p.Name.Value, 1, MaxCategoryNameLength)
+	}
+	return errs
+}
+
+// ToUpdate translates the patch to an update that only touches the fields in the patch.
+func (p CategoryPatch) ToUpdate() bson.M {
+	update := bson.M{}
+	p.Name.AddToUpdate(update, "name")
+	return update
+}
+
 type CategoryDto struct {
 	ID        string `json:"id"`
 	Name      string `json:"name"`
 	GifsCount int    `json:"gifsCount"`
 }
 
+// UncategorisedName is the name of the group of gifs without a category.
+const UncategorisedName = "Uncategorised"
+
+// GifsByCategoryDto is a category with some of its gifs, GifCount is the number of all its gifs that match the filter.
 type GifsByCategoryDto struct {
-	CategoryID string       `json:"categoryId,omitempty"`
-	Name       string       `json:"name"`
-	Gifs       gifs.GifDtos `json:"gifs"`
+	CategoryID    string       `json:"categoryId,omitempty"`
+	Name          string       `json:"name"`
+	Uncategorised bool         `json:"uncategorised,omitempty"`
+	GifCount      int          `json:"gifCount"`
+	Gifs          gifs.GifDtos `json:"gifs"`
 }
@@ -0,0 +1,31 @@
+package categories
+
+import (
+	"gifmanager-backend/httputil"
+	"net/http"
+)
+
+const (
+	ErrInvalidIDFmt        = "invalid id specified: %s"
+	ErrCategoryNotFoundFmt = "category with id %s does not exist"
+	ErrCategoryNotEmptyFmt = "category with id %s still has %d gifs"
+)
+
+const (
+	CodeCategoryNotFound = "category_not_found"
+	CodeCategoryNotEmpty = "category_not_empty"
+)
+
+var (
+	ErrInsertingCategory      = httputil.NewError(http.StatusInternalServerError, httputil.CodeInternal, "error encountered on inserting the category")
+	ErrEncodingCategories     = httputil.NewError(http.StatusInternalServerError, httputil.CodeInternal, "error encountered on encoding categories")
+	ErrUpdatingCategory       = httputil.NewError(http.StatusInternalServerError, httputil.CodeInternal, "error while updating category")
+	ErrFindingCategories      = httputil.NewError(http.StatusInternalServerError, httputil.CodeInternal, "error encountered while retrieving categories")
+	ErrDeletingCategory       = httputil.NewError(http.StatusInternalServerError, httputil.CodeInternal, "error encountered deleting the category")
+	ErrFindingGifsByCategory  = httputil.NewError(http.StatusInternalServerError, httputil.CodeInternal, "error encountered while retrieving gifs by category")
+	ErrEncodingGifsByCategory = httputil.NewError(http.StatusInternalServerError, httputil.CodeInternal, "error encountered on encoding gifs")
+
+	ErrCategoryNotFound         = httputil.NewError(http.StatusNotFound, CodeCategoryNotFound, "category does not exist")
+	ErrReassignCategoryNotFound = httputil.NewError(http.StatusUnprocessableEntity, CodeCategoryNotFound, "the category the gifs are reassigned to does not exist")
+	ErrCategoryNotEmpty         = httputil.NewError(http.StatusConflict, CodeCategoryNotEmpty, "category still has gifs")
+)
@@ -0,0 +1,673 @@
+package integration_tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"gifmanager-backend/categories"
+	"gifmanager-backend/dal"
+	"gifmanager-backend/gifs"
+	"gifmanager-backend/httputil"
+	"gifmanager-backend/shares"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// insertCategory stores a category owned by ownerID and removes it after the test
+func insertCategory(t *testing.T, ownerID primitive.ObjectID) categories.Category {
+	category := categories.Category{
+		ID:       primitive.NewObjectID(),
+		Name:     t.Name(),
+		UserId:   ownerID,
+		GifCount: 3,
+	}
+	_, errInsert := mongoDal.Insert(context.Background(), dal.CollCategories, []any{category})
+	require.Nil(t, errInsert)
+
+	t.Cleanup(func() {
+		mongoDal.Delete(context.Background(), dal.CollCategories, bson.M{"userId": ownerID})
+	})
+	return category
+}
+
+func newCategoryRequest(method string, userID primitive.ObjectID, categoryID primitive.ObjectID, body any) *http.Request {
+	bts, _ := json.Marshal(body)
+	request := httptest.NewRequest(method, "/categories", bytes.NewReader(bts))
+	request = request.WithContext(context.WithValue(context.Background(), "userID", userID))
+	return mux.SetURLVars(request, map[string]string{
+		"id": categoryID.Hex(),
+	})
+}
+
+func TestUpdateCategoryByIdHandler_ExpectedNoContent(t *testing.T) {
+	// 1.ARRANGE
+	ownerID := primitive.NewObjectID()
+	category := insertCategory(t, ownerID)
+	request := newCategoryRequest(http.MethodPut, ownerID, category.ID, categories.CategoryRequest{Name: "renamed"})
+	responseRecorder := httptest.NewRecorder()
+	api := categories.NewApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+	// 2.ACT
+	api.UpdateCategoryByIdHandler(responseRecorder, request)
+
+	// 3.ASSERT
+	assert.Equal(t, http.StatusNoContent, responseRecorder.Code)
+
+	// the name changes, but the owner and the gif count stay the same
+	var dbCategory categories.Category
+	require.Nil(t, mongoDal.FindByID(context.Background(), dal.CollCategories, category.ID.Hex(), &dbCategory))
+	assert.Equal(t, "renamed", dbCategory.Name)
+	assert.Equal(t, ownerID, dbCategory.UserId)
+	assert.Equal(t, category.GifCount, dbCategory.GifCount)
+}
+
+func TestUpdateCategoryByIdHandler_CategoryOfAnotherUser_ExpectedNotFound(t *testing.T) {
+	// 1.ARRANGE
+	category := insertCategory(t, primitive.NewObjectID())
+	request := newCategoryRequest(http.MethodPut, primitive.NewObjectID(), category.ID, categories.CategoryRequest{Name: "overwritten"})
+	responseRecorder := httptest.NewRecorder()
+	api := categories.NewApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+	// 2.ACT
+	api.UpdateCategoryByIdHandler(responseRecorder, request)
+
+	// 3.ASSERT
+	assert.Equal(t, http.StatusNotFound, responseRecorder.Code)
+
+	var dbCategory categories.Category
+	require.Nil(t, mongoDal.FindByID(context.Background(), dal.CollCategories, category.ID.Hex(), &dbCategory))
+	assert.Equal(t, category, dbCategory)
+}
+
+func TestDeleteCategoryByIdHandler_CategoryOfAnotherUser_ExpectedNotFound(t *testing.T) {
+	// 1.ARRANGE
+	category := insertCategory(t, primitive.NewObjectID())
+	request := newCategoryRequest(http.MethodDelete, primitive.NewObjectID(), category.ID, nil)
+	responseRecorder := httptest.NewRecorder()
+	api := categories.NewApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+	// 2.ACT
+	api.DeleteCategoryByIdHandler(responseRecorder, request)
+
+	// 3.ASSERT
+	assert.Equal(t, http.StatusNotFound, responseRecorder.Code)
+
+	var dbCategory categories.Category
+	require.Nil(t, mongoDal.FindByID(context.Background(), dal.CollCategories, category.ID.Hex(), &dbCategory))
+}
+
+func TestDeleteCategoryByIdHandler_ExpectedNoContent(t *testing.T) {
+	// 1.ARRANGE
+	ownerID := primitive.NewObjectID()
+	category := insertCategory(t, ownerID)
+	request := newCategoryRequest(http.MethodDelete, ownerID, category.ID, nil)
+	responseRecorder := httptest.NewRecorder()
+	api := categories.NewApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+	// 2.ACT
+	api.DeleteCategoryByIdHandler(responseRecorder, request)
+
+	// 3.ASSERT
+	assert.Equal(t, http.StatusNoContent, responseRecorder.Code)
+
+	var dbCategory categories.Category
+	require.NotNil(t, mongoDal.FindByID(context.Background(), dal.CollCategories, category.ID.Hex(), &dbCategory))
+}
+
+// insertGifs stores count gifs of the owner in the category and removes them after the test
+func insertGifs(t *testing.T, ownerID primitive.ObjectID, categoryID primitive.ObjectID, count int) {
+	documents := make([]any, 0, count)
+	for i := 0; i < count; i++ {
+		documents = append(documents, gifs.Gif{
+			ID:         primitive.NewObjectID(),
+			Name:       t.Name(),
+			URL:        "https://media.giphy.com/gif.gif",
+			UserId:     ownerID,
+			CategoryId: categoryID,
+		})
+	}
+	_, errInsert := mongoDal.Insert(context.Background(), dal.CollGifs, documents)
+	require.Nil(t, errInsert)
+
+	t.Cleanup(func() {
+		mongoDal.Delete(context.Background(), dal.CollGifs, bson.M{"userId": ownerID})
+	})
+}
+
+func countGifs(t *testing.T, categoryID primitive.ObjectID) int64 {
+	count, err := mongoDal.Count(context.Background(), dal.CollGifs, bson.M{"categoryId": categoryID})
+	require.Nil(t, err)
+	return count
+}
+
+func TestDeleteCategoryByIdHandler_CategoryWithGifs_ExpectedConflict(t *testing.T) {
+	// 1.ARRANGE
+	ownerID := primitive.NewObjectID()
+	category := insertCategory(t, ownerID)
+	insertGifs(t, ownerID, category.ID, 2)
+	request := newCategoryRequest(http.MethodDelete, ownerID, category.ID, nil)
+	responseRecorder := httptest.NewRecorder()
+	api := categories.NewApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+	// 2.ACT
+	api.DeleteCategoryByIdHandler(responseRecorder, request)
+
+	// 3.ASSERT
+	assert.Equal(t, http.StatusConflict, responseRecorder.Code)
+
+	var dbCategory categories.Category
+	require.Nil(t, mongoDal.FindByID(context.Background(), dal.CollCategories, category.ID.Hex(), &dbCategory))
+	assert.Equal(t, int64(2), countGifs(t, category.ID))
+}
+
+func TestDeleteCategoryByIdHandler_Cascade_ExpectedGifsDeleted(t *testing.T) {
+	// 1.ARRANGE
+	ownerID := primitive.NewObjectID()
+	category := insertCategory(t, ownerID)
+	insertGifs(t, ownerID, category.ID, 2)
+	request := newCategoryRequest(http.MethodDelete, ownerID, category.ID, nil)
+	request.URL.RawQuery = url.Values{categories.DeleteModeParam: {categories.DeleteModeCascade}}.Encode()
+	responseRecorder := httptest.NewRecorder()
+	api := categories.NewApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+	// 2.ACT
+	api.DeleteCategoryByIdHandler(responseRecorder, request)
+
+	// 3.ASSERT
+	assert.Equal(t, http.StatusNoContent, responseRecorder.Code)
+
+	var dbCategory categories.Category
+	require.NotNil(t, mongoDal.FindByID(context.Background(), dal.CollCategories, category.ID.Hex(), &dbCategory))
+	assert.Zero(t, countGifs(t, category.ID))
+}
+
+func TestDeleteCategoryByIdHandler_Cascade_ExpectedSharesOfGifsDeleted(t *testing.T) {
+	// 1.ARRANGE
+	ownerID := primitive.NewObjectID()
+	groupID := primitive.NewObjectID()
+	category := insertCategory(t, ownerID)
+	gif := gifs.Gif{ID: primitive.NewObjectID(), Name: t.Name(), URL: "https://media.giphy.com/gif.gif", UserId: ownerID, CategoryId: category.ID}
+	otherGif := gifs.Gif{ID: primitive.NewObjectID(), Name: t.Name(), URL: "https://media.giphy.com/gif.gif", UserId: ownerID}
+	_, errInsertGifs := mongoDal.Insert(context.Background(), dal.CollGifs, []any{gif, otherGif})
+	require.Nil(t, errInsertGifs)
+
+	gifShare := shares.Share{ID: primitive.NewObjectID(), UserId: ownerID, GroupId: groupID, GifId: gif.ID}
+	otherGifShare := shares.Share{ID: primitive.NewObjectID(), UserId: ownerID, GroupId: groupID, GifId: otherGif.ID}
+	_, errInsertShares := mongoDal.Insert(context.Background(), dal.CollShares, []any{gifShare, otherGifShare})
+	require.Nil(t, errInsertShares)
+	t.Cleanup(func() {
+		mongoDal.Delete(context.Background(), dal.CollGifs, bson.M{"userId": ownerID})
+		mongoDal.Delete(context.Background(), dal.CollShares, bson.M{"userId": ownerID})
+	})
+
+	request := newCategoryRequest(http.MethodDelete, ownerID, category.ID, nil)
+	request.URL.RawQuery = url.Values{categories.DeleteModeParam: {categories.DeleteModeCascade}}.Encode()
+	responseRecorder := httptest.NewRecorder()
+	api := categories.NewApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+	// 2.ACT
+	api.DeleteCategoryByIdHandler(responseRecorder, request)
+
+	// 3.ASSERT
+	require.Equal(t, http.StatusNoContent, responseRecorder.Code)
+
+	count, err := mongoDal.Count(context.Background(), dal.CollShares, bson.M{"gifId": gif.ID})
+	require.Nil(t, err)
+	assert.Zero(t, count)
+
+	// the shares of the user's other gifs are kept
+	count, err = mongoDal.Count(context.Background(), dal.CollShares, bson.M{"gifId": otherGif.ID})
+	require.Nil(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestDeleteCategoryByIdHandler_Reassign_ExpectedGifsMoved(t *testing.T) {
+	// 1.ARRANGE
+	ownerID := primitive.NewObjectID()
+	category := insertCategory(t, ownerID)
+	target := categories.Category{ID: primitive.NewObjectID(), Name: "target", UserId: ownerID, GifCount: 1}
+	_, errInsert := mongoDal.Insert(context.Background(), dal.CollCategories, []any{target})
+	require.Nil(t, errInsert)
+	insertGifs(t, ownerID, category.ID, 2)
+
+	request := newCategoryRequest(http.MethodDelete, ownerID, category.ID, nil)
+	request.URL.RawQuery = url.Values{
+		categories.DeleteModeParam: {categories.DeleteModeReassign},
+		categories.ReassignToParam: {target.ID.Hex()},
+	}.Encode()
+	responseRecorder := httptest.NewRecorder()
+	api := categories.NewApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+	// 2.ACT
+	api.DeleteCategoryByIdHandler(responseRecorder, request)
+
+	// 3.ASSERT
+	assert.Equal(t, http.StatusNoContent, responseRecorder.Code)
+	assert.Zero(t, countGifs(t, category.ID))
+	assert.Equal(t, int64(2), countGifs(t, target.ID))
+
+	var dbTarget categories.Category
+	require.Nil(t, mongoDal.FindByID(context.Background(), dal.CollCategories, target.ID.Hex(), &dbTarget))
+	assert.Equal(t, 3, dbTarget.GifCount)
+}
+
+func TestDeleteCategoryByIdHandler_ReassignToCategoryOfAnotherUser_ExpectedNothingChanged(t *testing.T) {
+	// 1.ARRANGE
+	ownerID := primitive.NewObjectID()
+	category := insertCategory(t, ownerID)
+	othersCategory := insertCategory(t, primitive.NewObjectID())
+	insertGifs(t, ownerID, category.ID, 2)
+
+	request := newCategoryRequest(http.MethodDelete, ownerID, category.ID, nil)
+	request.URL.RawQuery = url.Values{
+		categories.DeleteModeParam: {categories.DeleteModeReassign},
+		categories.ReassignToParam: {othersCategory.ID.Hex()},
+	}.Encode()
+	responseRecorder := httptest.NewRecorder()
+	api := categories.NewApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+	// 2.ACT
+	api.DeleteCategoryByIdHandler(responseRecorder, request)
+
+	// 3.ASSERT
+	assert.Equal(t, http.StatusUnprocessableEntity, responseRecorder.Code)
+	assert.Equal(t, int64(2), countGifs(t, category.ID))
+
+	var dbCategory categories.Category
+	require.Nil(t, mongoDal.FindByID(context.Background(), dal.CollCategories, category.ID.Hex(), &dbCategory))
+	require.Nil(t, mongoDal.FindByID(context.Background(), dal.CollCategories, othersCategory.ID.Hex(), &dbCategory))
+	assert.Equal(t, othersCategory.GifCount, dbCategory.GifCount)
+}
+
+func TestDeleteCategoryByIdHandler_InvalidMode_ExpectedBadRequest(t *testing.T) {
+	testCases := []url.Values{
+		{categories.DeleteModeParam: {"archive"}},
+		{categories.DeleteModeParam: {categories.DeleteModeReassign}},
+		{categories.DeleteModeParam: {categories.DeleteModeReassign}, categories.ReassignToParam: {"abc"}},
+	}
+
+	for _, query := range testCases {
+		t.Run(query.Encode(), func(t *testing.T) {
+			// 1.ARRANGE
+			ownerID := primitive.NewObjectID()
+			category := insertCategory(t, ownerID)
+			request := newCategoryRequest(http.MethodDelete, ownerID, category.ID, nil)
+			request.URL.RawQuery = query.Encode()
+			responseRecorder := httptest.NewRecorder()
+			api := categories.NewApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+			// 2.ACT
+			api.DeleteCategoryByIdHandler(responseRecorder, request)
+
+			// 3.ASSERT
+			assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+		})
+	}
+}
+
+func TestRecountGifs_ExpectedCountsOfTheGifs(t *testing.T) {
+	// 1.ARRANGE
+	ownerID := primitive.NewObjectID()
+	// insertCategory stores a gif count of 3 without any gifs
+	empty := insertCategory(t, ownerID)
+	withGifs := categories.Category{ID: primitive.NewObjectID(), Name: "with gifs", UserId: ownerID}
+	_, errInsert := mongoDal.Insert(context.Background(), dal.CollCategories, []any{withGifs})
+	require.Nil(t, errInsert)
+	insertGifs(t, ownerID, withGifs.ID, 2)
+
+	// 2.ACT
+	_, err := categories.RecountGifs(context.Background(), mongoDal)
+
+	// 3.ASSERT
+	require.Nil(t, err)
+
+	var dbCategory categories.Category
+	require.Nil(t, mongoDal.FindByID(context.Background(), dal.CollCategories, empty.ID.Hex(), &dbCategory))
+	assert.Zero(t, dbCategory.GifCount)
+	require.Nil(t, mongoDal.FindByID(context.Background(), dal.CollCategories, withGifs.ID.Hex(), &dbCategory))
+	assert.Equal(t, 2, dbCategory.GifCount)
+}
+
+func TestPatchCategoryByIdHandler_ExpectedUpdatedCategory(t *testing.T) {
+	// 1.ARRANGE
+	ownerID := primitive.NewObjectID()
+	category := insertCategory(t, ownerID)
+	request := newCategoryRequest(http.MethodPatch, ownerID, category.ID, map[string]string{"name": "renamed"})
+	request.Header.Set("Content-Type", httputil.MergePatchContentType)
+	responseRecorder := httptest.NewRecorder()
+	api := categories.NewApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+	// 2.ACT
+	api.PatchCategoryByIdHandler(responseRecorder, request)
+
+	// 3.ASSERT
+	require.Equal(t, http.StatusOK, responseRecorder.Code)
+
+	var categoryDTO categories.CategoryDto
+	require.Nil(t, json.NewDecoder(responseRecorder.Body).Decode(&categoryDTO))
+	assert.Equal(t, categories.CategoryDto{ID: category.ID.Hex(), Name: "renamed", GifsCount: category.GifCount}, categoryDTO)
+}
+
+func TestPatchCategoryByIdHandler_CategoryOfAnotherUser_ExpectedNotFound(t *testing.T) {
+	// 1.ARRANGE
+	category := insertCategory(t, primitive.NewObjectID())
+	request := newCategoryRequest(http.MethodPatch, primitive.NewObjectID(), category.ID, map[string]string{"name": "overwritten"})
+	request.Header.Set("Content-Type", httputil.MergePatchContentType)
+	responseRecorder := httptest.NewRecorder()
+	api := categories.NewApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+	// 2.ACT
+	api.PatchCategoryByIdHandler(responseRecorder, request)
+
+	// 3.ASSERT
+	assert.Equal(t, http.StatusNotFound, responseRecorder.Code)
+
+	var dbCategory categories.Category
+	require.Nil(t, mongoDal.FindByID(context.Background(), dal.CollCategories, category.ID.Hex(), &dbCategory))
+	assert.Equal(t, category, dbCategory)
+}
+
+func TestUpdateCategoryByIdHandler_StaleIfMatch_ExpectedPreconditionFailed(t *testing.T) {
+	// 1.ARRANGE
+	ownerID := primitive.NewObjectID()
+	category := insertCategory(t, ownerID)
+	request := newCategoryRequest(http.MethodPut, ownerID, category.ID, categories.CategoryRequest{Name: "overwritten"})
+	request.Header.Set("If-Match", httputil.VersionETag(category.Version+1))
+	responseRecorder := httptest.NewRecorder()
+	api := categories.NewApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+	// 2.ACT
+	api.UpdateCategoryByIdHandler(responseRecorder, request)
+
+	// 3.ASSERT
+	assert.Equal(t, http.StatusPreconditionFailed, responseRecorder.Code)
+
+	var dbCategory categories.Category
+	require.Nil(t, mongoDal.FindByID(context.Background(), dal.CollCategories, category.ID.Hex(), &dbCategory))
+	assert.Equal(t, category, dbCategory)
+}
+
+func TestUpdateCategoryByIdHandler_CategoryWithoutVersion_ExpectedVersionOne(t *testing.T) {
+	// 1.ARRANGE
+	// categories written before versions were added have no version field
+	ownerID := primitive.NewObjectID()
+	categoryID := primitive.NewObjectID()
+	_, errInsert := mongoDal.Insert(context.Background(), dal.CollCategories, []any{bson.M{"_id": categoryID, "name": t.Name(), "userId": ownerID}})
+	require.Nil(t, errInsert)
+	t.Cleanup(func() {
+		mongoDal.Delete(context.Background(), dal.CollCategories, bson.M{"userId": ownerID})
+	})
+
+	request := newCategoryRequest(http.MethodPut, ownerID, categoryID, categories.CategoryRequest{Name: "renamed"})
+	request.Header.Set("If-Match", httputil.VersionETag(0))
+	responseRecorder := httptest.NewRecorder()
+	api := categories.NewApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+	// 2.ACT
+	api.UpdateCategoryByIdHandler(responseRecorder, request)
+
+	// 3.ASSERT
+	assert.Equal(t, http.StatusNoContent, responseRecorder.Code)
+	assert.Equal(t, httputil.VersionETag(1), responseRecorder.Header().Get("ETag"))
+}
+
+func TestDeleteCategoryByIdHandler_Reassign_ExpectedGifVersionsIncremented(t *testing.T) {
+	// 1.ARRANGE
+	ownerID := primitive.NewObjectID()
+	category := insertCategory(t, ownerID)
+	target := categories.Category{ID: primitive.NewObjectID(), Name: "target", UserId: ownerID, Version: 1}
+	gif := gifs.Gif{ID: primitive.NewObjectID(), Name: t.Name(), URL: "https://media.giphy.com/gif.gif", UserId: ownerID, CategoryId: category.ID, Version: 1}
+	_, errInsertCategory := mongoDal.Insert(context.Background(), dal.CollCategories, []any{target})
+	require.Nil(t, errInsertCategory)
+	_, errInsertGif := mongoDal.Insert(context.Background(), dal.CollGifs, []any{gif})
+	require.Nil(t, errInsertGif)
+	t.Cleanup(func() {
+		mongoDal.Delete(context.Background(), dal.CollGifs, bson.M{"userId": ownerID})
+	})
+
+	request := newCategoryRequest(http.MethodDelete, ownerID, category.ID, nil)
+	request.URL.RawQuery = url.Values{
+		categories.DeleteModeParam: {categories.DeleteModeReassign},
+		categories.ReassignToParam: {target.ID.Hex()},
+	}.Encode()
+	responseRecorder := httptest.NewRecorder()
+	api := categories.NewApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+	// 2.ACT
+	api.DeleteCategoryByIdHandler(responseRecorder, request)
+
+	// 3.ASSERT
+	require.Equal(t, http.StatusNoContent, responseRecorder.Code)
+
+	// a client holding the old ETag can't write the deleted category back
+	gifRecorder := httptest.NewRecorder()
+	gifs.NewGifApi(mongoDal, httputil.NewGifsApiQueryParamParser()).GetGifHandler(gifRecorder, newCategoryRequest(http.MethodGet, ownerID, gif.ID, nil))
+	require.Equal(t, http.StatusOK, gifRecorder.Code)
+	assert.NotEqual(t, httputil.VersionETag(gif.Version), gifRecorder.Header().Get("ETag"))
+	assert.Equal(t, httputil.VersionETag(2), gifRecorder.Header().Get("ETag"))
+}
+
+func TestGetCategoriesHandler_ExpectedCategoryDtos(t *testing.T) {
+	// 1.ARRANGE
+	ownerID := primitive.NewObjectID()
+	category := insertCategory(t, ownerID)
+	request := newCategoryRequest(http.MethodGet, ownerID, primitive.NilObjectID, nil)
+	responseRecorder := httptest.NewRecorder()
+	api := categories.NewApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+	// 2.ACT
+	api.GetCategoriesHandler(responseRecorder, request)
+
+	// 3.ASSERT
+	require.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.NotContains(t, responseRecorder.Body.String(), "version")
+	assert.NotContains(t, responseRecorder.Body.String(), ownerID.Hex())
+
+	var categoryDTOs []categories.CategoryDto
+	require.Nil(t, json.NewDecoder(responseRecorder.Body).Decode(&categoryDTOs))
+	assert.Equal(t, []categories.CategoryDto{category.ToDto()}, categoryDTOs)
+}
+
+func TestGetCategoriesHandler_NoCategories_ExpectedEmptyList(t *testing.T) {
+	// 1.ARRANGE
+	request := newCategoryRequest(http.MethodGet, primitive.NewObjectID(), primitive.NilObjectID, nil)
+	responseRecorder := httptest.NewRecorder()
+	api := categories.NewApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+	// 2.ACT
+	api.GetCategoriesHandler(responseRecorder, request)
+
+	// 3.ASSERT
+	require.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.JSONEq(t, "[]", responseRecorder.Body.String())
+}
+
+func TestGetCategoryByIdHandler_ExpectedCategoryWithETag(t *testing.T) {
+	// 1.ARRANGE
+	ownerID := primitive.NewObjectID()
+	category := insertCategory(t, ownerID)
+	request := newCategoryRequest(http.MethodGet, ownerID, category.ID, nil)
+	responseRecorder := httptest.NewRecorder()
+	api := categories.NewApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+	// 2.ACT
+	api.GetCategoryByIdHandler(responseRecorder, request)
+
+	// 3.ASSERT
+	require.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.Equal(t, httputil.VersionETag(category.Version), responseRecorder.Header().Get("ETag"))
+
+	var categoryDTO categories.CategoryDto
+	require.Nil(t, json.NewDecoder(responseRecorder.Body).Decode(&categoryDTO))
+	assert.Equal(t, category.ToDto(), categoryDTO)
+}
+
+func TestGetCategoryByIdHandler_CategoryOfAnotherUser_ExpectedNotFound(t *testing.T) {
+	// 1.ARRANGE
+	category := insertCategory(t, primitive.NewObjectID())
+	request := newCategoryRequest(http.MethodGet, primitive.NewObjectID(), category.ID, nil)
+	responseRecorder := httptest.NewRecorder()
+	api := categories.NewApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+	// 2.ACT
+	api.GetCategoryByIdHandler(responseRecorder, request)
+
+	// 3.ASSERT
+	assert.Equal(t, http.StatusNotFound, responseRecorder.Code)
+}
+
+func TestInitializeEndpoints_GifsByCategoryIsNotTakenForAnId(t *testing.T) {
+	// 1.ARRANGE
+	router := mux.NewRouter()
+	categories.NewApi(mongoDal, httputil.NewGifsApiQueryParamParser()).InitializeEndpoints(router)
+	request := httptest.NewRequest(http.MethodGet, "/categories/gifs", nil)
+	request = request.WithContext(context.WithValue(context.Background(), "userID", primitive.NewObjectID()))
+	responseRecorder := httptest.NewRecorder()
+
+	// 2.ACT
+	router.ServeHTTP(responseRecorder, request)
+
+	// 3.ASSERT
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+}
+
+func getGifsByCategory(t *testing.T, userID primitive.ObjectID, query url.Values) (*httptest.ResponseRecorder, []categories.GifsByCategoryDto) {
+	request := httptest.NewRequest(http.MethodGet, "/categories/gifs?"+query.Encode(), nil)
+	request = request.WithContext(context.WithValue(context.Background(), "userID", userID))
+	responseRecorder := httptest.NewRecorder()
+	api := categories.NewApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+	api.GetGifsByCategory(responseRecorder, request)
+
+	require.Equal(t, http.StatusOK, responseRecorder.Code, responseRecorder.Body.String())
+	var groups []categories.GifsByCategoryDto
+	require.Nil(t, json.NewDecoder(responseRecorder.Body).Decode(&groups))
+	return responseRecorder, groups
+}
+
+// insertGroupedGifs stores a category "a" with 3 gifs, a category "b" with 1 gif, two gifs without a category
+// and a gif of another user.
+func insertGroupedGifs(t *testing.T, ownerID primitive.ObjectID) (categories.Category, categories.Category) {
+	first := categories.Category{ID: primitive.NewObjectID(), Name: "a", UserId: ownerID, GifCount: 3}
+	second := categories.Category{ID: primitive.NewObjectID(), Name: "b", UserId: ownerID, GifCount: 1}
+	_, errInsert := mongoDal.Insert(context.Background(), dal.CollCategories, []any{first, second})
+	require.Nil(t, errInsert)
+	t.Cleanup(func() {
+		mongoDal.Delete(context.Background(), dal.CollCategories, bson.M{"userId": ownerID})
+	})
+
+	insertGifs(t, ownerID, first.ID, 3)
+	insertGifs(t, ownerID, second.ID, 1)
+	insertGifs(t, ownerID, primitive.NilObjectID, 1)
+	insertGifs(t, primitive.NewObjectID(), first.ID, 1)
+
+	// a gif whose category was removed with a merge patch has no categoryId at all
+	_, errInsertGif := mongoDal.Insert(context.Background(), dal.CollGifs, []any{bson.M{"_id": primitive.NewObjectID(), "name": t.Name(), "userId": ownerID, "isFavourite": true}})
+	require.Nil(t, errInsertGif)
+	return first, second
+}
+
+func TestGetGifsByCategory_ExpectedGroupsWithUncategorisedBucket(t *testing.T) {
+	// 1.ARRANGE
+	ownerID := primitive.NewObjectID()
+	first, second := insertGroupedGifs(t, ownerID)
+
+	// 2.ACT
+	responseRecorder, groups := getGifsByCategory(t, ownerID, url.Values{categories.GifsPerCategoryParam: {"2"}})
+
+	// 3.ASSERT
+	assert.Equal(t, "3", responseRecorder.Header().Get("X-Total-Count"))
+	require.Len(t, groups, 3)
+
+	assert.True(t, groups[0].Uncategorised)
+	assert.Equal(t, categories.UncategorisedName, groups[0].Name)
+	assert.Empty(t, groups[0].CategoryID)
+	assert.Equal(t, 2, groups[0].GifCount)
+
+	assert.Equal(t, first.ID.Hex(), groups[1].CategoryID)
+	assert.Equal(t, first.Name, groups[1].Name)
+	assert.Equal(t, 3, groups[1].GifCount)
+	require.Len(t, groups[1].Gifs, 2)
+	for _, gif := range groups[1].Gifs {
+		assert.Equal(t, first.ID.Hex(), gif.CategoryID)
+	}
+
+	assert.Equal(t, second.ID.Hex(), groups[2].CategoryID)
+	assert.Equal(t, 1, groups[2].GifCount)
+	assert.Len(t, groups[2].Gifs, 1)
+}
+
+func TestGetGifsByCategory_PagedAndFiltered(t *testing.T) {
+	// 1.ARRANGE
+	ownerID := primitive.NewObjectID()
+	first, _ := insertGroupedGifs(t, ownerID)
+
+	// 2.ACT
+	pagedRecorder, paged := getGifsByCategory(t, ownerID, url.Values{"page": {"2"}, "pageSize": {"1"}})
+	_, filtered := getGifsByCategory(t, ownerID, url.Values{"filter": {"isFavourite=true"}})
+
+	// 3.ASSERT
+	require.Len(t, paged, 1)
+	assert.Equal(t, first.ID.Hex(), paged[0].CategoryID)
+	assert.Contains(t, pagedRecorder.Header().Get("Link"), `rel="next"`)
+
+	require.Len(t, filtered, 1)
+	assert.True(t, filtered[0].Uncategorised)
+	assert.Equal(t, 1, filtered[0].GifCount)
+}
+
+func TestGetGifsByCategory_InvalidGifsPerCategory_ExpectedBadRequest(t *testing.T) {
+	for _, value := range []string{"0", "abc", "101"} {
+		t.Run(value, func(t *testing.T) {
+			// 1.ARRANGE
+			request := httptest.NewRequest(http.MethodGet, "/categories/gifs?"+categories.GifsPerCategoryParam+"="+value, nil)
+			request = request.WithContext(context.WithValue(context.Background(), "userID", primitive.NewObjectID()))
+			responseRecorder := httptest.NewRecorder()
+			api := categories.NewApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+			// 2.ACT
+			api.GetGifsByCategory(responseRecorder, request)
+
+			// 3.ASSERT
+			assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+		})
+	}
+}
+
+// the gifs by category pipeline is written with the bson names of the models, this fails when a model is renamed
+func TestGetGifsByCategory_PipelineFieldsMatchModelTags(t *testing.T) {
+	testCases := []struct {
+		model    any
+		field    string
+		expected string
+	}{
+		{gifs.Gif{}, "UserId", "userId"},
+		{gifs.Gif{}, "CategoryId", "categoryId"},
+		{categories.Category{}, "ID", "_id"},
+		{categories.Category{}, "Name", "name"},
+		{categories.GifsByCategory{}, "CategoryId", "_id"},
+		{categories.GifsByCategory{}, "Name", "name"},
+		{categories.GifsByCategory{}, "GifCount", "gifCount"},
+		{categories.GifsByCategory{}, "Gifs", "gifs"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.field, func(t *testing.T) {
+			// 1.ACT
+			field, ok := reflect.TypeOf(testCase.model).FieldByName(testCase.field)
+
+			// 2.ASSERT
+			require.True(t, ok)
+			assert.Equal(t, testCase.expected, strings.Split(field.Tag.Get("bson"), ",")[0])
+		})
+	}
+}
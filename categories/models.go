@@ -10,6 +10,8 @@ type Category struct {
 	Name     string             `bson:"name"`
 	UserId   primitive.ObjectID `bson:"userId"`
 	GifCount int                `bson:"gifCount"`
+	// Version is incremented when the category is renamed, not when its gifCount changes. It is sent to the clients as the ETag
+	Version int `bson:"version"`
 }
 
 type Categories []Category
@@ -22,16 +24,36 @@ func (c Category) ToDto() CategoryDto {
 	}
 }
 
+func (categories Categories) ToDto() []CategoryDto {
+	dtos := make([]CategoryDto, 0, len(categories))
+	for _, category := range categories {
+		dtos = append(dtos, category.ToDto())
+	}
+	return dtos
+}
+
+// GifsByCategory is a group of gifs, the group of the gifs without a category has no CategoryId.
 type GifsByCategory struct {
-	CategoryId primitive.ObjectID `bson:"categoryId,omitempty"`
+	CategoryId primitive.ObjectID `bson:"_id,omitempty"`
 	Name       string             `bson:"name"`
+	GifCount   int                `bson:"gifCount"`
 	Gifs       gifs.Gifs          `bson:"gifs"`
 }
 
 func (model GifsByCategory) ToDto() GifsByCategoryDto {
+	if model.CategoryId.IsZero() {
+		return GifsByCategoryDto{
+			Name:          UncategorisedName,
+			Uncategorised: true,
+			GifCount:      model.GifCount,
+			Gifs:          model.Gifs.ToDto(),
+		}
+	}
+
 	return GifsByCategoryDto{
 		CategoryID: model.CategoryId.Hex(),
 		Name:       model.Name,
+		GifCount:   model.GifCount,
 		Gifs:       model.Gifs.ToDto(),
 	}
 }
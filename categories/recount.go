@@ -0,0 +1,52 @@
+package categories
+
+import (
+	"context"
+	"fmt"
+	"gifmanager-backend/dal"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type categoryGifCount struct {
+	CategoryID primitive.ObjectID `bson:"_id"`
+	Count      int                `bson:"count"`
+}
+
+// RecountGifs rebuilds the gifCount of every category from the gifs collection and returns how many categories have gifs.
+// It repairs counters that drifted, e.g. when they were written without a transaction.
+func RecountGifs(ctx context.Context, mongoDal dal.DAL) (int, error) {
+	categoriesWithGifs := 0
+	err := mongoDal.WithTransaction(ctx, func(txCtx context.Context) error {
+		// fn may be retried, so nothing is kept from a previous attempt
+		categoriesWithGifs = 0
+		var counts []categoryGifCount
+		pipeline := []any{
+			bson.M{"$group": bson.M{"_id": "$categoryId", "count": bson.M{"$sum": 1}}},
+		}
+		if err := mongoDal.Aggregate(txCtx, dal.CollGifs, pipeline, &counts); err != nil {
+			return fmt.Errorf("error while counting the gifs: %w", err)
+		}
+
+		reset := bson.M{"$set": bson.M{"gifCount": 0}}
+		if _, err := mongoDal.Update(txCtx, dal.CollCategories, bson.M{}, reset, dal.UpdateAllMatching); err != nil {
+			return fmt.Errorf("error while resetting the gif counts: %w", err)
+		}
+
+		for _, count := range counts {
+			if count.CategoryID.IsZero() {
+				continue
+			}
+			update := bson.M{"$set": bson.M{"gifCount": count.Count}}
+			if _, err := mongoDal.UpdateByID(txCtx, dal.CollCategories, count.CategoryID.Hex(), update); err != nil {
+				return fmt.Errorf("error while updating the gif count of category %s: %w", count.CategoryID.Hex(), err)
+			}
+			categoriesWithGifs++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return categoriesWithGifs, nil
+}
@@ -0,0 +1,224 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"gifmanager-backend/dal"
+	"gifmanager-backend/users"
+	"gopkg.in/yaml.v3"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+// Config is the configuration of the server. The defaults are overridden by the YAML file, the file by the
+// environment variables and the environment variables by the flags, see settings for their names.
+type Config struct {
+	Mongo    Mongo  `yaml:"mongo"`
+	Server   Server `yaml:"server"`
+	Auth     Auth   `yaml:"auth"`
+	LogLevel string `yaml:"logLevel"`
+}
+
+type Mongo struct {
+	URI            string        `yaml:"uri"`
+	Database       string        `yaml:"database"`
+	ConnectTimeout time.Duration `yaml:"connectTimeout"`
+	// InMemory keeps all data in memory instead of connecting to mongo, URI is ignored
+	InMemory bool `yaml:"inMemory"`
+}
+
+type Server struct {
+	Address      string        `yaml:"address"`
+	ReadTimeout  time.Duration `yaml:"readTimeout"`
+	WriteTimeout time.Duration `yaml:"writeTimeout"`
+	IdleTimeout  time.Duration `yaml:"idleTimeout"`
+	// ShutdownTimeout is how long the requests in flight may take to finish after SIGINT or SIGTERM
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout"`
+	MaxHeaderBytes  int           `yaml:"maxHeaderBytes"`
+	// TLSCertFile and TLSKeyFile are PEM files, the server serves HTTPS when both are set
+	TLSCertFile string `yaml:"tlsCertFile"`
+	TLSKeyFile  string `yaml:"tlsKeyFile"`
+	// CORSOrigins are the origins browsers may call the API from, * allows every origin
+	CORSOrigins []string `yaml:"corsOrigins"`
+}
+
+// TLS reports whether the server serves HTTPS.
+func (s Server) TLS() bool {
+	return s.TLSCertFile != "" && s.TLSKeyFile != ""
+}
+
+type Auth struct {
+	// TokenSecret signs the session tokens, without it a random secret is used,
+	// which invalidates every token when the server restarts
+	TokenSecret string        `yaml:"tokenSecret"`
+	TokenTTL    time.Duration `yaml:"tokenTTL"`
+	RefreshTTL  time.Duration `yaml:"refreshTTL"`
+}
+
+// log levels
+const (
+	LogLevelDebug = "debug"
+	LogLevelInfo  = "info"
+	LogLevelWarn  = "warn"
+	LogLevelError = "error"
+)
+
+// ConfigFileEnv names the YAML file when the -config flag is not given.
+const ConfigFileEnv = "CONFIG_FILE"
+
+func Default() Config {
+	return Config{
+		Mongo: Mongo{
+			URI:            "mongodb://localhost:27017",
+			Database:       dal.DbName,
+			ConnectTimeout: 10 * time.Second,
+		},
+		Server: Server{
+			Address:         "localhost:8888",
+			ReadTimeout:     15 * time.Second,
+			WriteTimeout:    30 * time.Second,
+			IdleTimeout:     2 * time.Minute,
+			ShutdownTimeout: 20 * time.Second,
+			MaxHeaderBytes:  64 << 10,
+			CORSOrigins:     []string{"*"},
+		},
+		Auth: Auth{
+			TokenTTL:   users.DefaultTokenTTL,
+			RefreshTTL: users.DefaultRefreshTTL,
+		},
+		LogLevel: LogLevelInfo,
+	}
+}
+
+// Load reads the configuration of the server. The flags of every setting are added to flags, which parses args,
+// so the arguments that are left, e.g. a subcommand, can be read from flags.Args() afterward.
+func Load(flags *flag.FlagSet, args []string, lookupEnv func(string) (string, bool)) (Config, error) {
+	configFile := flags.String("config", "", "the YAML configuration file, also read from $"+ConfigFileEnv)
+	flagValues := addFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	if *configFile == "" {
+		*configFile, _ = lookupEnv(ConfigFileEnv)
+	}
+	return load(Default(), *configFile, lookupEnv, *flagValues)
+}
+
+// LoadForTests reads the configuration of the test suites from the environment, without flags, since go test owns
+// them. The tests use the test database and run against the in-memory DAL unless MONGO_URI is set.
+func LoadForTests(lookupEnv func(string) (string, bool)) (Config, error) {
+	mongoURI, _ := lookupEnv("MONGO_URI")
+	defaults := Default()
+	defaults.Mongo.Database = dal.TestDbName
+	defaults.Mongo.InMemory = mongoURI == ""
+
+	configFile, _ := lookupEnv(ConfigFileEnv)
+	return load(defaults, configFile, lookupEnv, nil)
+}
+
+func load(config Config, configFile string, lookupEnv func(string) (string, bool), flagValues []flagValue) (Config, error) {
+	if configFile != "" {
+		if err := readFile(&config, configFile); err != nil {
+			return Config{}, err
+		}
+	}
+
+	var problems []error
+	for _, setting := range settings {
+		if setting.env == "" {
+			continue
+		}
+		if value, ok := lookupEnv(setting.env); ok {
+			if err := setting.set(&config, value); err != nil {
+				problems = append(problems, fmt.Errorf("$%s: %w", setting.env, err))
+			}
+		}
+	}
+	for _, flagValue := range flagValues {
+		if err := flagValue.setting.set(&config, flagValue.value); err != nil {
+			problems = append(problems, fmt.Errorf("-%s: %w", flagValue.setting.flag, err))
+		}
+	}
+
+	problems = append(problems, config.validate()...)
+	if len(problems) > 0 {
+		return Config{}, fmt.Errorf("invalid configuration: %w", errors.Join(problems...))
+	}
+	return config, nil
+}
+
+// readFile overrides the configuration with the settings in the file, unknown settings are refused so typos show up.
+func readFile(config *Config, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error reading the configuration file: %w", err)
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(config); err != nil {
+		return fmt.Errorf("error reading the configuration file %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c Config) validate() []error {
+	var problems []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			problems = append(problems, fmt.Errorf(format, args...))
+		}
+	}
+
+	if !c.Mongo.InMemory {
+		check(strings.HasPrefix(c.Mongo.URI, "mongodb://") || strings.HasPrefix(c.Mongo.URI, "mongodb+srv://"),
+			"mongo.uri must start with mongodb:// or mongodb+srv://")
+	}
+	check(c.Mongo.Database != "", "mongo.database is required")
+	check(c.Mongo.ConnectTimeout > 0, "mongo.connectTimeout must be positive")
+
+	check(c.Server.Address != "", "server.address is required")
+	check(c.Server.ReadTimeout > 0, "server.readTimeout must be positive")
+	check(c.Server.WriteTimeout > 0, "server.writeTimeout must be positive")
+	check(c.Server.IdleTimeout > 0, "server.idleTimeout must be positive")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdownTimeout must be positive")
+	check(c.Server.MaxHeaderBytes > 0, "server.maxHeaderBytes must be positive")
+	check((c.Server.TLSCertFile == "") == (c.Server.TLSKeyFile == ""), "server.tlsCertFile and server.tlsKeyFile must be set together")
+	for _, file := range []string{c.Server.TLSCertFile, c.Server.TLSKeyFile} {
+		if file != "" {
+			_, err := os.Stat(file)
+			check(err == nil, "%v", err)
+		}
+	}
+	for _, origin := range c.Server.CORSOrigins {
+		check(origin == "*" || strings.HasPrefix(origin, "http://") || strings.HasPrefix(origin, "https://"),
+			"server.corsOrigins must be * or origins like https://example.com, found %q", origin)
+	}
+
+	check(c.Auth.TokenTTL > 0, "auth.tokenTTL must be positive")
+	check(c.Auth.RefreshTTL >= c.Auth.TokenTTL, "auth.refreshTTL must be at least auth.tokenTTL")
+
+	_, err := c.SlogLevel()
+	check(err == nil, "logLevel must be %s, %s, %s or %s", LogLevelDebug, LogLevelInfo, LogLevelWarn, LogLevelError)
+	return problems
+}
+
+// SlogLevel translates the log level.
+func (c Config) SlogLevel() (slog.Level, error) {
+	switch c.LogLevel {
+	case LogLevelDebug:
+		return slog.LevelDebug, nil
+	case LogLevelInfo:
+		return slog.LevelInfo, nil
+	case LogLevelWarn:
+		return slog.LevelWarn, nil
+	case LogLevelError:
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q", c.LogLevel)
+}
@@ -0,0 +1,185 @@
+package config_test
+
+import (
+	"flag"
+	"gifmanager-backend/config"
+	"gifmanager-backend/dal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func env(values map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := values[key]
+		return value, ok
+	}
+}
+
+func writeConfigFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.Nil(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func newFlagSet() *flag.FlagSet {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.SetOutput(&discard{})
+	return flags
+}
+
+type discard struct{}
+
+func (discard) Write(p []byte) (int, error) { return len(p), nil }
+
+func TestLoad_WithoutSettings_ExpectedDefaults(t *testing.T) {
+	// 1.ARRANGE
+	flags := newFlagSet()
+
+	// 2.ACT
+	cfg, err := config.Load(flags, []string{"recount"}, env(nil))
+
+	// 3.ASSERT
+	require.Nil(t, err)
+	assert.Equal(t, config.Default(), cfg)
+	assert.Equal(t, []string{"recount"}, flags.Args())
+}
+
+func TestLoad_FlagsOverrideEnvironmentOverridesFile(t *testing.T) {
+	// 1.ARRANGE
+	path := writeConfigFile(t, `
+mongo:
+  uri: mongodb://file:27017
+  database: file
+server:
+  address: file:8888
+  corsOrigins: [https://file.example.com]
+auth:
+  tokenSecret: file-secret
+  tokenTTL: 5m
+logLevel: debug
+`)
+	lookupEnv := env(map[string]string{
+		"MONGO_DATABASE": "env",
+		"LISTEN_ADDRESS": "env:8888",
+		"CORS_ORIGINS":   "https://a.example.com, https://b.example.com",
+		"TOKEN_SECRET":   "env-secret",
+	})
+	args := []string{"-config", path, "-address", "flag:8888", "-in-memory", "migrate", "status"}
+	flags := newFlagSet()
+
+	// 2.ACT
+	cfg, err := config.Load(flags, args, lookupEnv)
+
+	// 3.ASSERT
+	require.Nil(t, err)
+	assert.Equal(t, "mongodb://file:27017", cfg.Mongo.URI)
+	assert.Equal(t, "env", cfg.Mongo.Database)
+	assert.True(t, cfg.Mongo.InMemory)
+	assert.Equal(t, "flag:8888", cfg.Server.Address)
+	assert.Equal(t, []string{"https://a.example.com", "https://b.example.com"}, cfg.Server.CORSOrigins)
+	assert.Equal(t, "env-secret", cfg.Auth.TokenSecret)
+	assert.Equal(t, 5*time.Minute, cfg.Auth.TokenTTL)
+	assert.Equal(t, config.Default().Auth.RefreshTTL, cfg.Auth.RefreshTTL)
+	assert.Equal(t, config.LogLevelDebug, cfg.LogLevel)
+	assert.Equal(t, []string{"migrate", "status"}, flags.Args())
+}
+
+func TestLoad_ConfigFileFromEnvironment(t *testing.T) {
+	// 1.ARRANGE
+	path := writeConfigFile(t, "server:\n  address: file:8888\n")
+
+	// 2.ACT
+	cfg, err := config.Load(newFlagSet(), nil, env(map[string]string{config.ConfigFileEnv: path}))
+
+	// 3.ASSERT
+	require.Nil(t, err)
+	assert.Equal(t, "file:8888", cfg.Server.Address)
+}
+
+func TestLoad_InvalidSettings_ExpectedErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		file          string
+		env           map[string]string
+		args          []string
+		expectedError string
+	}{
+		{"unknown file setting", "server:\n  adress: localhost:8888\n", nil, nil, "field adress not found"},
+		{"missing file", "", map[string]string{config.ConfigFileEnv: "/does/not/exist.yaml"}, nil, "configuration file"},
+		{"invalid duration", "", map[string]string{"READ_TIMEOUT": "soon"}, nil, "$READ_TIMEOUT: expected a duration"},
+		{"invalid bool", "", map[string]string{"IN_MEMORY": "maybe"}, nil, "$IN_MEMORY: expected true or false"},
+		{"invalid flag", "", nil, []string{"-token-ttl", "forever"}, "expected a duration"},
+		{"invalid uri", "", map[string]string{"MONGO_URI": "localhost:27017"}, nil, "mongo.uri must start with mongodb://"},
+		{"uri ignored in memory", "", map[string]string{"MONGO_URI": "localhost:27017", "IN_MEMORY": "true"}, nil, ""},
+		{"empty address", "server:\n  address: \"\"\n", nil, nil, "server.address is required"},
+		{"negative timeout", "", map[string]string{"WRITE_TIMEOUT": "-1s"}, nil, "server.writeTimeout must be positive"},
+		{"invalid number", "", map[string]string{"MAX_HEADER_BYTES": "1MB"}, nil, "$MAX_HEADER_BYTES: expected a number"},
+		{"zero header size", "", map[string]string{"MAX_HEADER_BYTES": "0"}, nil, "server.maxHeaderBytes must be positive"},
+		{"certificate without key", "", map[string]string{"TLS_CERT_FILE": "config_test.go"}, nil, "must be set together"},
+		{"missing certificate", "", map[string]string{"TLS_CERT_FILE": "/does/not/exist.pem", "TLS_KEY_FILE": "config_test.go"}, nil, "/does/not/exist.pem"},
+		{"invalid origin", "", map[string]string{"CORS_ORIGINS": "example.com"}, nil, `found "example.com"`},
+		{"refresh shorter than token", "", map[string]string{"TOKEN_TTL": "2h", "REFRESH_TTL": "1h"}, nil, "auth.refreshTTL must be at least auth.tokenTTL"},
+		{"unknown log level", "", map[string]string{"LOG_LEVEL": "verbose"}, nil, "logLevel must be"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// 1.ARRANGE
+			args := test.args
+			if test.file != "" {
+				args = append([]string{"-config", writeConfigFile(t, test.file)}, args...)
+			}
+
+			// 2.ACT
+			_, err := config.Load(newFlagSet(), args, env(test.env))
+
+			// 3.ASSERT
+			if test.expectedError == "" {
+				assert.Nil(t, err)
+				return
+			}
+			require.NotNil(t, err)
+			assert.Contains(t, err.Error(), test.expectedError)
+		})
+	}
+}
+
+func TestLoad_ReportsEveryProblem(t *testing.T) {
+	// 1.ARRANGE
+	lookupEnv := env(map[string]string{"LISTEN_ADDRESS": "", "LOG_LEVEL": "verbose"})
+
+	// 2.ACT
+	_, err := config.Load(newFlagSet(), nil, lookupEnv)
+
+	// 3.ASSERT
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "server.address is required")
+	assert.Contains(t, err.Error(), "logLevel must be")
+}
+
+func TestLoadForTests(t *testing.T) {
+	t.Run("without MONGO_URI", func(t *testing.T) {
+		// 2.ACT
+		cfg, err := config.LoadForTests(env(nil))
+
+		// 3.ASSERT
+		require.Nil(t, err)
+		assert.True(t, cfg.Mongo.InMemory)
+		assert.Equal(t, dal.TestDbName, cfg.Mongo.Database)
+	})
+
+	t.Run("with MONGO_URI", func(t *testing.T) {
+		// 2.ACT
+		cfg, err := config.LoadForTests(env(map[string]string{"MONGO_URI": "mongodb://localhost:27017"}))
+
+		// 3.ASSERT
+		require.Nil(t, err)
+		assert.False(t, cfg.Mongo.InMemory)
+		assert.Equal(t, "mongodb://localhost:27017", cfg.Mongo.URI)
+		assert.Equal(t, dal.TestDbName, cfg.Mongo.Database)
+	})
+}
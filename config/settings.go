@@ -0,0 +1,123 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// setting binds a field of the configuration to its flag and environment variable, either can be empty.
+type setting struct {
+	flag  string
+	env   string
+	usage string
+	field func(c *Config) any
+}
+
+// settings are the flags and environment variables of the configuration. The token secret has no flag,
+// since the arguments of a process can be read by every user of the machine.
+var settings = []setting{
+	{"mongo-uri", "MONGO_URI", "the URI of the mongo server", func(c *Config) any { return &c.Mongo.URI }},
+	{"mongo-database", "MONGO_DATABASE", "the mongo database", func(c *Config) any { return &c.Mongo.Database }},
+	{"mongo-connect-timeout", "MONGO_CONNECT_TIMEOUT", "how long to wait for mongo on start", func(c *Config) any { return &c.Mongo.ConnectTimeout }},
+	{"in-memory", "IN_MEMORY", "keep all data in memory instead of connecting to mongo", func(c *Config) any { return &c.Mongo.InMemory }},
+	{"address", "LISTEN_ADDRESS", "the address the server listens on", func(c *Config) any { return &c.Server.Address }},
+	{"read-timeout", "READ_TIMEOUT", "how long reading a request may take", func(c *Config) any { return &c.Server.ReadTimeout }},
+	{"write-timeout", "WRITE_TIMEOUT", "how long handling a request and writing the response may take", func(c *Config) any { return &c.Server.WriteTimeout }},
+	{"idle-timeout", "IDLE_TIMEOUT", "how long an idle keep-alive connection is kept open", func(c *Config) any { return &c.Server.IdleTimeout }},
+	{"shutdown-timeout", "SHUTDOWN_TIMEOUT", "how long the requests in flight may take to finish on shutdown", func(c *Config) any { return &c.Server.ShutdownTimeout }},
+	{"max-header-bytes", "MAX_HEADER_BYTES", "the maximum size of the request headers", func(c *Config) any { return &c.Server.MaxHeaderBytes }},
+	{"tls-cert-file", "TLS_CERT_FILE", "the PEM certificate to serve HTTPS with, requires -tls-key-file", func(c *Config) any { return &c.Server.TLSCertFile }},
+	{"tls-key-file", "TLS_KEY_FILE", "the PEM private key of the certificate", func(c *Config) any { return &c.Server.TLSKeyFile }},
+	{"cors-origins", "CORS_ORIGINS", "comma separated origins browsers may call the API from, * allows every origin", func(c *Config) any { return &c.Server.CORSOrigins }},
+	{"", "TOKEN_SECRET", "", func(c *Config) any { return &c.Auth.TokenSecret }},
+	{"token-ttl", "TOKEN_TTL", "how long a session token is valid", func(c *Config) any { return &c.Auth.TokenTTL }},
+	{"refresh-ttl", "REFRESH_TTL", "how long a session can be refreshed", func(c *Config) any { return &c.Auth.RefreshTTL }},
+	{"log-level", "LOG_LEVEL", "debug, info, warn or error", func(c *Config) any { return &c.LogLevel }},
+}
+
+// set parses the value according to the type of the field, lists are comma separated.
+func (s setting) set(c *Config, value string) error {
+	switch field := s.field(c).(type) {
+	case *string:
+		*field = value
+	case *bool:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("expected true or false, found %q", value)
+		}
+		*field = parsed
+	case *int:
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("expected a number, found %q", value)
+		}
+		*field = parsed
+	case *time.Duration:
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("expected a duration like 30s or 5m, found %q", value)
+		}
+		*field = parsed
+	case *[]string:
+		list := make([]string, 0)
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		*field = list
+	default:
+		panic(fmt.Sprintf("setting %s has an unsupported type %T", s.flag, field))
+	}
+	return nil
+}
+
+// defaultValue prints the default of the setting for the usage of its flag.
+func (s setting) defaultValue() string {
+	defaults := Default()
+	switch field := s.field(&defaults).(type) {
+	case *[]string:
+		return strings.Join(*field, ",")
+	case *string:
+		return *field
+	case *bool:
+		return strconv.FormatBool(*field)
+	case *int:
+		return strconv.Itoa(*field)
+	case *time.Duration:
+		return field.String()
+	}
+	return ""
+}
+
+// flagValue is a flag given on the command line, it is applied after the file and the environment variables.
+type flagValue struct {
+	setting setting
+	value   string
+}
+
+// addFlags defines the flags of the settings and returns the values given on the command line, in their order.
+// The flags are only recorded while parsing, since the configuration file they override is read afterward.
+func addFlags(flags *flag.FlagSet) *[]flagValue {
+	values := make([]flagValue, 0)
+	for _, s := range settings {
+		if s.flag == "" {
+			continue
+		}
+		s := s
+		usage := fmt.Sprintf("%s, also read from $%s (default %q)", s.usage, s.env, s.defaultValue())
+		record := func(value string) error {
+			values = append(values, flagValue{setting: s, value: value})
+			return s.set(&Config{}, value)
+		}
+		if _, isBool := s.field(&Config{}).(*bool); isBool {
+			flags.BoolFunc(s.flag, usage, record)
+		} else {
+			flags.Func(s.flag, usage, record)
+		}
+	}
+	return &values
+}
@@ -15,6 +15,9 @@ const (
 	CollCategories = "categories"
 	CollGifs       = "gifs"
 	CollGroups     = "groups"
+	CollMigrations = "migrations"
+	CollSessions   = "sessions"
+	CollShares     = "shares"
 	CollUsers      = "users"
 )
 
@@ -22,10 +25,21 @@ type DAL interface {
 	Disconnect(ctx context.Context) error
 	Insert(ctx context.Context, collection string, document []any) (*InsertResult, error)
 	Find(ctx context.Context, collection string, findArguments FindArguments, result any) error
+	Count(ctx context.Context, collection string, filter any) (int64, error)
 	FindByID(ctx context.Context, collection string, id string, result any) error
 	Delete(ctx context.Context, collection string, filter any) (*DeleteResult, error)
 	FindAndDeleteByID(ctx context.Context, collection string, id string, document interface{}) error
+	FindAndDelete(ctx context.Context, collection string, filter any, document any) error
 	Aggregate(ctx context.Context, collection string, pipeline []any, result any) error
 	Update(ctx context.Context, collection string, filter any, update any, optionFuncs ...UpdateOptionsFunc) (*UpdateResult, error)
 	UpdateByID(ctx context.Context, collection string, id string, update any, optionFuncs ...UpdateOptionsFunc) (*UpdateResult, error)
+	EnsureIndex(ctx context.Context, collection string, index Index) error
+	// ListIndexes returns the indexes of the collection, including the index on _id.
+	ListIndexes(ctx context.Context, collection string) ([]Index, error)
+	DropIndex(ctx context.Context, collection string, name string) error
+	// Search finds the documents matching the text, the most relevant first, and returns how many match in total.
+	// The collection needs a text index, the relevance of every document is set to ScoreField.
+	Search(ctx context.Context, collection string, searchArguments SearchArguments, result any) (int64, error)
+	// WithTransaction runs fn in a transaction, the writes made with txCtx are reverted when fn returns an error.
+	WithTransaction(ctx context.Context, fn func(txCtx context.Context) error) error
 }
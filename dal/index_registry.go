@@ -0,0 +1,187 @@
+package dal
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// IndexRegistry declares the indexes of every collection, by collection name.
+type IndexRegistry map[string][]Index
+
+// Indexes are the indexes the handlers rely on. The field names are the bson names of the models, which can't be
+// imported here, so they are repeated. EnsureIndexes creates them at startup.
+var Indexes = IndexRegistry{
+	CollCategories: {
+		{Name: "userId_name", Keys: []IndexKey{{FieldName: "userId", Ascending: true}, {FieldName: "name", Ascending: true}}},
+	},
+	CollGifs: {
+		{Name: "userId_1", Keys: []IndexKey{{FieldName: "userId", Ascending: true}}},
+		{Name: "categoryId_1", Keys: []IndexKey{{FieldName: "categoryId", Ascending: true}}},
+		{Name: "userId_tags", Keys: []IndexKey{{FieldName: "userId", Ascending: true}, {FieldName: "tags", Ascending: true}}},
+		// a term found in the name of a gif is more relevant than one found in its tags
+		{
+			Name:    "name_tags_text",
+			Keys:    []IndexKey{{FieldName: "name", Text: true}, {FieldName: "tags", Text: true}},
+			Weights: map[string]int32{"name": 3, "tags": 1},
+		},
+	},
+	CollGroups: {
+		{Name: "userId_1", Keys: []IndexKey{{FieldName: "userId", Ascending: true}}},
+		{Name: "members.userId_1", Keys: []IndexKey{{FieldName: "members.userId", Ascending: true}}},
+	},
+	CollShares: {
+		// keeps a gif or category from being shared twice with the same group, mongo indexes a missing field as null,
+		// so the gif shares and the category shares don't collide
+		{
+			Name: "group_gif_category_unique",
+			Keys: []IndexKey{
+				{FieldName: "groupId", Ascending: true},
+				{FieldName: "gifId", Ascending: true},
+				{FieldName: "categoryId", Ascending: true},
+			},
+			Unique: true,
+		},
+		{Name: "userId_1", Keys: []IndexKey{{FieldName: "userId", Ascending: true}}},
+		{Name: "gifId_1", Keys: []IndexKey{{FieldName: "gifId", Ascending: true}}},
+		{Name: "categoryId_1", Keys: []IndexKey{{FieldName: "categoryId", Ascending: true}}},
+	},
+	CollSessions: {
+		// mongo deletes the sessions once they can no longer be refreshed
+		{Name: "refreshExpiresAt_ttl", Keys: []IndexKey{{FieldName: "refreshExpiresAt", Ascending: true}}, TTL: true},
+	},
+	CollUsers: {
+		// the same email can't be registered twice
+		{Name: "username_unique", Keys: []IndexKey{{FieldName: "username", Ascending: true}}, Unique: true},
+	},
+}
+
+// idIndexName is the name of the index mongo creates on _id, it is never part of a registry.
+const idIndexName = "_id_"
+
+// IndexDrift is a difference between the registry and the indexes of the database.
+type IndexDrift struct {
+	Collection string
+	Name       string
+	Reason     string
+}
+
+func (d IndexDrift) String() string {
+	return fmt.Sprintf("%s.%s %s", d.Collection, d.Name, d.Reason)
+}
+
+// IndexReport lists the indexes EnsureIndexes created, as collection.name, and the drift it couldn't fix.
+type IndexReport struct {
+	Created []string
+	Drift   []IndexDrift
+}
+
+func (r IndexReport) HasDrift() bool {
+	return len(r.Drift) > 0
+}
+
+// EnsureIndexes creates the indexes of the registry that are missing. An index that exists with another definition,
+// or under another name, is reported as drift and left alone, since changing it means dropping it first, as are the
+// indexes that are not in the registry.
+func EnsureIndexes(ctx context.Context, d DAL, registry IndexRegistry) (IndexReport, error) {
+	return syncIndexes(ctx, d, registry, true)
+}
+
+// CheckIndexes reports how the database differs from the registry without changing it, missing indexes are drift.
+func CheckIndexes(ctx context.Context, d DAL, registry IndexRegistry) (IndexReport, error) {
+	return syncIndexes(ctx, d, registry, false)
+}
+
+func syncIndexes(ctx context.Context, d DAL, registry IndexRegistry, create bool) (IndexReport, error) {
+	report := IndexReport{Created: make([]string, 0), Drift: make([]IndexDrift, 0)}
+
+	collections := make([]string, 0, len(registry))
+	for collection := range registry {
+		collections = append(collections, collection)
+	}
+	sort.Strings(collections)
+
+	for _, collection := range collections {
+		existing, err := d.ListIndexes(ctx, collection)
+		if err != nil {
+			return report, err
+		}
+		existingByName := make(map[string]Index, len(existing))
+		for _, index := range existing {
+			existingByName[index.Name] = index
+		}
+
+		declared := make(map[string]bool, len(registry[collection]))
+		for _, index := range registry[collection] {
+			declared[index.Name] = true
+
+			current, exists := existingByName[index.Name]
+			switch {
+			case exists && !IndexesEqual(current, index):
+				report.Drift = append(report.Drift, IndexDrift{Collection: collection, Name: index.Name, Reason: "differs from the registry, drop it to recreate it"})
+			case exists:
+			case findEqualIndex(existing, index) != "":
+				report.Drift = append(report.Drift, IndexDrift{Collection: collection, Name: index.Name, Reason: "exists as " + findEqualIndex(existing, index)})
+			case !create:
+				report.Drift = append(report.Drift, IndexDrift{Collection: collection, Name: index.Name, Reason: "is missing"})
+			default:
+				if err := d.EnsureIndex(ctx, collection, index); err != nil {
+					return report, err
+				}
+				report.Created = append(report.Created, collection+"."+index.Name)
+			}
+		}
+
+		for _, index := range existing {
+			if index.Name != idIndexName && !declared[index.Name] {
+				report.Drift = append(report.Drift, IndexDrift{Collection: collection, Name: index.Name, Reason: "is not in the registry"})
+			}
+		}
+	}
+	return report, nil
+}
+
+// findEqualIndex returns the name of the index with the same definition as the expected one, but another name.
+func findEqualIndex(existing []Index, expected Index) string {
+	for _, index := range existing {
+		if index.Name != expected.Name && IndexesEqual(index, expected) {
+			return index.Name
+		}
+	}
+	return ""
+}
+
+// IndexesEqual compares the definitions of the indexes, not their names. Mongo doesn't keep the order of text keys,
+// so they are compared with their weights regardless of the order.
+func IndexesEqual(a, b Index) bool {
+	if a.Unique != b.Unique || a.TTL != b.TTL || a.ExpireAfter != b.ExpireAfter {
+		return false
+	}
+	return reflect.DeepEqual(a.keyNames(), b.keyNames()) && reflect.DeepEqual(a.textWeights(), b.textWeights())
+}
+
+// keyNames returns the keys that are not text keys with their direction, e.g. userId_1.
+func (index Index) keyNames() []string {
+	names := make([]string, 0, len(index.Keys))
+	for _, key := range index.Keys {
+		switch {
+		case key.Text:
+		case key.Ascending:
+			names = append(names, key.FieldName+"_1")
+		default:
+			names = append(names, key.FieldName+"_-1")
+		}
+	}
+	return names
+}
+
+func (index Index) textWeights() map[string]float64 {
+	weights := make(map[string]float64)
+	for _, key := range index.Keys {
+		if key.Text {
+			weights[key.FieldName] = index.weight(key.FieldName)
+		}
+	}
+	return weights
+}
@@ -0,0 +1,116 @@
+package dal_test
+
+import (
+	"context"
+	"gifmanager-backend/dal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+var testRegistry = dal.IndexRegistry{
+	dal.CollGifs: {
+		{Name: "userId_1", Keys: []dal.IndexKey{{FieldName: "userId", Ascending: true}}},
+		{Name: "name_text", Keys: []dal.IndexKey{{FieldName: "name", Text: true}}, Weights: map[string]int32{"name": 2}},
+	},
+	dal.CollUsers: {
+		{Name: "username_unique", Keys: []dal.IndexKey{{FieldName: "username", Ascending: true}}, Unique: true},
+	},
+}
+
+func TestEnsureIndexes_CreatesMissingIndexesOnce(t *testing.T) {
+	// 1.ARRANGE
+	memoryDal := dal.NewMemoryDal()
+
+	// 2.ACT
+	first, errFirst := dal.EnsureIndexes(context.Background(), memoryDal, testRegistry)
+	second, errSecond := dal.EnsureIndexes(context.Background(), memoryDal, testRegistry)
+
+	// 3.ASSERT
+	require.Nil(t, errFirst)
+	assert.Equal(t, []string{"gifs.userId_1", "gifs.name_text", "users.username_unique"}, first.Created)
+	assert.False(t, first.HasDrift())
+
+	require.Nil(t, errSecond)
+	assert.Empty(t, second.Created)
+	assert.False(t, second.HasDrift())
+
+	indexes, err := memoryDal.ListIndexes(context.Background(), dal.CollGifs)
+	require.Nil(t, err)
+	assert.Len(t, indexes, 3)
+}
+
+func TestEnsureIndexes_ReportsDrift(t *testing.T) {
+	// 1.ARRANGE
+	memoryDal := dal.NewMemoryDal()
+	ctx := context.Background()
+	// the text index weighs the name differently, the username index has another name and likes_1 isn't declared
+	require.Nil(t, memoryDal.EnsureIndex(ctx, dal.CollGifs, dal.Index{Name: "name_text", Keys: []dal.IndexKey{{FieldName: "name", Text: true}}}))
+	require.Nil(t, memoryDal.EnsureIndex(ctx, dal.CollGifs, dal.Index{Name: "likes_1", Keys: []dal.IndexKey{{FieldName: "likes", Ascending: true}}}))
+	require.Nil(t, memoryDal.EnsureIndex(ctx, dal.CollUsers, dal.Index{Name: "username", Keys: []dal.IndexKey{{FieldName: "username", Ascending: true}}, Unique: true}))
+
+	// 2.ACT
+	report, err := dal.EnsureIndexes(ctx, memoryDal, testRegistry)
+
+	// 3.ASSERT
+	require.Nil(t, err)
+	assert.Equal(t, []string{"gifs.userId_1"}, report.Created)
+	assert.Equal(t, []dal.IndexDrift{
+		{Collection: dal.CollGifs, Name: "name_text", Reason: "differs from the registry, drop it to recreate it"},
+		{Collection: dal.CollGifs, Name: "likes_1", Reason: "is not in the registry"},
+		{Collection: dal.CollUsers, Name: "username_unique", Reason: "exists as username"},
+		{Collection: dal.CollUsers, Name: "username", Reason: "is not in the registry"},
+	}, report.Drift)
+}
+
+func TestCheckIndexes_ReportsMissingIndexesWithoutCreatingThem(t *testing.T) {
+	// 1.ARRANGE
+	memoryDal := dal.NewMemoryDal()
+	ctx := context.Background()
+	require.Nil(t, memoryDal.EnsureIndex(ctx, dal.CollUsers, testRegistry[dal.CollUsers][0]))
+
+	// 2.ACT
+	report, err := dal.CheckIndexes(ctx, memoryDal, testRegistry)
+
+	// 3.ASSERT
+	require.Nil(t, err)
+	assert.Empty(t, report.Created)
+	assert.Equal(t, []dal.IndexDrift{
+		{Collection: dal.CollGifs, Name: "userId_1", Reason: "is missing"},
+		{Collection: dal.CollGifs, Name: "name_text", Reason: "is missing"},
+	}, report.Drift)
+
+	indexes, errList := memoryDal.ListIndexes(ctx, dal.CollGifs)
+	require.Nil(t, errList)
+	assert.Len(t, indexes, 1)
+}
+
+func TestIndexesEqual_TextKeysInAnyOrderWithDefaultWeights(t *testing.T) {
+	declared := dal.Index{
+		Name:    "name_tags_text",
+		Keys:    []dal.IndexKey{{FieldName: "name", Text: true}, {FieldName: "tags", Text: true}},
+		Weights: map[string]int32{"name": 3},
+	}
+	listed := dal.Index{
+		Name:    "name_tags_text",
+		Keys:    []dal.IndexKey{{FieldName: "tags", Text: true}, {FieldName: "name", Text: true}},
+		Weights: map[string]int32{"name": 3, "tags": 1},
+	}
+
+	assert.True(t, dal.IndexesEqual(declared, listed))
+	listed.Weights["tags"] = 2
+	assert.False(t, dal.IndexesEqual(declared, listed))
+}
+
+func TestIndexesEqual_ComparesExpiry(t *testing.T) {
+	plain := dal.Index{Name: "refreshExpiresAt_ttl", Keys: []dal.IndexKey{{FieldName: "refreshExpiresAt", Ascending: true}}}
+	ttl := plain
+	ttl.TTL = true
+	delayed := ttl
+	delayed.ExpireAfter = time.Hour
+
+	assert.True(t, dal.IndexesEqual(ttl, ttl))
+	assert.False(t, dal.IndexesEqual(plain, ttl))
+	assert.False(t, dal.IndexesEqual(ttl, delayed))
+}
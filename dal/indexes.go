@@ -0,0 +1,63 @@
+package dal
+
+import (
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"time"
+)
+
+// ErrDuplicateKey is wrapped by the errors of writes that violate a unique index.
+var ErrDuplicateKey = errors.New("duplicate key")
+
+// IndexKey is a key of an index. Text keys make the field searchable with DAL.Search, Ascending is ignored for them.
+type IndexKey struct {
+	FieldName string
+	Ascending bool
+	Text      bool
+}
+
+// Index describes an index of a collection. Weights are the relevance of the text keys, 1 by default, e.g. a term found
+// in a field with weight 2 counts twice as much as one found in a field with weight 1.
+// TTL indexes make mongo delete the documents ExpireAfter after the date of their only key, at the date itself when
+// ExpireAfter is 0. Mongo removes them in the background, about once a minute, the in-memory DAL never does.
+type Index struct {
+	Name        string
+	Keys        []IndexKey
+	Unique      bool
+	Weights     map[string]int32
+	TTL         bool
+	ExpireAfter time.Duration
+}
+
+func (index Index) ToMongoKeys() bson.D {
+	keys := bson.D{}
+	for _, key := range index.Keys {
+		switch {
+		case key.Text:
+			keys = append(keys, bson.E{Key: key.FieldName, Value: "text"})
+		case key.Ascending:
+			keys = append(keys, bson.E{Key: key.FieldName, Value: 1})
+		default:
+			keys = append(keys, bson.E{Key: key.FieldName, Value: -1})
+		}
+	}
+	return keys
+}
+
+// IsText reports whether the index has text keys, a collection can have only one text index.
+func (index Index) IsText() bool {
+	for _, key := range index.Keys {
+		if key.Text {
+			return true
+		}
+	}
+	return false
+}
+
+// weight returns the weight of a text key.
+func (index Index) weight(fieldName string) float64 {
+	if weight, ok := index.Weights[fieldName]; ok {
+		return float64(weight)
+	}
+	return 1
+}
@@ -0,0 +1,577 @@
+package dal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// MemoryDal is a thread-safe DAL implementation that keeps every collection in memory.
+// It understands the subset of the mongo query and update language used by the handlers,
+// so the whole server can run without a database.
+type MemoryDal struct {
+	mu          sync.RWMutex
+	collections map[string][]bson.M
+	indexes     map[string][]Index
+}
+
+func NewMemoryDal() *MemoryDal {
+	return &MemoryDal{
+		collections: make(map[string][]bson.M),
+		indexes:     make(map[string][]Index),
+	}
+}
+
+func (m *MemoryDal) Disconnect(ctx context.Context) error {
+	return nil
+}
+
+func (m *MemoryDal) Insert(ctx context.Context, collection string, documents []any) (*InsertResult, error) {
+	newDocuments := make([]bson.M, 0, len(documents))
+	for _, document := range documents {
+		doc, err := toDocument(document)
+		if err != nil {
+			return nil, fmt.Errorf("error while inserting documnets in %s: %w", collection, err)
+		}
+		if _, ok := doc["_id"]; !ok {
+			doc["_id"] = primitive.NewObjectID()
+		}
+		newDocuments = append(newDocuments, doc)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	candidates := m.collections[collection]
+	for _, doc := range newDocuments {
+		if err := m.checkUniqueIndexes(collection, candidates, doc, -1); err != nil {
+			return nil, fmt.Errorf("error while inserting documnets in %s: %w", collection, err)
+		}
+		candidates = append(candidates, doc)
+	}
+	m.collections[collection] = candidates
+	for _, doc := range newDocuments {
+		recordUndo(ctx, collection, doc["_id"], nil)
+	}
+
+	return &InsertResult{
+		InsertedDocumentsCount: len(newDocuments),
+	}, nil
+}
+
+func (m *MemoryDal) Find(ctx context.Context, collection string, findArguments FindArguments, result any) error {
+	filter, err := toDocument(findArguments.Filter)
+	if err != nil {
+		return fmt.Errorf("error finding documents in %s: %w", collection, err)
+	}
+
+	m.mu.RLock()
+	matched, err := m.filterDocuments(collection, filter)
+	m.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("error finding documents in %s: %w", collection, err)
+	}
+
+	if findArguments.Sort != nil {
+		sortDocuments(matched, findArguments.Sort)
+	}
+
+	matched, err = skipDocuments(matched, findArguments.Skip)
+	if err != nil {
+		return fmt.Errorf("error finding documents in %s: %w", collection, err)
+	}
+
+	if findArguments.Limit != nil && *findArguments.Limit > 0 && int(*findArguments.Limit) < len(matched) {
+		matched = matched[:*findArguments.Limit]
+	}
+
+	if findArguments.Projection != nil {
+		for i, doc := range matched {
+			matched[i] = applyProjection(doc, findArguments.Projection)
+		}
+	}
+
+	return decodeDocuments(matched, result)
+}
+
+func (m *MemoryDal) Count(ctx context.Context, collection string, filter any) (int64, error) {
+	filterDocument, err := toDocument(filter)
+	if err != nil {
+		return 0, fmt.Errorf("error counting documents in %s: %w", collection, err)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	matched, err := m.filterDocuments(collection, filterDocument)
+	if err != nil {
+		return 0, fmt.Errorf("error counting documents in %s: %w", collection, err)
+	}
+	return int64(len(matched)), nil
+}
+
+func (m *MemoryDal) FindByID(ctx context.Context, collection string, id string, result any) error {
+	objId, _ := primitive.ObjectIDFromHex(id)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	index := m.indexOfID(collection, objId)
+	if index < 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return decodeDocument(m.collections[collection][index], result)
+}
+
+func (m *MemoryDal) Delete(ctx context.Context, collection string, filter any) (*DeleteResult, error) {
+	filterDoc, err := toDocument(filter)
+	if err != nil {
+		return nil, fmt.Errorf("Error deleting documents in %s, %w", collection, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	kept := make([]bson.M, 0, len(m.collections[collection]))
+	var deletedCount int64
+	for _, doc := range m.collections[collection] {
+		matches, err := matchDocument(doc, filterDoc)
+		if err != nil {
+			return nil, fmt.Errorf("Error deleting documents in %s, %w", collection, err)
+		}
+		if matches {
+			recordUndo(ctx, collection, doc["_id"], doc)
+			deletedCount++
+			continue
+		}
+		kept = append(kept, doc)
+	}
+	m.collections[collection] = kept
+
+	return &DeleteResult{
+		DeletedCount: deletedCount,
+	}, nil
+}
+
+func (m *MemoryDal) FindAndDeleteByID(ctx context.Context, collection string, id string, document interface{}) error {
+	objId, _ := primitive.ObjectIDFromHex(id)
+
+	return m.FindAndDelete(ctx, collection, bson.M{"_id": objId}, document)
+}
+
+func (m *MemoryDal) FindAndDelete(ctx context.Context, collection string, filter any, document any) error {
+	filterDoc, err := toDocument(filter)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, doc := range m.collections[collection] {
+		matches, err := matchDocument(doc, filterDoc)
+		if err != nil {
+			return err
+		}
+		if !matches {
+			continue
+		}
+
+		m.collections[collection] = append(m.collections[collection][:i], m.collections[collection][i+1:]...)
+		recordUndo(ctx, collection, doc["_id"], doc)
+		return decodeDocument(doc, document)
+	}
+
+	return mongo.ErrNoDocuments
+}
+
+func (m *MemoryDal) Aggregate(ctx context.Context, collection string, pipeline []any, result any) error {
+	m.mu.RLock()
+	documents, _ := m.filterDocuments(collection, bson.M{})
+	aggregated, err := EvaluatePipeline(documents, pipeline, func(from string) ([]bson.M, error) {
+		return m.filterDocuments(from, bson.M{})
+	})
+	m.mu.RUnlock()
+
+	if err != nil {
+		return fmt.Errorf("error finding documents in %s: %w", collection, err)
+	}
+
+	if err := decodeDocuments(aggregated, result); err != nil {
+		return fmt.Errorf("error reading results %w", err)
+	}
+	return nil
+}
+
+func (m *MemoryDal) Update(ctx context.Context, collection string, filter any, update any, optionFuncs ...UpdateOptionsFunc) (*UpdateResult, error) {
+	filterDoc, err := toDocument(filter)
+	if err != nil {
+		return nil, fmt.Errorf("error while updating document in %s: %w", collection, err)
+	}
+
+	return m.updateDocuments(ctx, collection, filterDoc, update, optionFuncs)
+}
+
+func (m *MemoryDal) UpdateByID(ctx context.Context, collection string, id string, update any, optionFuncs ...UpdateOptionsFunc) (*UpdateResult, error) {
+	objID, _ := primitive.ObjectIDFromHex(id)
+
+	return m.updateDocuments(ctx, collection, bson.M{"_id": objID}, update, optionFuncs)
+}
+
+func (m *MemoryDal) updateDocuments(ctx context.Context, collection string, filter bson.M, update any, optionFuncs []UpdateOptionsFunc) (*UpdateResult, error) {
+	opts := UpdateOptions{}
+	for _, optFunc := range optionFuncs {
+		optFunc(&opts)
+	}
+
+	updateDoc, err := toDocument(update)
+	if err != nil {
+		return nil, fmt.Errorf("error while updating document in %s: %w", collection, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := &UpdateResult{}
+	for i, doc := range m.collections[collection] {
+		matches, err := matchDocument(doc, filter)
+		if err != nil {
+			return nil, fmt.Errorf("error while updating document in %s: %w", collection, err)
+		}
+		if !matches {
+			continue
+		}
+
+		updated := copyDocument(doc)
+		if err := applyUpdate(updated, updateDoc, false); err != nil {
+			return nil, fmt.Errorf("error while updating document in %s: %w", collection, err)
+		}
+
+		if err := m.checkUniqueIndexes(collection, m.collections[collection], updated, i); err != nil {
+			return nil, fmt.Errorf("error while updating document in %s: %w", collection, err)
+		}
+
+		result.MatchedCount++
+		if !documentsEqual(doc, updated) {
+			recordUndo(ctx, collection, doc["_id"], doc)
+			m.collections[collection][i] = updated
+			result.ModifiedCount++
+		}
+		if !opts.Multi {
+			return result, nil
+		}
+	}
+
+	if result.MatchedCount > 0 || !opts.Upsert {
+		return result, nil
+	}
+
+	upserted := equalityFields(filter)
+	if err := applyUpdate(upserted, updateDoc, true); err != nil {
+		return nil, fmt.Errorf("error while updating document in %s: %w", collection, err)
+	}
+	if _, ok := upserted["_id"]; !ok {
+		upserted["_id"] = primitive.NewObjectID()
+	}
+	if err := m.checkUniqueIndexes(collection, m.collections[collection], upserted, -1); err != nil {
+		return nil, fmt.Errorf("error while updating document in %s: %w", collection, err)
+	}
+	m.collections[collection] = append(m.collections[collection], upserted)
+	recordUndo(ctx, collection, upserted["_id"], nil)
+
+	return &UpdateResult{
+		UpsertedCount: 1,
+		UpsertedID:    upserted["_id"],
+	}, nil
+}
+
+func (m *MemoryDal) EnsureIndex(ctx context.Context, collection string, index Index) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, existing := range m.indexes[collection] {
+		if existing.Name != index.Name {
+			continue
+		}
+		if !reflect.DeepEqual(existing, index) {
+			return fmt.Errorf("error while creating index %s in %s: an index with the same name but different options exists", index.Name, collection)
+		}
+		return nil
+	}
+
+	if index.Unique {
+		documents := m.collections[collection]
+		for i, doc := range documents {
+			if findUniqueConflict(documents, doc, i, index) {
+				return fmt.Errorf("error while creating index %s in %s: %w", index.Name, collection, ErrDuplicateKey)
+			}
+		}
+	}
+
+	m.indexes[collection] = append(m.indexes[collection], index)
+	return nil
+}
+
+func (m *MemoryDal) DropIndex(ctx context.Context, collection string, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, index := range m.indexes[collection] {
+		if index.Name == name {
+			m.indexes[collection] = append(m.indexes[collection][:i], m.indexes[collection][i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("error while dropping index %s in %s: index not found", name, collection)
+}
+
+func (m *MemoryDal) ListIndexes(ctx context.Context, collection string) ([]Index, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	indexes := []Index{{Name: idIndexName, Keys: []IndexKey{{FieldName: "_id", Ascending: true}}, Unique: true}}
+	return append(indexes, m.indexes[collection]...), nil
+}
+
+// Search scores the documents with textScore, which imitates mongo's text score, so the results can be tested offline.
+func (m *MemoryDal) Search(ctx context.Context, collection string, searchArguments SearchArguments, result any) (int64, error) {
+	filter, err := toDocument(searchArguments.Filter)
+	if err != nil {
+		return 0, fmt.Errorf("error searching documents in %s: %w", collection, err)
+	}
+
+	m.mu.RLock()
+	index, hasTextIndex := m.textIndex(collection)
+	matched, err := m.filterDocuments(collection, filter)
+	m.mu.RUnlock()
+	if !hasTextIndex {
+		return 0, fmt.Errorf("error searching documents in %s: %w", collection, ErrNoTextIndex)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error searching documents in %s: %w", collection, err)
+	}
+
+	terms := Tokenize(searchArguments.Text)
+	found := make([]bson.M, 0, len(matched))
+	for _, doc := range matched {
+		if score := textScore(index, doc, terms); score > 0 {
+			doc[ScoreField] = score
+			found = append(found, doc)
+		}
+	}
+	sortDocuments(found, Sorts{{FieldName: ScoreField, Ascending: false}, {FieldName: "_id", Ascending: true}})
+	total := int64(len(found))
+
+	found, err = skipDocuments(found, searchArguments.Skip)
+	if err != nil {
+		return 0, fmt.Errorf("error searching documents in %s: %w", collection, err)
+	}
+	if searchArguments.Limit != nil && *searchArguments.Limit > 0 && int(*searchArguments.Limit) < len(found) {
+		found = found[:*searchArguments.Limit]
+	}
+
+	return total, decodeDocuments(found, result)
+}
+
+// textIndex returns the text index of the collection. The caller must hold the lock.
+func (m *MemoryDal) textIndex(collection string) (Index, bool) {
+	for _, index := range m.indexes[collection] {
+		if index.IsText() {
+			return index, true
+		}
+	}
+	return Index{}, false
+}
+
+// checkUniqueIndexes returns ErrDuplicateKey when doc has the same _id or unique index key as one of the documents.
+// ignore is the position of the document doc replaces, or -1. The caller must hold the lock.
+func (m *MemoryDal) checkUniqueIndexes(collection string, documents []bson.M, doc bson.M, ignore int) error {
+	idIndex := Index{Name: idIndexName, Keys: []IndexKey{{FieldName: "_id", Ascending: true}}, Unique: true}
+	for _, index := range append([]Index{idIndex}, m.indexes[collection]...) {
+		if index.Unique && findUniqueConflict(documents, doc, ignore, index) {
+			return fmt.Errorf("%w: index %s", ErrDuplicateKey, index.Name)
+		}
+	}
+	return nil
+}
+
+func findUniqueConflict(documents []bson.M, doc bson.M, ignore int, index Index) bool {
+	for i, other := range documents {
+		if i == ignore {
+			continue
+		}
+
+		sameKey := true
+		for _, key := range index.Keys {
+			if !valuesEqual(firstValue(doc, key.FieldName), firstValue(other, key.FieldName)) {
+				sameKey = false
+				break
+			}
+		}
+		if sameKey {
+			return true
+		}
+	}
+	return false
+}
+
+// filterDocuments returns copies of the documents in the collection that match the filter.
+// The caller must hold the read lock.
+func (m *MemoryDal) filterDocuments(collection string, filter bson.M) ([]bson.M, error) {
+	matched := make([]bson.M, 0)
+	for _, doc := range m.collections[collection] {
+		matches, err := matchDocument(doc, filter)
+		if err != nil {
+			return nil, err
+		}
+		if matches {
+			matched = append(matched, copyDocument(doc))
+		}
+	}
+	return matched, nil
+}
+
+// indexOfID returns the position of the document with the given _id or -1.
+// The caller must hold the lock.
+func (m *MemoryDal) indexOfID(collection string, id any) int {
+	for i, doc := range m.collections[collection] {
+		if valuesEqual(doc["_id"], id) {
+			return i
+		}
+	}
+	return -1
+}
+
+// toDocument converts bson.M, bson.D, structs and pointers to structs to a bson.M
+// holding the same values mongo would store, e.g. int32 instead of int and primitive.DateTime instead of time.Time.
+func toDocument(value any) (bson.M, error) {
+	if value == nil {
+		return bson.M{}, nil
+	}
+
+	raw, err := bson.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := bson.M{}
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// skipDocuments drops the first skip documents, like mongo it refuses a negative skip.
+func skipDocuments(documents []bson.M, skip *int64) ([]bson.M, error) {
+	if skip == nil {
+		return documents, nil
+	}
+	if *skip < 0 {
+		return nil, ErrNegativeSkip
+	}
+	if *skip > int64(len(documents)) {
+		return documents[len(documents):], nil
+	}
+	return documents[*skip:], nil
+}
+
+func copyDocument(doc bson.M) bson.M {
+	copied, err := toDocument(doc)
+	if err != nil {
+		// documents in the store were produced by toDocument, so they can always be marshalled again
+		panic(err)
+	}
+	return copied
+}
+
+// documentsEqual compares the encodings of the documents. Maps are encoded in a random order, so the fields are sorted first.
+func documentsEqual(a, b bson.M) bool {
+	rawA, errA := bson.Marshal(sortFields(a))
+	rawB, errB := bson.Marshal(sortFields(b))
+	return errA == nil && errB == nil && bytes.Equal(rawA, rawB)
+}
+
+// sortFields returns the value with the fields of its maps, and of the maps nested in it, sorted by name.
+func sortFields(value any) any {
+	switch typed := value.(type) {
+	case bson.M:
+		return sortMapFields(typed)
+	case map[string]any:
+		return sortMapFields(typed)
+	case bson.D:
+		sorted := make(bson.D, 0, len(typed))
+		for _, elem := range typed {
+			sorted = append(sorted, bson.E{Key: elem.Key, Value: sortFields(elem.Value)})
+		}
+		return sorted
+	case bson.A:
+		sorted := make(bson.A, 0, len(typed))
+		for _, elem := range typed {
+			sorted = append(sorted, sortFields(elem))
+		}
+		return sorted
+	case []any:
+		sorted := make(bson.A, 0, len(typed))
+		for _, elem := range typed {
+			sorted = append(sorted, sortFields(elem))
+		}
+		return sorted
+	}
+	return value
+}
+
+func sortMapFields(doc map[string]any) bson.D {
+	keys := make([]string, 0, len(doc))
+	for key := range doc {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	sorted := make(bson.D, 0, len(doc))
+	for _, key := range keys {
+		sorted = append(sorted, bson.E{Key: key, Value: sortFields(doc[key])})
+	}
+	return sorted
+}
+
+func decodeDocument(doc bson.M, result any) error {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return bson.Unmarshal(raw, result)
+}
+
+// decodeDocuments decodes the documents into the slice result points to, the same way mongo's cursor.All does.
+func decodeDocuments(docs []bson.M, result any) error {
+	resultValue := reflect.ValueOf(result)
+	if resultValue.Kind() != reflect.Ptr || resultValue.IsNil() {
+		return errors.New("result argument must be a pointer to a slice")
+	}
+
+	sliceValue := resultValue.Elem()
+	if sliceValue.Kind() != reflect.Slice {
+		return errors.New("result argument must be a pointer to a slice")
+	}
+
+	elemType := sliceValue.Type().Elem()
+	decoded := reflect.MakeSlice(sliceValue.Type(), 0, len(docs))
+	for _, doc := range docs {
+		elem := reflect.New(elemType)
+		if err := decodeDocument(doc, elem.Interface()); err != nil {
+			return err
+		}
+		decoded = reflect.Append(decoded, elem.Elem())
+	}
+	sliceValue.Set(decoded)
+
+	return nil
+}
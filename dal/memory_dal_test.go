@@ -0,0 +1,406 @@
+package dal_test
+
+import (
+	"context"
+	"errors"
+	"gifmanager-backend/dal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"testing"
+)
+
+type testGif struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"`
+	Name       string             `bson:"name"`
+	Likes      int                `bson:"likes"`
+	IsFavorite bool               `bson:"isFavourite"`
+	UserId     primitive.ObjectID `bson:"userId"`
+	Tags       []string           `bson:"tags,omitempty"`
+}
+
+func insertTestGifs(t *testing.T, memoryDal *dal.MemoryDal, gifs ...testGif) {
+	documents := make([]any, 0, len(gifs))
+	for _, gif := range gifs {
+		documents = append(documents, gif)
+	}
+	_, err := memoryDal.Insert(context.Background(), dal.CollGifs, documents)
+	require.Nil(t, err)
+}
+
+func TestMemoryDal_Find_EvaluatesFilterOperators(t *testing.T) {
+	// 1. ARRANGE
+	userID := primitive.NewObjectID()
+	memoryDal := dal.NewMemoryDal()
+	insertTestGifs(t, memoryDal,
+		testGif{ID: primitive.NewObjectID(), Name: "Funny Cat", Likes: 10, UserId: userID, Tags: []string{"cat", "funny"}},
+		testGif{ID: primitive.NewObjectID(), Name: "spider-man", Likes: 3, UserId: userID, IsFavorite: true, Tags: []string{"funny"}},
+		testGif{ID: primitive.NewObjectID(), Name: "dog", Likes: 7, UserId: primitive.NewObjectID()},
+	)
+
+	testCases := []struct {
+		name          string
+		filter        bson.M
+		expectedNames []string
+	}{
+		{"equality", bson.M{"userId": userID}, []string{"Funny Cat", "spider-man"}},
+		{"regex", bson.M{"name": bson.M{"$regex": "cat", "$options": "i"}}, []string{"Funny Cat"}},
+		{"in", bson.M{"name": bson.M{"$in": []string{"dog", "spider-man"}}}, []string{"spider-man", "dog"}},
+		{"gt and lt", bson.M{"likes": bson.M{"$gt": 3, "$lt": 10}}, []string{"dog"}},
+		{"and", bson.M{"$and": bson.A{bson.M{"userId": userID}, bson.M{"isFavourite": true}}}, []string{"spider-man"}},
+		{"or", bson.M{"$or": bson.A{bson.M{"likes": 10}, bson.M{"name": "dog"}}}, []string{"Funny Cat", "dog"}},
+		{"array element", bson.M{"tags": "funny"}, []string{"Funny Cat", "spider-man"}},
+		{"all", bson.M{"tags": bson.M{"$all": bson.A{"funny", "cat"}}}, []string{"Funny Cat"}},
+		{"empty all", bson.M{"tags": bson.M{"$all": bson.A{}}}, []string{}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			// 2. ACT
+			var result []testGif
+			err := memoryDal.Find(context.Background(), dal.CollGifs, *dal.NewFindArguments().WithFilter(testCase.filter), &result)
+
+			// 3. ASSERT
+			require.Nil(t, err)
+			names := make([]string, 0, len(result))
+			for _, gif := range result {
+				names = append(names, gif.Name)
+			}
+			assert.Equal(t, testCase.expectedNames, names)
+		})
+	}
+}
+
+func TestMemoryDal_NegativeSkip_ExpectedError(t *testing.T) {
+	// 1. ARRANGE
+	memoryDal := dal.NewMemoryDal()
+	require.Nil(t, memoryDal.EnsureIndex(context.Background(), dal.CollGifs, dal.Index{
+		Name: "name_text",
+		Keys: []dal.IndexKey{{FieldName: "name", Text: true}},
+	}))
+	insertTestGifs(t, memoryDal, testGif{Name: "dog"})
+
+	// 2. ACT
+	var found []bson.M
+	errFind := memoryDal.Find(context.Background(), dal.CollGifs, *dal.NewFindArguments().WithSkip(-1), &found)
+	_, errSearch := memoryDal.Search(context.Background(), dal.CollGifs, *dal.NewSearchArguments("dog").WithSkip(-1), &found)
+
+	// 3. ASSERT
+	assert.ErrorIs(t, errFind, dal.ErrNegativeSkip)
+	assert.ErrorIs(t, errSearch, dal.ErrNegativeSkip)
+}
+
+func TestMemoryDal_Find_AppliesSortSkipLimitAndProjection(t *testing.T) {
+	// 1. ARRANGE
+	memoryDal := dal.NewMemoryDal()
+	insertTestGifs(t, memoryDal,
+		testGif{Name: "b", Likes: 2},
+		testGif{Name: "a", Likes: 3},
+		testGif{Name: "c", Likes: 1},
+	)
+
+	findArgs := dal.NewFindArguments().
+		WithSorts(dal.Sorts{{FieldName: "likes", Ascending: false}}).
+		WithSkip(1).
+		WithLimit(1).
+		WithProjection(dal.Projections{{FieldName: "name"}})
+
+	// 2. ACT
+	var result []bson.M
+	err := memoryDal.Find(context.Background(), dal.CollGifs, *findArgs, &result)
+
+	// 3. ASSERT
+	require.Nil(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, "b", result[0]["name"])
+	assert.NotContains(t, result[0], "likes")
+	assert.Contains(t, result[0], "_id")
+}
+
+func TestMemoryDal_Find_ProjectionOfOnlyID_ExpectedOnlyID(t *testing.T) {
+	// 1. ARRANGE
+	memoryDal := dal.NewMemoryDal()
+	insertTestGifs(t, memoryDal, testGif{ID: primitive.NewObjectID(), Name: "a", Likes: 1})
+
+	findArgs := dal.NewFindArguments().
+		WithProjection(dal.Projections{{FieldName: "_id"}})
+
+	// 2. ACT
+	var result []bson.M
+	err := memoryDal.Find(context.Background(), dal.CollGifs, *findArgs, &result)
+
+	// 3. ASSERT
+	require.Nil(t, err)
+	require.Len(t, result, 1)
+	assert.Contains(t, result[0], "_id")
+	assert.NotContains(t, result[0], "name")
+}
+
+func TestMemoryDal_Update_SetIncAndUpsert(t *testing.T) {
+	// 1. ARRANGE
+	memoryDal := dal.NewMemoryDal()
+	gif := testGif{ID: primitive.NewObjectID(), Name: "cat", Likes: 1}
+	insertTestGifs(t, memoryDal, gif)
+
+	// 2. ACT
+	result, errUpdate := memoryDal.UpdateByID(context.Background(), dal.CollGifs, gif.ID.Hex(),
+		bson.M{"$set": bson.M{"name": "dog"}, "$inc": bson.M{"likes": 2}})
+	upsertResult, errUpsert := memoryDal.Update(context.Background(), dal.CollGifs,
+		bson.M{"name": "parrot"}, bson.M{"$inc": bson.M{"likes": 1}}, dal.InsertIfNotFound)
+
+	// 3. ASSERT
+	require.Nil(t, errUpdate)
+	assert.Equal(t, int64(1), result.MatchedCount)
+	assert.Equal(t, int64(1), result.ModifiedCount)
+
+	var dbGif testGif
+	require.Nil(t, memoryDal.FindByID(context.Background(), dal.CollGifs, gif.ID.Hex(), &dbGif))
+	assert.Equal(t, "dog", dbGif.Name)
+	assert.Equal(t, 3, dbGif.Likes)
+
+	require.Nil(t, errUpsert)
+	assert.Equal(t, int64(1), upsertResult.UpsertedCount)
+	var upserted []testGif
+	require.Nil(t, memoryDal.Find(context.Background(), dal.CollGifs, *dal.NewFindArguments().WithFilter(bson.M{"name": "parrot"}), &upserted))
+	require.Len(t, upserted, 1)
+	assert.Equal(t, upsertResult.UpsertedID, upserted[0].ID)
+	assert.Equal(t, 1, upserted[0].Likes)
+}
+
+func TestMemoryDal_Update_PushAndPull(t *testing.T) {
+	// 1. ARRANGE
+	memoryDal := dal.NewMemoryDal()
+	id := primitive.NewObjectID()
+	_, errInsert := memoryDal.Insert(context.Background(), dal.CollGroups, []any{bson.M{"_id": id}})
+	require.Nil(t, errInsert)
+
+	push := func(member bson.M) {
+		_, err := memoryDal.Update(context.Background(), dal.CollGroups, bson.M{"_id": id}, bson.M{"$push": bson.M{"members": member}})
+		require.Nil(t, err)
+	}
+
+	// 2. ACT
+	push(bson.M{"name": "a", "role": "admin"})
+	push(bson.M{"name": "b", "role": "member"})
+	push(bson.M{"name": "c", "role": "member"})
+	_, errPull := memoryDal.Update(context.Background(), dal.CollGroups, bson.M{"_id": id},
+		bson.M{"$pull": bson.M{"members": bson.M{"role": "member", "name": bson.M{"$ne": "c"}}}})
+
+	// 3. ASSERT
+	require.Nil(t, errPull)
+	var result []struct {
+		Members []struct {
+			Name string `bson:"name"`
+		} `bson:"members"`
+	}
+	require.Nil(t, memoryDal.Find(context.Background(), dal.CollGroups, dal.FindArguments{}, &result))
+	require.Len(t, result, 1)
+	require.Len(t, result[0].Members, 2)
+	assert.Equal(t, "a", result[0].Members[0].Name)
+	assert.Equal(t, "c", result[0].Members[1].Name)
+}
+
+func TestMemoryDal_FindAndDeleteByID_RemovesDocument(t *testing.T) {
+	// 1. ARRANGE
+	memoryDal := dal.NewMemoryDal()
+	gif := testGif{ID: primitive.NewObjectID(), Name: "cat"}
+	insertTestGifs(t, memoryDal, gif)
+
+	// 2. ACT
+	var deleted testGif
+	err := memoryDal.FindAndDeleteByID(context.Background(), dal.CollGifs, gif.ID.Hex(), &deleted)
+
+	// 3. ASSERT
+	require.Nil(t, err)
+	assert.Equal(t, gif, deleted)
+	assert.Equal(t, mongo.ErrNoDocuments, memoryDal.FindByID(context.Background(), dal.CollGifs, gif.ID.Hex(), &deleted))
+	assert.Equal(t, mongo.ErrNoDocuments, memoryDal.FindAndDeleteByID(context.Background(), dal.CollGifs, gif.ID.Hex(), &deleted))
+}
+
+func TestMemoryDal_EnsureIndex_RejectsDuplicateKeys(t *testing.T) {
+	// 1. ARRANGE
+	memoryDal := dal.NewMemoryDal()
+	index := dal.Index{Name: "name_unique", Keys: []dal.IndexKey{{FieldName: "name", Ascending: true}}, Unique: true}
+	require.Nil(t, memoryDal.EnsureIndex(context.Background(), dal.CollGifs, index))
+	insertTestGifs(t, memoryDal, testGif{Name: "cat"})
+
+	// 2. ACT
+	_, errInsert := memoryDal.Insert(context.Background(), dal.CollGifs, []any{testGif{Name: "cat"}})
+	_, errUpsert := memoryDal.Update(context.Background(), dal.CollGifs, bson.M{"likes": 5}, bson.M{"$set": bson.M{"name": "cat"}}, dal.InsertIfNotFound)
+
+	// 3. ASSERT
+	assert.ErrorIs(t, errInsert, dal.ErrDuplicateKey)
+	assert.ErrorIs(t, errUpsert, dal.ErrDuplicateKey)
+}
+
+func TestMemoryDal_WithTransaction_RevertsWritesOnError(t *testing.T) {
+	// 1. ARRANGE
+	memoryDal := dal.NewMemoryDal()
+	updated := testGif{ID: primitive.NewObjectID(), Name: "cat", Likes: 1}
+	deleted := testGif{ID: primitive.NewObjectID(), Name: "dog", Likes: 2}
+	insertTestGifs(t, memoryDal, updated, deleted)
+	errAbort := errors.New("abort")
+
+	// 2. ACT
+	err := memoryDal.WithTransaction(context.Background(), func(txCtx context.Context) error {
+		_, errInsert := memoryDal.Insert(txCtx, dal.CollGifs, []any{testGif{Name: "parrot"}})
+		require.Nil(t, errInsert)
+		_, errUpdate := memoryDal.UpdateByID(txCtx, dal.CollGifs, updated.ID.Hex(), bson.M{"$inc": bson.M{"likes": 1}})
+		require.Nil(t, errUpdate)
+		_, errDelete := memoryDal.Delete(txCtx, dal.CollGifs, bson.M{"_id": deleted.ID})
+		require.Nil(t, errDelete)
+		return errAbort
+	})
+
+	// 3. ASSERT
+	assert.ErrorIs(t, err, errAbort)
+	var result []testGif
+	require.Nil(t, memoryDal.Find(context.Background(), dal.CollGifs, *dal.NewFindArguments(), &result))
+	assert.ElementsMatch(t, []testGif{updated, deleted}, result)
+}
+
+func TestMemoryDal_WithTransaction_KeepsWritesOnSuccess(t *testing.T) {
+	// 1. ARRANGE
+	memoryDal := dal.NewMemoryDal()
+
+	// 2. ACT
+	err := memoryDal.WithTransaction(context.Background(), func(txCtx context.Context) error {
+		_, errInsert := memoryDal.Insert(txCtx, dal.CollGifs, []any{testGif{Name: "parrot"}})
+		return errInsert
+	})
+
+	// 3. ASSERT
+	require.Nil(t, err)
+	count, errCount := memoryDal.Count(context.Background(), dal.CollGifs, bson.M{"name": "parrot"})
+	require.Nil(t, errCount)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestMemoryDal_Update_AllMatching(t *testing.T) {
+	// 1. ARRANGE
+	memoryDal := dal.NewMemoryDal()
+	insertTestGifs(t, memoryDal,
+		testGif{Name: "cat", Likes: 1},
+		testGif{Name: "cat", Likes: 2},
+		testGif{Name: "dog", Likes: 3},
+	)
+
+	// 2. ACT
+	result, err := memoryDal.Update(context.Background(), dal.CollGifs, bson.M{"name": "cat"}, bson.M{"$set": bson.M{"likes": 0}}, dal.UpdateAllMatching)
+
+	// 3. ASSERT
+	require.Nil(t, err)
+	assert.Equal(t, int64(2), result.MatchedCount)
+	assert.Equal(t, int64(2), result.ModifiedCount)
+	count, errCount := memoryDal.Count(context.Background(), dal.CollGifs, bson.M{"likes": 0})
+	require.Nil(t, errCount)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestTokenize(t *testing.T) {
+	assert.Equal(t, []string{"spider", "man", "2", "über"}, dal.Tokenize("  Spider-Man 2: ÜBER!"))
+	assert.Empty(t, dal.Tokenize(" -- "))
+}
+
+func TestMemoryDal_Search_RanksByWeightedScore(t *testing.T) {
+	// 1.ARRANGE
+	memoryDal := dal.NewMemoryDal()
+	require.Nil(t, memoryDal.EnsureIndex(context.Background(), dal.CollGifs, dal.Index{
+		Name:    "name_tags_text",
+		Keys:    []dal.IndexKey{{FieldName: "name", Text: true}, {FieldName: "tags", Text: true}},
+		Weights: map[string]int32{"name": 3},
+	}))
+	userID := primitive.NewObjectID()
+	insertTestGifs(t, memoryDal,
+		testGif{ID: primitive.NewObjectID(), Name: "dancing dog", UserId: userID, Tags: []string{"cat"}},
+		testGif{ID: primitive.NewObjectID(), Name: "Cat", UserId: userID},
+		testGif{ID: primitive.NewObjectID(), Name: "cat and dog party", UserId: userID, Tags: []string{"funny"}},
+		testGif{ID: primitive.NewObjectID(), Name: "no match", UserId: userID, Tags: []string{"catalog"}},
+		testGif{ID: primitive.NewObjectID(), Name: "cat of another user", UserId: primitive.NewObjectID()},
+	)
+
+	var found []struct {
+		Name  string  `bson:"name"`
+		Score float64 `bson:"score"`
+	}
+	searchArgs := dal.NewSearchArguments("CAT").WithFilter(bson.M{"userId": userID})
+
+	// 2.ACT
+	total, err := memoryDal.Search(context.Background(), dal.CollGifs, *searchArgs, &found)
+
+	// 3.ASSERT
+	require.Nil(t, err)
+	assert.Equal(t, int64(3), total)
+	require.Len(t, found, 3)
+	// a name that is only the term beats a longer name, which beats a tag because names weigh 3 times as much
+	assert.Equal(t, "Cat", found[0].Name)
+	assert.InDelta(t, 3*1.1, found[0].Score, 0.0001)
+	assert.Equal(t, "cat and dog party", found[1].Name)
+	assert.InDelta(t, 3*0.625, found[1].Score, 0.0001)
+	assert.Equal(t, "dancing dog", found[2].Name)
+	assert.InDelta(t, 1.1, found[2].Score, 0.0001)
+}
+
+func TestMemoryDal_Search_SkipAndLimit(t *testing.T) {
+	// 1.ARRANGE
+	memoryDal := dal.NewMemoryDal()
+	require.Nil(t, memoryDal.EnsureIndex(context.Background(), dal.CollGifs, dal.Index{
+		Name: "name_text",
+		Keys: []dal.IndexKey{{FieldName: "name", Text: true}},
+	}))
+	insertTestGifs(t, memoryDal,
+		testGif{ID: primitive.NewObjectID(), Name: "dog"},
+		testGif{ID: primitive.NewObjectID(), Name: "dog dog"},
+		testGif{ID: primitive.NewObjectID(), Name: "dog and cat"},
+	)
+
+	var found []testGif
+	searchArgs := dal.NewSearchArguments("dog").WithSkip(1).WithLimit(1)
+
+	// 2.ACT
+	total, err := memoryDal.Search(context.Background(), dal.CollGifs, *searchArgs, &found)
+
+	// 3.ASSERT
+	require.Nil(t, err)
+	assert.Equal(t, int64(3), total)
+	require.Len(t, found, 1)
+	assert.Equal(t, "dog", found[0].Name)
+}
+
+func TestMemoryDal_Search_WithoutTextIndex_ExpectedError(t *testing.T) {
+	// 1.ARRANGE
+	memoryDal := dal.NewMemoryDal()
+	insertTestGifs(t, memoryDal, testGif{ID: primitive.NewObjectID(), Name: "dog"})
+	var found []testGif
+
+	// 2.ACT
+	_, err := memoryDal.Search(context.Background(), dal.CollGifs, *dal.NewSearchArguments("dog"), &found)
+
+	// 3.ASSERT
+	assert.True(t, errors.Is(err, dal.ErrNoTextIndex))
+}
+
+func TestMemoryDal_Update_Rename(t *testing.T) {
+	// 1.ARRANGE
+	memoryDal := dal.NewMemoryDal()
+	ownerID := primitive.NewObjectID()
+	_, errInsert := memoryDal.Insert(context.Background(), dal.CollGroups, []any{
+		bson.M{"name": "legacy", "user_id": ownerID},
+		bson.M{"name": "migrated", "userId": ownerID},
+	})
+	require.Nil(t, errInsert)
+
+	// 2.ACT
+	result, err := memoryDal.Update(context.Background(), dal.CollGroups, bson.M{},
+		bson.M{"$rename": bson.M{"user_id": "userId"}}, dal.UpdateAllMatching)
+
+	// 3.ASSERT
+	require.Nil(t, err)
+	assert.Equal(t, int64(1), result.ModifiedCount)
+
+	count, errCount := memoryDal.Count(context.Background(), dal.CollGroups, bson.M{"userId": ownerID, "user_id": bson.M{"$exists": false}})
+	require.Nil(t, errCount)
+	assert.Equal(t, int64(2), count)
+}
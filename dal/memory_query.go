@@ -0,0 +1,480 @@
+package dal
+
+import (
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// matchDocument reports whether doc satisfies the mongo filter.
+func matchDocument(doc bson.M, filter bson.M) (bool, error) {
+	for key, condition := range filter {
+		var matches bool
+		var err error
+
+		switch key {
+		case "$and":
+			matches, err = matchAll(doc, condition)
+		case "$or":
+			matches, err = matchAny(doc, condition)
+		case "$nor":
+			matches, err = matchAny(doc, condition)
+			matches = !matches
+		default:
+			if strings.HasPrefix(key, "$") {
+				return false, fmt.Errorf("unsupported query operator %s", key)
+			}
+			matches, err = matchField(doc, key, condition)
+		}
+
+		if err != nil || !matches {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func matchAll(doc bson.M, conditions any) (bool, error) {
+	filters, err := toFilterList(conditions)
+	if err != nil {
+		return false, err
+	}
+	for _, filter := range filters {
+		matches, err := matchDocument(doc, filter)
+		if err != nil || !matches {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func matchAny(doc bson.M, conditions any) (bool, error) {
+	filters, err := toFilterList(conditions)
+	if err != nil {
+		return false, err
+	}
+	for _, filter := range filters {
+		matches, err := matchDocument(doc, filter)
+		if err != nil {
+			return false, err
+		}
+		if matches {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func toFilterList(conditions any) ([]bson.M, error) {
+	list, ok := conditions.(bson.A)
+	if !ok || len(list) == 0 {
+		return nil, fmt.Errorf("$and/$or/$nor must be a nonempty array")
+	}
+
+	filters := make([]bson.M, 0, len(list))
+	for _, item := range list {
+		filter, ok := item.(bson.M)
+		if !ok {
+			return nil, fmt.Errorf("$and/$or/$nor entries must be documents")
+		}
+		filters = append(filters, filter)
+	}
+	return filters, nil
+}
+
+func matchField(doc bson.M, path string, condition any) (bool, error) {
+	values := lookupPath(doc, path)
+
+	operators, ok := condition.(bson.M)
+	if !ok || !isOperatorDocument(operators) {
+		if regex, ok := condition.(primitive.Regex); ok {
+			return matchRegex(values, regex.Pattern, regex.Options)
+		}
+		return matchEquals(values, condition), nil
+	}
+
+	for operator, argument := range operators {
+		matches, err := matchOperator(values, operator, argument, operators)
+		if err != nil || !matches {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func matchOperator(values []any, operator string, argument any, operators bson.M) (bool, error) {
+	switch operator {
+	case "$eq":
+		return matchEquals(values, argument), nil
+	case "$ne":
+		return !matchEquals(values, argument), nil
+	case "$gt", "$gte", "$lt", "$lte":
+		return matchComparison(values, operator, argument), nil
+	case "$in":
+		list, ok := argument.(bson.A)
+		if !ok {
+			return false, fmt.Errorf("$in needs an array")
+		}
+		return matchIn(values, list), nil
+	case "$nin":
+		list, ok := argument.(bson.A)
+		if !ok {
+			return false, fmt.Errorf("$nin needs an array")
+		}
+		return !matchIn(values, list), nil
+	case "$all":
+		list, ok := argument.(bson.A)
+		if !ok {
+			return false, fmt.Errorf("$all needs an array")
+		}
+		// like mongo, an empty $all matches nothing
+		for _, expected := range list {
+			if !matchEquals(values, expected) {
+				return false, nil
+			}
+		}
+		return len(list) > 0, nil
+	case "$exists":
+		exists, _ := argument.(bool)
+		return (len(values) > 0) == exists, nil
+	case "$regex":
+		options, _ := operators["$options"].(string)
+		switch pattern := argument.(type) {
+		case string:
+			return matchRegex(values, pattern, options)
+		case primitive.Regex:
+			if options == "" {
+				options = pattern.Options
+			}
+			return matchRegex(values, pattern.Pattern, options)
+		}
+		return false, fmt.Errorf("$regex has to be a string")
+	case "$options":
+		if _, ok := operators["$regex"]; !ok {
+			return false, fmt.Errorf("$options needs a $regex")
+		}
+		return true, nil
+	}
+	return false, fmt.Errorf("unsupported query operator %s", operator)
+}
+
+func isOperatorDocument(doc bson.M) bool {
+	if len(doc) == 0 {
+		return false
+	}
+	for key := range doc {
+		if !strings.HasPrefix(key, "$") {
+			return false
+		}
+	}
+	return true
+}
+
+// lookupPath resolves a dotted path in the document. Arrays are traversed the way mongo does,
+// so the result holds every value the path can refer to, including the elements of a matched array.
+func lookupPath(doc bson.M, path string) []any {
+	return lookupParts(doc, strings.Split(path, "."))
+}
+
+func lookupParts(value any, parts []string) []any {
+	if len(parts) == 0 {
+		if array, ok := value.(bson.A); ok {
+			return append([]any{array}, array...)
+		}
+		return []any{value}
+	}
+
+	switch typed := value.(type) {
+	case bson.M:
+		child, ok := typed[parts[0]]
+		if !ok {
+			return nil
+		}
+		return lookupParts(child, parts[1:])
+	case bson.A:
+		results := make([]any, 0)
+		for _, elem := range typed {
+			if _, ok := elem.(bson.M); ok {
+				results = append(results, lookupParts(elem, parts)...)
+			}
+		}
+		return results
+	}
+	return nil
+}
+
+func matchEquals(values []any, expected any) bool {
+	if expected == nil && len(values) == 0 {
+		return true
+	}
+	for _, value := range values {
+		if valuesEqual(value, expected) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchIn(values []any, list bson.A) bool {
+	for _, expected := range list {
+		if regex, ok := expected.(primitive.Regex); ok {
+			if matches, _ := matchRegex(values, regex.Pattern, regex.Options); matches {
+				return true
+			}
+			continue
+		}
+		if matchEquals(values, expected) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchComparison(values []any, operator string, argument any) bool {
+	for _, value := range values {
+		if typeOrder(value) != typeOrder(argument) {
+			continue
+		}
+
+		result := compareValues(value, argument)
+		switch {
+		case operator == "$gt" && result > 0,
+			operator == "$gte" && result >= 0,
+			operator == "$lt" && result < 0,
+			operator == "$lte" && result <= 0:
+			return true
+		}
+	}
+	return false
+}
+
+func matchRegex(values []any, pattern string, options string) (bool, error) {
+	flags := ""
+	for _, option := range options {
+		switch option {
+		case 'i', 'm', 's':
+			flags += string(option)
+		case 'x':
+		default:
+			return false, fmt.Errorf("invalid regex option %q", option)
+		}
+	}
+	if flags != "" {
+		pattern = "(?" + flags + ")" + pattern
+	}
+
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return false, fmt.Errorf("invalid regex: %w", err)
+	}
+
+	for _, value := range values {
+		if str, ok := value.(string); ok && regex.MatchString(str) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// equalityFields returns the fields of the filter that are compared by equality.
+// They become the base of the document inserted by an upsert.
+func equalityFields(filter bson.M) bson.M {
+	doc := bson.M{}
+	for key, condition := range filter {
+		if strings.HasPrefix(key, "$") {
+			continue
+		}
+		if operators, ok := condition.(bson.M); ok && isOperatorDocument(operators) {
+			if value, ok := operators["$eq"]; ok {
+				setPath(doc, key, value)
+			}
+			continue
+		}
+		setPath(doc, key, condition)
+	}
+	return doc
+}
+
+func applyProjection(doc bson.M, projections Projections) bson.M {
+	// _id is included unless it is excluded, so it only makes the projection inclusive when it is the only field
+	inclusive := false
+	for _, projection := range projections {
+		if !projection.ShouldExclude && (projection.FieldName != "_id" || len(projections) == 1) {
+			inclusive = true
+		}
+	}
+
+	if !inclusive {
+		for _, projection := range projections {
+			unsetPath(doc, projection.FieldName)
+		}
+		return doc
+	}
+
+	projected := bson.M{}
+	if id, ok := doc["_id"]; ok {
+		projected["_id"] = id
+	}
+	for _, projection := range projections {
+		if projection.ShouldExclude {
+			delete(projected, projection.FieldName)
+			continue
+		}
+		if values := lookupParts(doc, strings.Split(projection.FieldName, ".")); len(values) > 0 {
+			setPath(projected, projection.FieldName, values[0])
+		}
+	}
+	return projected
+}
+
+func sortDocuments(docs []bson.M, sorts Sorts) {
+	sort.SliceStable(docs, func(i, j int) bool {
+		for _, s := range sorts {
+			result := compareValues(firstValue(docs[i], s.FieldName), firstValue(docs[j], s.FieldName))
+			if result == 0 {
+				continue
+			}
+			if s.Ascending {
+				return result < 0
+			}
+			return result > 0
+		}
+		return false
+	})
+}
+
+func firstValue(doc bson.M, path string) any {
+	values := lookupPath(doc, path)
+	if len(values) == 0 {
+		return nil
+	}
+	return values[0]
+}
+
+func valuesEqual(a, b any) bool {
+	return typeOrder(a) == typeOrder(b) && compareValues(a, b) == 0
+}
+
+// typeOrder follows mongo's comparison order of the BSON types.
+func typeOrder(value any) int {
+	switch value.(type) {
+	case nil, primitive.Null, primitive.Undefined:
+		return 1
+	case int32, int64, float64, int, primitive.Decimal128:
+		return 2
+	case string, primitive.Symbol:
+		return 3
+	case bson.M:
+		return 4
+	case bson.A:
+		return 5
+	case primitive.Binary:
+		return 6
+	case primitive.ObjectID:
+		return 7
+	case bool:
+		return 8
+	case primitive.DateTime:
+		return 9
+	case primitive.Timestamp:
+		return 10
+	case primitive.Regex:
+		return 11
+	}
+	return 12
+}
+
+// compareValues returns a negative number, zero or a positive number when a is less than, equal to or greater than b.
+func compareValues(a, b any) int {
+	orderA, orderB := typeOrder(a), typeOrder(b)
+	if orderA != orderB {
+		return orderA - orderB
+	}
+
+	switch typedA := a.(type) {
+	case int32, int64, float64, int:
+		return compareFloats(toFloat(typedA), toFloat(b))
+	case string:
+		return strings.Compare(typedA, fmt.Sprint(b))
+	case primitive.ObjectID:
+		objB := b.(primitive.ObjectID)
+		return strings.Compare(typedA.Hex(), objB.Hex())
+	case bool:
+		boolB := b.(bool)
+		switch {
+		case typedA == boolB:
+			return 0
+		case typedA:
+			return 1
+		}
+		return -1
+	case primitive.DateTime:
+		return compareFloats(float64(typedA), float64(b.(primitive.DateTime)))
+	case bson.M:
+		return compareDocuments(typedA, b.(bson.M))
+	case bson.A:
+		arrayB := b.(bson.A)
+		for i := 0; i < len(typedA) && i < len(arrayB); i++ {
+			if result := compareValues(typedA[i], arrayB[i]); result != 0 {
+				return result
+			}
+		}
+		return len(typedA) - len(arrayB)
+	case nil, primitive.Null, primitive.Undefined:
+		return 0
+	}
+
+	rawA, _ := bson.Marshal(bson.M{"v": a})
+	rawB, _ := bson.Marshal(bson.M{"v": b})
+	return strings.Compare(string(rawA), string(rawB))
+}
+
+// compareDocuments compares the documents field by field in key order, since bson.M doesn't keep the stored order.
+func compareDocuments(a, b bson.M) int {
+	keysA, keysB := sortedKeys(a), sortedKeys(b)
+	for i := 0; i < len(keysA) && i < len(keysB); i++ {
+		if result := strings.Compare(keysA[i], keysB[i]); result != 0 {
+			return result
+		}
+		if result := compareValues(a[keysA[i]], b[keysB[i]]); result != 0 {
+			return result
+		}
+	}
+	return len(keysA) - len(keysB)
+}
+
+func sortedKeys(doc bson.M) []string {
+	keys := make([]string, 0, len(doc))
+	for key := range doc {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func toFloat(value any) float64 {
+	switch typed := value.(type) {
+	case int32:
+		return float64(typed)
+	case int64:
+		return float64(typed)
+	case int:
+		return float64(typed)
+	case float64:
+		return typed
+	}
+	return 0
+}
@@ -0,0 +1,80 @@
+package dal
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"sync"
+)
+
+type memoryTransactionKey struct{}
+
+// memoryTransaction records how to undo the writes made in a transaction.
+// Only atomicity is emulated: other requests see the writes before the transaction finishes.
+type memoryTransaction struct {
+	mu   sync.Mutex
+	undo []memoryUndo
+}
+
+// memoryUndo restores the document with the id to previous, or removes it when previous is nil.
+type memoryUndo struct {
+	collection string
+	id         any
+	previous   bson.M
+}
+
+// WithTransaction runs fn and reverts all the writes fn made through txCtx when it returns an error.
+// Transactions started inside fn join the outer one.
+func (m *MemoryDal) WithTransaction(ctx context.Context, fn func(txCtx context.Context) error) error {
+	if transactionFrom(ctx) != nil {
+		return fn(ctx)
+	}
+
+	tx := &memoryTransaction{}
+	if err := fn(context.WithValue(ctx, memoryTransactionKey{}, tx)); err != nil {
+		m.rollback(tx)
+		return err
+	}
+	return nil
+}
+
+func transactionFrom(ctx context.Context) *memoryTransaction {
+	tx, _ := ctx.Value(memoryTransactionKey{}).(*memoryTransaction)
+	return tx
+}
+
+// recordUndo remembers the state of the document before a write, when the write is part of a transaction.
+// previous is nil for inserted documents. The documents are never modified in place, so previous can be kept as is.
+func recordUndo(ctx context.Context, collection string, id any, previous bson.M) {
+	tx := transactionFrom(ctx)
+	if tx == nil {
+		return
+	}
+
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	tx.undo = append(tx.undo, memoryUndo{collection: collection, id: id, previous: previous})
+}
+
+// rollback undoes the writes of the transaction, the latest first.
+func (m *MemoryDal) rollback(tx *memoryTransaction) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := len(tx.undo) - 1; i >= 0; i-- {
+		undo := tx.undo[i]
+		documents := m.collections[undo.collection]
+		index := m.indexOfID(undo.collection, undo.id)
+
+		switch {
+		case undo.previous == nil && index >= 0:
+			m.collections[undo.collection] = append(documents[:index], documents[index+1:]...)
+		case undo.previous != nil && index >= 0:
+			documents[index] = undo.previous
+		case undo.previous != nil:
+			m.collections[undo.collection] = append(documents, undo.previous)
+		}
+	}
+}
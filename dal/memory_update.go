@@ -0,0 +1,187 @@
+package dal
+
+import (
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"strings"
+)
+
+// applyUpdate applies the mongo update operators to doc in place.
+// isInsert is true when the update creates a new document through an upsert, which enables $setOnInsert.
+func applyUpdate(doc bson.M, update bson.M, isInsert bool) error {
+	if len(update) == 0 {
+		return fmt.Errorf("update document must not be empty")
+	}
+
+	for operator, argument := range update {
+		fields, ok := argument.(bson.M)
+		if !ok {
+			if !strings.HasPrefix(operator, "$") {
+				return fmt.Errorf("update document must contain only atomic operators")
+			}
+			return fmt.Errorf("argument of %s must be a document", operator)
+		}
+
+		for path, value := range fields {
+			if path == "_id" && operator != "$setOnInsert" && !(isInsert && operator == "$set") {
+				if current, ok := doc["_id"]; !ok || !valuesEqual(current, value) {
+					return fmt.Errorf("performing an update on the path '_id' would modify the immutable field '_id'")
+				}
+			}
+
+			if err := applyUpdateOperator(doc, operator, path, value, isInsert); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func applyUpdateOperator(doc bson.M, operator, path string, value any, isInsert bool) error {
+	switch operator {
+	case "$set":
+		setPath(doc, path, value)
+	case "$setOnInsert":
+		if isInsert {
+			setPath(doc, path, value)
+		}
+	case "$unset":
+		unsetPath(doc, path)
+	case "$inc":
+		if typeOrder(value) != typeOrder(int32(0)) {
+			return fmt.Errorf("cannot increment with non-numeric argument: {%s: %v}", path, value)
+		}
+		current := firstValue(doc, path)
+		if current == nil {
+			setPath(doc, path, value)
+			return nil
+		}
+		if typeOrder(current) != typeOrder(value) {
+			return fmt.Errorf("cannot apply $inc to a value of non-numeric type at %s", path)
+		}
+		setPath(doc, path, addNumbers(current, value))
+	case "$push":
+		current := firstValue(doc, path)
+		if current == nil {
+			setPath(doc, path, bson.A{value})
+			return nil
+		}
+		array, ok := current.(bson.A)
+		if !ok {
+			return fmt.Errorf("the field %s must be an array to $push to it", path)
+		}
+		setPath(doc, path, append(array, value))
+	case "$pull":
+		array, ok := firstValue(doc, path).(bson.A)
+		if !ok {
+			return nil
+		}
+		kept := bson.A{}
+		for _, elem := range array {
+			matches, err := matchPullCondition(elem, value)
+			if err != nil {
+				return err
+			}
+			if !matches {
+				kept = append(kept, elem)
+			}
+		}
+		setPath(doc, path, kept)
+	case "$rename":
+		target, ok := value.(string)
+		if !ok || target == "" {
+			return fmt.Errorf("the new name of %s must be a non-empty string", path)
+		}
+		values := lookupPath(doc, path)
+		if len(values) == 0 {
+			return nil
+		}
+		unsetPath(doc, path)
+		setPath(doc, target, values[0])
+	default:
+		if strings.HasPrefix(operator, "$") {
+			return fmt.Errorf("unsupported update operator %s", operator)
+		}
+		return fmt.Errorf("update document must contain only atomic operators")
+	}
+	return nil
+}
+
+// matchPullCondition reports whether $pull removes the array element. A document condition is a query on the fields of
+// the elements, e.g. {"$pull": {"members": {"userId": id}}}, any other condition is compared with the element.
+func matchPullCondition(elem any, condition any) (bool, error) {
+	query, ok := condition.(bson.M)
+	if !ok || isOperatorDocument(query) {
+		return matchField(bson.M{"value": elem}, "value", condition)
+	}
+	elemDoc, ok := elem.(bson.M)
+	if !ok {
+		return false, nil
+	}
+	return matchDocument(elemDoc, query)
+}
+
+// addNumbers adds two numbers keeping the widest of their types, the same way mongo's $inc does.
+func addNumbers(a, b any) any {
+	switch {
+	case isFloat(a) || isFloat(b):
+		return toFloat(a) + toFloat(b)
+	case isInt64(a) || isInt64(b):
+		return toInt64(a) + toInt64(b)
+	}
+
+	sum := toInt64(a) + toInt64(b)
+	if sum > 1<<31-1 || sum < -1<<31 {
+		return sum
+	}
+	return int32(sum)
+}
+
+func toInt64(value any) int64 {
+	switch typed := value.(type) {
+	case int32:
+		return int64(typed)
+	case int64:
+		return typed
+	case int:
+		return int64(typed)
+	}
+	return int64(toFloat(value))
+}
+
+func isFloat(value any) bool {
+	_, ok := value.(float64)
+	return ok
+}
+
+func isInt64(value any) bool {
+	_, ok := value.(int64)
+	return ok
+}
+
+func setPath(doc bson.M, path string, value any) {
+	parts := strings.Split(path, ".")
+	current := doc
+	for _, part := range parts[:len(parts)-1] {
+		child, ok := current[part].(bson.M)
+		if !ok {
+			child = bson.M{}
+			current[part] = child
+		}
+		current = child
+	}
+	current[parts[len(parts)-1]] = value
+}
+
+func unsetPath(doc bson.M, path string) {
+	parts := strings.Split(path, ".")
+	current := doc
+	for _, part := range parts[:len(parts)-1] {
+		child, ok := current[part].(bson.M)
+		if !ok {
+			return
+		}
+		current = child
+	}
+	delete(current, parts[len(parts)-1])
+}
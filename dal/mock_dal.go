@@ -79,6 +79,70 @@ func (_m *MockDAL) Disconnect(ctx context.Context) error {
 	return r0
 }
 
+// DropIndex provides a mock function with given fields: ctx, collection, name
+func (_m *MockDAL) DropIndex(ctx context.Context, collection string, name string) error {
+	ret := _m.Called(ctx, collection, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DropIndex")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, collection, name)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// EnsureIndex provides a mock function with given fields: ctx, collection, index
+func (_m *MockDAL) EnsureIndex(ctx context.Context, collection string, index Index) error {
+	ret := _m.Called(ctx, collection, index)
+
+	if len(ret) == 0 {
+		panic("no return value specified for EnsureIndex")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, Index) error); ok {
+		r0 = rf(ctx, collection, index)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Count provides a mock function with given fields: ctx, collection, filter
+func (_m *MockDAL) Count(ctx context.Context, collection string, filter interface{}) (int64, error) {
+	ret := _m.Called(ctx, collection, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Count")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, interface{}) (int64, error)); ok {
+		return rf(ctx, collection, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, interface{}) int64); ok {
+		r0 = rf(ctx, collection, filter)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, interface{}) error); ok {
+		r1 = rf(ctx, collection, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Find provides a mock function with given fields: ctx, collection, findArguments, result
 func (_m *MockDAL) Find(ctx context.Context, collection string, findArguments FindArguments, result interface{}) error {
 	ret := _m.Called(ctx, collection, findArguments, result)
@@ -97,6 +161,24 @@ func (_m *MockDAL) Find(ctx context.Context, collection string, findArguments Fi
 	return r0
 }
 
+// FindAndDelete provides a mock function with given fields: ctx, collection, filter, document
+func (_m *MockDAL) FindAndDelete(ctx context.Context, collection string, filter interface{}, document interface{}) error {
+	ret := _m.Called(ctx, collection, filter, document)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FindAndDelete")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, interface{}, interface{}) error); ok {
+		r0 = rf(ctx, collection, filter, document)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // FindAndDeleteByID provides a mock function with given fields: ctx, collection, id, document
 func (_m *MockDAL) FindAndDeleteByID(ctx context.Context, collection string, id string, document interface{}) error {
 	ret := _m.Called(ctx, collection, id, document)
@@ -163,6 +245,64 @@ func (_m *MockDAL) Insert(ctx context.Context, collection string, document []int
 	return r0, r1
 }
 
+// ListIndexes provides a mock function with given fields: ctx, collection
+func (_m *MockDAL) ListIndexes(ctx context.Context, collection string) ([]Index, error) {
+	ret := _m.Called(ctx, collection)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListIndexes")
+	}
+
+	var r0 []Index
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]Index, error)); ok {
+		return rf(ctx, collection)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []Index); ok {
+		r0 = rf(ctx, collection)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]Index)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, collection)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Search provides a mock function with given fields: ctx, collection, searchArguments, result
+func (_m *MockDAL) Search(ctx context.Context, collection string, searchArguments SearchArguments, result interface{}) (int64, error) {
+	ret := _m.Called(ctx, collection, searchArguments, result)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Search")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, SearchArguments, interface{}) (int64, error)); ok {
+		return rf(ctx, collection, searchArguments, result)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, SearchArguments, interface{}) int64); ok {
+		r0 = rf(ctx, collection, searchArguments, result)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, SearchArguments, interface{}) error); ok {
+		r1 = rf(ctx, collection, searchArguments, result)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Update provides a mock function with given fields: ctx, collection, filter, update, optionFuncs
 func (_m *MockDAL) Update(ctx context.Context, collection string, filter interface{}, update interface{}, optionFuncs ...UpdateOptionsFunc) (*UpdateResult, error) {
 	_va := make([]interface{}, len(optionFuncs))
@@ -237,6 +377,24 @@ func (_m *MockDAL) UpdateByID(ctx context.Context, collection string, id string,
 	return r0, r1
 }
 
+// WithTransaction provides a mock function with given fields: ctx, fn
+func (_m *MockDAL) WithTransaction(ctx context.Context, fn func(context.Context) error) error {
+	ret := _m.Called(ctx, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for WithTransaction")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, func(context.Context) error) error); ok {
+		r0 = rf(ctx, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // NewMockDAL creates a new instance of MockDAL. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewMockDAL(t interface {
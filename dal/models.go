@@ -1,9 +1,13 @@
 package dal
 
 import (
+	"errors"
 	"go.mongodb.org/mongo-driver/bson"
 )
 
+// ErrNegativeSkip is returned by the in-memory DAL for a negative skip, which mongo refuses as well.
+var ErrNegativeSkip = errors.New("the skip must not be negative")
+
 type InsertResult struct {
 	InsertedDocumentsCount int
 }
@@ -38,13 +42,14 @@ type Sort struct {
 
 type Sorts []Sort
 
-func (sorts Sorts) ToMongoSorting() bson.M {
-	mongoSorting := bson.M{}
+// ToMongoSorting keeps the order of the sorts, later sorts only break ties of the earlier ones.
+func (sorts Sorts) ToMongoSorting() bson.D {
+	mongoSorting := bson.D{}
 	for _, sort := range sorts {
 		if sort.Ascending {
-			mongoSorting[sort.FieldName] = 1
+			mongoSorting = append(mongoSorting, bson.E{Key: sort.FieldName, Value: 1})
 		} else {
-			mongoSorting[sort.FieldName] = -1
+			mongoSorting = append(mongoSorting, bson.E{Key: sort.FieldName, Value: -1})
 		}
 	}
 	return mongoSorting
@@ -77,9 +82,8 @@ func (args *FindArguments) WithSorts(sort Sorts) *FindArguments {
 	return args
 }
 
-func (args *FindArguments) WithSkip(skip int) *FindArguments {
-	skip64 := int64(skip)
-	args.Skip = &skip64
+func (args *FindArguments) WithSkip(skip int64) *FindArguments {
+	args.Skip = &skip
 	return args
 }
 
@@ -8,11 +8,15 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"sort"
+	"time"
 )
 
 type MongoDal struct {
 	client   *mongo.Client
 	database *mongo.Database
+	// transactions need a replica set or a sharded cluster, standalone servers don't support them
+	supportsTransactions bool
 }
 
 func (m MongoDal) FindByID(ctx context.Context, collection string, id string, document any) error {
@@ -47,6 +51,21 @@ func (m MongoDal) FindAndDeleteByID(ctx context.Context, collection string, id s
 	return nil
 }
 
+func (m MongoDal) FindAndDelete(ctx context.Context, collection string, filter any, document any) error {
+	result := m.database.Collection(collection).
+		FindOneAndDelete(ctx, filter)
+
+	if result.Err() != nil {
+		return result.Err()
+	}
+
+	if errDecode := result.Decode(document); errDecode != nil {
+		return errDecode
+	}
+
+	return nil
+}
+
 func NewMongoDal(ctx context.Context, address string, databaseName string) (*MongoDal, error) {
 	clientOptions := options.Client().
 		ApplyURI(address)
@@ -60,12 +79,44 @@ func NewMongoDal(ctx context.Context, address string, databaseName string) (*Mon
 		return nil, err
 	}
 
+	var hello bson.M
+	if err := client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		return nil, err
+	}
+	_, isReplicaSet := hello["setName"]
+
 	return &MongoDal{
-		client:   client,
-		database: client.Database(databaseName),
+		client:               client,
+		database:             client.Database(databaseName),
+		supportsTransactions: isReplicaSet || hello["msg"] == "isdbgrid",
 	}, nil
 }
 
+// SupportsTransactions reports whether the server is a replica set or a sharded cluster.
+// On standalone servers WithTransaction runs without a transaction.
+func (m MongoDal) SupportsTransactions() bool {
+	return m.supportsTransactions
+}
+
+// WithTransaction runs fn in a transaction of a new session. fn may be retried on transient errors,
+// so it must not have side effects outside the database. Transactions started inside fn join the outer one.
+func (m MongoDal) WithTransaction(ctx context.Context, fn func(txCtx context.Context) error) error {
+	if !m.supportsTransactions || mongo.SessionFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+
+	session, err := m.client.StartSession()
+	if err != nil {
+		return fmt.Errorf("error while starting a session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessionCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessionCtx)
+	})
+	return err
+}
+
 func (m MongoDal) Update(ctx context.Context, collection string, filter any, update any, optionFuncs ...UpdateOptionsFunc) (*UpdateResult, error) {
 	updateOptions := options.Update()
 
@@ -78,12 +129,17 @@ func (m MongoDal) Update(ctx context.Context, collection string, filter any, upd
 		updateOptions.SetUpsert(true)
 	}
 
-	result, err := m.database.
-		Collection(collection).
-		UpdateOne(ctx, filter, update, updateOptions)
+	coll := m.database.Collection(collection)
+	var result *mongo.UpdateResult
+	var err error
+	if opts.Multi {
+		result, err = coll.UpdateMany(ctx, filter, update, updateOptions)
+	} else {
+		result, err = coll.UpdateOne(ctx, filter, update, updateOptions)
+	}
 
 	if err != nil {
-		return nil, fmt.Errorf("error while updating document in %s: %w", collection, err)
+		return nil, fmt.Errorf("error while updating document in %s: %w", collection, wrapDuplicateKeyError(err))
 	}
 
 	return &UpdateResult{
@@ -135,6 +191,22 @@ func (m MongoDal) Find(ctx context.Context, collection string, findArguments Fin
 	return cursor.All(ctx, documents)
 }
 
+func (m MongoDal) Count(ctx context.Context, collection string, filter any) (int64, error) {
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	count, err := m.database.
+		Collection(collection).
+		CountDocuments(ctx, filter)
+
+	if err != nil {
+		return 0, fmt.Errorf("error counting documents in %s: %w", collection, err)
+	}
+
+	return count, nil
+}
+
 func (m MongoDal) Disconnect(ctx context.Context) error {
 	return m.client.Disconnect(ctx)
 }
@@ -145,7 +217,7 @@ func (m MongoDal) Insert(ctx context.Context, collection string, document []any)
 		InsertMany(ctx, document)
 
 	if err != nil {
-		return nil, fmt.Errorf("error while inserting documnets in %s: %w", collection, err)
+		return nil, fmt.Errorf("error while inserting documnets in %s: %w", collection, wrapDuplicateKeyError(err))
 	}
 
 	return &InsertResult{
@@ -187,7 +259,7 @@ func (m MongoDal) UpdateByID(ctx context.Context, collection string, id string,
 		UpdateByID(ctx, objID, update, updateOptions)
 
 	if err != nil {
-		return nil, fmt.Errorf("error while updating document in %s: %w", collection, err)
+		return nil, fmt.Errorf("error while updating document in %s: %w", collection, wrapDuplicateKeyError(err))
 	}
 
 	return &UpdateResult{
@@ -208,3 +280,140 @@ func (m MongoDal) Delete(ctx context.Context, collection string, filter any) (*D
 		DeletedCount: result.DeletedCount,
 	}, nil
 }
+
+func (m MongoDal) EnsureIndex(ctx context.Context, collection string, index Index) error {
+	indexOptions := options.Index().
+		SetName(index.Name)
+	if index.Unique {
+		indexOptions.SetUnique(true)
+	}
+	if index.TTL {
+		indexOptions.SetExpireAfterSeconds(int32(index.ExpireAfter.Seconds()))
+	}
+	if index.IsText() {
+		// without a language words are neither stemmed nor dropped as stop words, so Tokenize splits text the same way
+		indexOptions.SetDefaultLanguage("none")
+		if index.Weights != nil {
+			weights := bson.M{}
+			for field, weight := range index.Weights {
+				weights[field] = weight
+			}
+			indexOptions.SetWeights(weights)
+		}
+	}
+
+	_, err := m.database.
+		Collection(collection).
+		Indexes().
+		CreateOne(ctx, mongo.IndexModel{Keys: index.ToMongoKeys(), Options: indexOptions})
+
+	if err != nil {
+		return fmt.Errorf("error while creating index %s in %s: %w", index.Name, collection, err)
+	}
+	return nil
+}
+
+// mongoIndexSpec is the part of the specification of an index returned by listIndexes that Index describes.
+type mongoIndexSpec struct {
+	Name    string `bson:"name"`
+	Key     bson.D `bson:"key"`
+	Unique  bool   `bson:"unique"`
+	Weights bson.M `bson:"weights"`
+	// ExpireAfterSeconds is only set on TTL indexes, its number type depends on the client that created the index
+	ExpireAfterSeconds any `bson:"expireAfterSeconds"`
+}
+
+// toIndex translates the specification back to an Index. Mongo stores the text keys of an index as _fts and _ftsx
+// and the text fields only as the weights, so the text keys are sorted by field name.
+func (spec mongoIndexSpec) toIndex() Index {
+	index := Index{Name: spec.Name, Keys: make([]IndexKey, 0, len(spec.Key)), Unique: spec.Unique}
+	if spec.ExpireAfterSeconds != nil {
+		index.TTL = true
+		index.ExpireAfter = time.Duration(toFloat(spec.ExpireAfterSeconds)) * time.Second
+	}
+	for _, key := range spec.Key {
+		switch key.Key {
+		case "_fts":
+			index.Weights = make(map[string]int32, len(spec.Weights))
+			fields := make([]string, 0, len(spec.Weights))
+			for field, weight := range spec.Weights {
+				fields = append(fields, field)
+				index.Weights[field] = int32(toFloat(weight))
+			}
+			sort.Strings(fields)
+			for _, field := range fields {
+				index.Keys = append(index.Keys, IndexKey{FieldName: field, Text: true})
+			}
+		case "_ftsx":
+		default:
+			index.Keys = append(index.Keys, IndexKey{FieldName: key.Key, Ascending: toFloat(key.Value) > 0})
+		}
+	}
+	return index
+}
+
+func (m MongoDal) DropIndex(ctx context.Context, collection string, name string) error {
+	_, err := m.database.
+		Collection(collection).
+		Indexes().
+		DropOne(ctx, name)
+	if err != nil {
+		return fmt.Errorf("error while dropping index %s in %s: %w", name, collection, err)
+	}
+	return nil
+}
+
+func (m MongoDal) ListIndexes(ctx context.Context, collection string) ([]Index, error) {
+	cursor, err := m.database.
+		Collection(collection).
+		Indexes().
+		List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error listing the indexes of %s: %w", collection, err)
+	}
+
+	var specs []mongoIndexSpec
+	if err := cursor.All(ctx, &specs); err != nil {
+		return nil, fmt.Errorf("error listing the indexes of %s: %w", collection, err)
+	}
+
+	indexes := make([]Index, 0, len(specs))
+	for _, spec := range specs {
+		indexes = append(indexes, spec.toIndex())
+	}
+	return indexes, nil
+}
+
+func (m MongoDal) Search(ctx context.Context, collection string, searchArguments SearchArguments, documents any) (int64, error) {
+	filter := searchArguments.ToMongoFilter()
+	total, err := m.Count(ctx, collection, filter)
+	if err != nil {
+		return 0, fmt.Errorf("error searching documents in %s: %w", collection, err)
+	}
+
+	score := bson.M{"$meta": "textScore"}
+	findOptions := options.Find().
+		SetProjection(bson.M{ScoreField: score}).
+		SetSort(bson.D{{Key: ScoreField, Value: score}, {Key: "_id", Value: 1}})
+	if searchArguments.Skip != nil {
+		findOptions.SetSkip(*searchArguments.Skip)
+	}
+	if searchArguments.Limit != nil {
+		findOptions.SetLimit(*searchArguments.Limit)
+	}
+
+	cursor, err := m.database.
+		Collection(collection).
+		Find(ctx, filter, findOptions)
+	if err != nil {
+		return 0, fmt.Errorf("error searching documents in %s: %w", collection, err)
+	}
+	return total, cursor.All(ctx, documents)
+}
+
+func wrapDuplicateKeyError(err error) error {
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("%w: %w", ErrDuplicateKey, err)
+	}
+	return err
+}
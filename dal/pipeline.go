@@ -0,0 +1,320 @@
+package dal
+
+import (
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"strings"
+)
+
+// CollectionReader returns every document of a collection. EvaluatePipeline uses it to resolve $lookup stages.
+type CollectionReader func(collection string) ([]bson.M, error)
+
+// missing is the result of an expression that refers to a field which doesn't exist.
+// Unlike null it is never written to the output documents.
+type missing struct{}
+
+// EvaluatePipeline runs a mongo aggregation pipeline over documents without a database,
+// so DAL implementations that don't talk to mongo can support Aggregate.
+// Supported stages are $match, $lookup, $addFields/$set, $group, $project, $sort, $limit, $skip, $unwind and $count.
+func EvaluatePipeline(documents []bson.M, pipeline []any, readCollection CollectionReader) ([]bson.M, error) {
+	for _, stage := range pipeline {
+		name, argument, err := parseStage(stage)
+		if err != nil {
+			return nil, err
+		}
+
+		documents, err = evaluateStage(documents, name, argument, readCollection)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return documents, nil
+}
+
+// parseStage splits a stage like {$match: {...}} into its name and argument.
+// $sort arguments are kept as bson.D because the order of the sort keys matters.
+func parseStage(stage any) (string, bson.RawValue, error) {
+	raw, err := bson.Marshal(stage)
+	if err != nil {
+		return "", bson.RawValue{}, fmt.Errorf("invalid pipeline stage: %w", err)
+	}
+
+	elements, err := bson.Raw(raw).Elements()
+	if err != nil {
+		return "", bson.RawValue{}, fmt.Errorf("invalid pipeline stage: %w", err)
+	}
+	if len(elements) != 1 {
+		return "", bson.RawValue{}, fmt.Errorf("a pipeline stage specification object must contain exactly one field")
+	}
+
+	return elements[0].Key(), elements[0].Value(), nil
+}
+
+func evaluateStage(documents []bson.M, name string, argument bson.RawValue, readCollection CollectionReader) ([]bson.M, error) {
+	switch name {
+	case "$sort":
+		var sortSpec bson.D
+		if err := argument.Unmarshal(&sortSpec); err != nil {
+			return nil, fmt.Errorf("the sort key specification must be an object")
+		}
+		return sortStage(documents, sortSpec)
+	case "$limit", "$skip":
+		count, ok := argument.AsInt64OK()
+		if !ok {
+			if value, isDouble := argument.DoubleOK(); isDouble {
+				count, ok = int64(value), true
+			}
+		}
+		if !ok || count < 0 {
+			return nil, fmt.Errorf("the argument must be a non-negative number")
+		}
+		if count > int64(len(documents)) {
+			count = int64(len(documents))
+		}
+		if name == "$limit" {
+			return documents[:count], nil
+		}
+		return documents[count:], nil
+	case "$count":
+		field, ok := argument.StringValueOK()
+		if !ok || field == "" || strings.HasPrefix(field, "$") || strings.Contains(field, ".") {
+			return nil, fmt.Errorf("the count field must be a non-empty string without '$' or '.'")
+		}
+		if len(documents) == 0 {
+			return documents, nil
+		}
+		return []bson.M{{field: int32(len(documents))}}, nil
+	case "$unwind":
+		return unwindStage(documents, argument)
+	}
+
+	var spec bson.M
+	if err := argument.Unmarshal(&spec); err != nil {
+		return nil, fmt.Errorf("the argument must be an object")
+	}
+
+	switch name {
+	case "$match":
+		return matchStage(documents, spec)
+	case "$lookup":
+		return lookupStage(documents, spec, readCollection)
+	case "$addFields", "$set":
+		return addFieldsStage(documents, spec)
+	case "$group":
+		return groupStage(documents, spec)
+	case "$project":
+		return projectStage(documents, spec)
+	}
+	return nil, fmt.Errorf("unrecognized pipeline stage name")
+}
+
+func matchStage(documents []bson.M, filter bson.M) ([]bson.M, error) {
+	matched := make([]bson.M, 0, len(documents))
+	for _, doc := range documents {
+		matches, err := matchDocument(doc, filter)
+		if err != nil {
+			return nil, err
+		}
+		if matches {
+			matched = append(matched, doc)
+		}
+	}
+	return matched, nil
+}
+
+func lookupStage(documents []bson.M, spec bson.M, readCollection CollectionReader) ([]bson.M, error) {
+	from, _ := spec["from"].(string)
+	localField, _ := spec["localField"].(string)
+	foreignField, _ := spec["foreignField"].(string)
+	as, _ := spec["as"].(string)
+	if from == "" || localField == "" || foreignField == "" || as == "" {
+		return nil, fmt.Errorf("from, localField, foreignField and as must be non-empty strings")
+	}
+
+	if readCollection == nil {
+		return nil, fmt.Errorf("no collection reader to resolve %s", from)
+	}
+	foreignDocuments, err := readCollection(from)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, doc := range documents {
+		localValues := valuesOrNull(lookupPath(doc, localField))
+
+		joined := bson.A{}
+		for _, foreignDoc := range foreignDocuments {
+			if anyValueEqual(localValues, valuesOrNull(lookupPath(foreignDoc, foreignField))) {
+				joined = append(joined, copyDocument(foreignDoc))
+			}
+		}
+		setPath(doc, as, joined)
+	}
+	return documents, nil
+}
+
+// valuesOrNull treats a missing field as null, which is how $lookup matches documents without the join field.
+func valuesOrNull(values []any) []any {
+	if len(values) == 0 {
+		return []any{nil}
+	}
+	return values
+}
+
+func anyValueEqual(a, b []any) bool {
+	for _, valueA := range a {
+		for _, valueB := range b {
+			if valuesEqual(valueA, valueB) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func addFieldsStage(documents []bson.M, spec bson.M) ([]bson.M, error) {
+	for _, doc := range documents {
+		values := make(bson.M, len(spec))
+		for path, expression := range spec {
+			value, err := evaluateExpression(doc, expression)
+			if err != nil {
+				return nil, err
+			}
+			values[path] = value
+		}
+		for path, value := range values {
+			if _, isMissing := value.(missing); !isMissing {
+				setPath(doc, path, value)
+			}
+		}
+	}
+	return documents, nil
+}
+
+func projectStage(documents []bson.M, spec bson.M) ([]bson.M, error) {
+	excludeID := false
+	inclusive := false
+	for path, value := range spec {
+		if flag, isFlag := projectionFlag(value); isFlag {
+			if path == "_id" {
+				excludeID = !flag
+			} else if flag {
+				inclusive = true
+			}
+			continue
+		}
+		inclusive = true
+	}
+
+	projected := make([]bson.M, 0, len(documents))
+	for _, doc := range documents {
+		if !inclusive {
+			for path := range spec {
+				unsetPath(doc, path)
+			}
+			projected = append(projected, doc)
+			continue
+		}
+
+		result := bson.M{}
+		if id, ok := doc["_id"]; ok && !excludeID {
+			result["_id"] = id
+		}
+		for path, value := range spec {
+			if flag, isFlag := projectionFlag(value); isFlag {
+				if path == "_id" && !flag {
+					delete(result, "_id")
+				} else if flag {
+					if values := lookupPath(doc, path); len(values) > 0 {
+						setPath(result, path, values[0])
+					}
+				}
+				continue
+			}
+
+			computed, err := evaluateExpression(doc, value)
+			if err != nil {
+				return nil, err
+			}
+			if _, isMissing := computed.(missing); !isMissing {
+				setPath(result, path, computed)
+			}
+		}
+		projected = append(projected, result)
+	}
+	return projected, nil
+}
+
+// projectionFlag reports whether a $project value is an include (1/true) or exclude (0/false) flag.
+func projectionFlag(value any) (bool, bool) {
+	switch typed := value.(type) {
+	case bool:
+		return typed, true
+	case int32, int64, float64:
+		return toFloat(typed) != 0, true
+	}
+	return false, false
+}
+
+func sortStage(documents []bson.M, spec bson.D) ([]bson.M, error) {
+	if len(spec) == 0 {
+		return nil, fmt.Errorf("the sort key specification must not be empty")
+	}
+
+	sorts := make(Sorts, 0, len(spec))
+	for _, element := range spec {
+		direction := toFloat(element.Value)
+		if direction != 1 && direction != -1 {
+			return nil, fmt.Errorf("the sort order for %s must be 1 or -1", element.Key)
+		}
+		sorts = append(sorts, Sort{FieldName: element.Key, Ascending: direction == 1})
+	}
+
+	sortDocuments(documents, sorts)
+	return documents, nil
+}
+
+func unwindStage(documents []bson.M, argument bson.RawValue) ([]bson.M, error) {
+	path, preserveEmpty := "", false
+	if value, ok := argument.StringValueOK(); ok {
+		path = value
+	} else {
+		var spec bson.M
+		if err := argument.Unmarshal(&spec); err != nil {
+			return nil, fmt.Errorf("expected either a string or an object as specification")
+		}
+		path, _ = spec["path"].(string)
+		preserveEmpty, _ = spec["preserveNullAndEmptyArrays"].(bool)
+	}
+	if !strings.HasPrefix(path, "$") || len(path) < 2 {
+		return nil, fmt.Errorf("path option must be prefixed with a '$'")
+	}
+	path = path[1:]
+
+	unwound := make([]bson.M, 0, len(documents))
+	for _, doc := range documents {
+		values := lookupParts(doc, strings.Split(path, "."))
+		var value any
+		if len(values) > 0 {
+			value = values[0]
+		}
+
+		array, isArray := value.(bson.A)
+		switch {
+		case isArray && len(array) > 0:
+			for _, elem := range array {
+				unwoundDoc := copyDocument(doc)
+				setPath(unwoundDoc, path, elem)
+				unwound = append(unwound, unwoundDoc)
+			}
+		case len(values) > 0 && value != nil && !isArray:
+			unwound = append(unwound, doc)
+		case preserveEmpty:
+			if isArray {
+				unsetPath(doc, path)
+			}
+			unwound = append(unwound, doc)
+		}
+	}
+	return unwound, nil
+}
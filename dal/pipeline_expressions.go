@@ -0,0 +1,411 @@
+package dal
+
+import (
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"strings"
+)
+
+// evaluateExpression evaluates an aggregation expression like "$name", {$arrayElemAt: ["$categories", 0]} or a literal against doc.
+func evaluateExpression(doc bson.M, expression any) (any, error) {
+	switch typed := expression.(type) {
+	case string:
+		switch {
+		case strings.HasPrefix(typed, "$$"):
+			variable, path, _ := strings.Cut(typed[2:], ".")
+			if variable != "ROOT" && variable != "CURRENT" {
+				return nil, fmt.Errorf("use of undefined variable: %s", variable)
+			}
+			if path == "" {
+				return copyDocument(doc), nil
+			}
+			return fieldPath(doc, strings.Split(path, ".")), nil
+		case strings.HasPrefix(typed, "$"):
+			return fieldPath(doc, strings.Split(typed[1:], ".")), nil
+		}
+		return typed, nil
+	case bson.M:
+		if len(typed) == 1 {
+			for operator, argument := range typed {
+				if strings.HasPrefix(operator, "$") {
+					return evaluateOperator(doc, operator, argument)
+				}
+			}
+		}
+
+		object := bson.M{}
+		for key, value := range typed {
+			if strings.HasPrefix(key, "$") {
+				return nil, fmt.Errorf("an expression specification must contain exactly one field, the name of the expression")
+			}
+			evaluated, err := evaluateExpression(doc, value)
+			if err != nil {
+				return nil, err
+			}
+			if _, isMissing := evaluated.(missing); !isMissing {
+				object[key] = evaluated
+			}
+		}
+		return object, nil
+	case bson.A:
+		array := make(bson.A, 0, len(typed))
+		for _, value := range typed {
+			evaluated, err := evaluateExpression(doc, value)
+			if err != nil {
+				return nil, err
+			}
+			if _, isMissing := evaluated.(missing); isMissing {
+				evaluated = nil
+			}
+			array = append(array, evaluated)
+		}
+		return array, nil
+	}
+	return expression, nil
+}
+
+// fieldPath resolves a field path the way aggregation expressions do: a path through an array of documents
+// yields the array of the values found in them.
+func fieldPath(value any, parts []string) any {
+	if len(parts) == 0 {
+		return value
+	}
+
+	switch typed := value.(type) {
+	case bson.M:
+		child, ok := typed[parts[0]]
+		if !ok {
+			return missing{}
+		}
+		return fieldPath(child, parts[1:])
+	case bson.A:
+		values := bson.A{}
+		for _, elem := range typed {
+			if _, isDocument := elem.(bson.M); !isDocument {
+				continue
+			}
+			if resolved := fieldPath(elem, parts); resolved != (missing{}) {
+				values = append(values, resolved)
+			}
+		}
+		return values
+	}
+	return missing{}
+}
+
+func evaluateOperator(doc bson.M, operator string, argument any) (any, error) {
+	if operator == "$literal" {
+		return argument, nil
+	}
+
+	evaluated, err := evaluateExpression(doc, argument)
+	if err != nil {
+		return nil, err
+	}
+
+	switch operator {
+	case "$arrayElemAt":
+		args, ok := evaluated.(bson.A)
+		if !ok || len(args) != 2 {
+			return nil, fmt.Errorf("$arrayElemAt requires 2 arguments")
+		}
+		if args[0] == nil {
+			return nil, nil
+		}
+		array, ok := args[0].(bson.A)
+		if !ok {
+			return nil, fmt.Errorf("$arrayElemAt's first argument must be an array")
+		}
+		if typeOrder(args[1]) != typeOrder(int32(0)) {
+			return nil, fmt.Errorf("$arrayElemAt's second argument must be a numeric value")
+		}
+		index := int(toInt64(args[1]))
+		if index < 0 {
+			index += len(array)
+		}
+		if index < 0 || index >= len(array) {
+			return missing{}, nil
+		}
+		return array[index], nil
+	case "$first", "$last":
+		if array, ok := unwrapSingleArgument(argument, evaluated).(bson.A); ok {
+			if len(array) == 0 {
+				return missing{}, nil
+			}
+			if operator == "$first" {
+				return array[0], nil
+			}
+			return array[len(array)-1], nil
+		}
+		value := unwrapSingleArgument(argument, evaluated)
+		if value == nil || value == (missing{}) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%s's argument must be an array", operator)
+	case "$slice":
+		return sliceArray(evaluated)
+	case "$size":
+		array, ok := unwrapSingleArgument(argument, evaluated).(bson.A)
+		if !ok {
+			return nil, fmt.Errorf("the argument to $size must be an array")
+		}
+		return int32(len(array)), nil
+	case "$ifNull":
+		args, ok := evaluated.(bson.A)
+		if !ok || len(args) < 2 {
+			return nil, fmt.Errorf("$ifNull needs at least two arguments")
+		}
+		for _, value := range args[:len(args)-1] {
+			if value != nil {
+				return value, nil
+			}
+		}
+		return args[len(args)-1], nil
+	case "$sum":
+		value := unwrapSingleArgument(argument, evaluated)
+		if array, ok := value.(bson.A); ok {
+			return sumValues(array), nil
+		}
+		return sumValues(bson.A{value}), nil
+	}
+	return nil, fmt.Errorf("unrecognized expression '%s'", operator)
+}
+
+// sliceArray evaluates {$slice: [array, n]} and {$slice: [array, position, n]}, a negative n takes the last elements.
+func sliceArray(evaluated any) (any, error) {
+	args, ok := evaluated.(bson.A)
+	if !ok || len(args) < 2 || len(args) > 3 {
+		return nil, fmt.Errorf("$slice requires 2 or 3 arguments")
+	}
+	if args[0] == nil {
+		return nil, nil
+	}
+	array, ok := args[0].(bson.A)
+	if !ok {
+		return nil, fmt.Errorf("$slice's first argument must be an array")
+	}
+	for _, arg := range args[1:] {
+		if typeOrder(arg) != typeOrder(int32(0)) {
+			return nil, fmt.Errorf("$slice's arguments must be numeric values")
+		}
+	}
+
+	start, n := 0, int(toInt64(args[1]))
+	if len(args) == 3 {
+		start, n = int(toInt64(args[1])), int(toInt64(args[2]))
+		if n <= 0 {
+			return nil, fmt.Errorf("$slice's third argument must be positive")
+		}
+		if start < 0 {
+			start = max(len(array)+start, 0)
+		}
+	} else if n < 0 {
+		start, n = max(len(array)+n, 0), -n
+	}
+
+	start = min(start, len(array))
+	end := min(start+n, len(array))
+	return array[start:end], nil
+}
+
+// unwrapSingleArgument unwraps operators written as {$first: ["$field"]} instead of {$first: "$field"}.
+func unwrapSingleArgument(argument any, evaluated any) any {
+	if _, isArrayLiteral := argument.(bson.A); isArrayLiteral {
+		if args := evaluated.(bson.A); len(args) == 1 {
+			return args[0]
+		}
+	}
+	return evaluated
+}
+
+func sumValues(values bson.A) any {
+	var sum any = int32(0)
+	for _, value := range values {
+		if typeOrder(value) == typeOrder(int32(0)) {
+			sum = addNumbers(sum, value)
+		}
+	}
+	return sum
+}
+
+type groupAccumulator struct {
+	field      string
+	operator   string
+	expression any
+	// n caps how many values $firstN keeps per group.
+	n int
+}
+
+type groupState struct {
+	id     any
+	values map[string]bson.A
+	count  int32
+}
+
+func groupStage(documents []bson.M, spec bson.M) ([]bson.M, error) {
+	idExpression, ok := spec["_id"]
+	if !ok {
+		return nil, fmt.Errorf("a group specification must include an _id")
+	}
+
+	accumulators := make([]groupAccumulator, 0, len(spec)-1)
+	for field, value := range spec {
+		if field == "_id" {
+			continue
+		}
+		accumulator, ok := value.(bson.M)
+		if !ok || len(accumulator) != 1 {
+			return nil, fmt.Errorf("the field '%s' must be an accumulator object", field)
+		}
+		for operator, expression := range accumulator {
+			if operator == "$firstN" {
+				firstN, err := firstNAccumulator(field, expression)
+				if err != nil {
+					return nil, err
+				}
+				accumulators = append(accumulators, firstN)
+				continue
+			}
+			accumulators = append(accumulators, groupAccumulator{field: field, operator: operator, expression: expression})
+		}
+	}
+
+	groups := make([]*groupState, 0)
+	for _, doc := range documents {
+		id, err := evaluateExpression(doc, idExpression)
+		if err != nil {
+			return nil, err
+		}
+		if _, isMissing := id.(missing); isMissing {
+			id = nil
+		}
+
+		var group *groupState
+		for _, existing := range groups {
+			if valuesEqual(existing.id, id) {
+				group = existing
+				break
+			}
+		}
+		if group == nil {
+			group = &groupState{id: id, values: make(map[string]bson.A)}
+			groups = append(groups, group)
+		}
+
+		group.count++
+		for _, accumulator := range accumulators {
+			if accumulator.operator == "$firstN" && len(group.values[accumulator.field]) >= accumulator.n {
+				continue
+			}
+			value, err := evaluateExpression(doc, accumulator.expression)
+			if err != nil {
+				return nil, err
+			}
+			group.values[accumulator.field] = append(group.values[accumulator.field], value)
+		}
+	}
+
+	results := make([]bson.M, 0, len(groups))
+	for _, group := range groups {
+		result := bson.M{"_id": group.id}
+		for _, accumulator := range accumulators {
+			value, err := accumulate(accumulator.operator, group.values[accumulator.field], group.count)
+			if err != nil {
+				return nil, fmt.Errorf("field '%s': %w", accumulator.field, err)
+			}
+			result[accumulator.field] = value
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func firstNAccumulator(field string, spec any) (groupAccumulator, error) {
+	arguments, ok := spec.(bson.M)
+	if !ok {
+		return groupAccumulator{}, fmt.Errorf("$firstN of field '%s' must be an object with 'input' and 'n'", field)
+	}
+	input, hasInput := arguments["input"]
+	n, hasN := arguments["n"]
+	if !hasInput || !hasN || len(arguments) != 2 {
+		return groupAccumulator{}, fmt.Errorf("$firstN of field '%s' must be an object with 'input' and 'n'", field)
+	}
+	if typeOrder(n) != typeOrder(int32(0)) || toInt64(n) <= 0 {
+		return groupAccumulator{}, fmt.Errorf("$firstN of field '%s' requires 'n' to be a positive integer", field)
+	}
+	return groupAccumulator{field: field, operator: "$firstN", expression: input, n: int(toInt64(n))}, nil
+}
+
+func accumulate(operator string, values bson.A, count int32) (any, error) {
+	present := make(bson.A, 0, len(values))
+	for _, value := range values {
+		if _, isMissing := value.(missing); !isMissing {
+			present = append(present, value)
+		}
+	}
+
+	switch operator {
+	case "$push":
+		return present, nil
+	case "$addToSet":
+		set := bson.A{}
+		for _, value := range present {
+			if !anyValueEqual(set, []any{value}) {
+				set = append(set, value)
+			}
+		}
+		return set, nil
+	case "$firstN":
+		first := make(bson.A, 0, len(values))
+		for _, value := range values {
+			if _, isMissing := value.(missing); isMissing {
+				value = nil
+			}
+			first = append(first, value)
+		}
+		return first, nil
+	case "$first", "$last":
+		if len(values) == 0 {
+			return nil, nil
+		}
+		value := values[0]
+		if operator == "$last" {
+			value = values[len(values)-1]
+		}
+		if _, isMissing := value.(missing); isMissing {
+			return nil, nil
+		}
+		return value, nil
+	case "$sum":
+		return sumValues(present), nil
+	case "$count":
+		return count, nil
+	case "$avg":
+		var total float64
+		var numbers int
+		for _, value := range present {
+			if typeOrder(value) == typeOrder(int32(0)) {
+				total += toFloat(value)
+				numbers++
+			}
+		}
+		if numbers == 0 {
+			return nil, nil
+		}
+		return total / float64(numbers), nil
+	case "$min", "$max":
+		var result any
+		for _, value := range present {
+			if value == nil {
+				continue
+			}
+			if result == nil ||
+				(operator == "$min" && compareValues(value, result) < 0) ||
+				(operator == "$max" && compareValues(value, result) > 0) {
+				result = value
+			}
+		}
+		return result, nil
+	}
+	return nil, fmt.Errorf("unknown group operator '%s'", operator)
+}
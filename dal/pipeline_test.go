@@ -0,0 +1,176 @@
+package dal_test
+
+import (
+	"context"
+	"gifmanager-backend/dal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+)
+
+func TestMemoryDal_Aggregate_GroupsGifsByCategory(t *testing.T) {
+	// 1. ARRANGE
+	userID := primitive.NewObjectID()
+	funnyID := primitive.NewObjectID()
+	catsID := primitive.NewObjectID()
+
+	memoryDal := dal.NewMemoryDal()
+	_, err := memoryDal.Insert(context.Background(), dal.CollCategories, []any{
+		bson.M{"_id": funnyID, "name": "funny", "userId": userID},
+		bson.M{"_id": catsID, "name": "cats", "userId": userID},
+	})
+	require.Nil(t, err)
+	_, err = memoryDal.Insert(context.Background(), dal.CollGifs, []any{
+		bson.M{"name": "a", "userId": userID, "categoryId": funnyID},
+		bson.M{"name": "b", "userId": userID, "categoryId": catsID},
+		bson.M{"name": "c", "userId": userID, "categoryId": funnyID},
+		bson.M{"name": "d", "userId": primitive.NewObjectID(), "categoryId": funnyID},
+	})
+	require.Nil(t, err)
+
+	// the same shape of pipeline categories.GetGifsByCategory sends to mongo
+	pipeline := []any{
+		bson.M{"$match": bson.M{"userId": userID}},
+		bson.M{"$lookup": bson.M{
+			"from":         dal.CollCategories,
+			"localField":   "categoryId",
+			"foreignField": "_id",
+			"as":           "categories",
+		}},
+		bson.M{"$addFields": bson.M{
+			"category": bson.M{"$arrayElemAt": bson.A{"$categories", 0}},
+		}},
+		bson.M{"$group": bson.M{
+			"_id":   "$categoryId",
+			"gifs":  bson.M{"$push": "$name"},
+			"name":  bson.M{"$first": "$category.name"},
+			"count": bson.M{"$sum": 1},
+		}},
+		bson.M{"$project": bson.M{"categoryId": "$_id", "gifs": 1, "name": 1, "count": 1, "_id": 0}},
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "name", Value: 1}}}},
+	}
+
+	// 2. ACT
+	var result []bson.M
+	err = memoryDal.Aggregate(context.Background(), dal.CollGifs, pipeline, &result)
+
+	// 3. ASSERT
+	require.Nil(t, err)
+	require.Len(t, result, 2)
+	assert.Equal(t, bson.M{"categoryId": funnyID, "name": "funny", "gifs": bson.A{"a", "c"}, "count": int32(2)}, result[0])
+	assert.Equal(t, bson.M{"categoryId": catsID, "name": "cats", "gifs": bson.A{"b"}, "count": int32(1)}, result[1])
+}
+
+func TestEvaluatePipeline_UnwindSkipLimitAndCount(t *testing.T) {
+	// 1. ARRANGE
+	documents := []bson.M{
+		{"name": "a", "tags": bson.A{"x", "y"}},
+		{"name": "b", "tags": bson.A{}},
+		{"name": "c", "tags": bson.A{"z"}},
+	}
+
+	// 2. ACT
+	unwound, errUnwind := dal.EvaluatePipeline(documents, []any{
+		bson.M{"$unwind": "$tags"},
+		bson.M{"$skip": 1},
+		bson.M{"$limit": 1},
+	}, nil)
+	counted, errCount := dal.EvaluatePipeline(documents, []any{
+		bson.M{"$unwind": bson.M{"path": "$tags", "preserveNullAndEmptyArrays": true}},
+		bson.M{"$count": "total"},
+	}, nil)
+
+	// 3. ASSERT
+	require.Nil(t, errUnwind)
+	assert.Equal(t, []bson.M{{"name": "a", "tags": "y"}}, unwound)
+	require.Nil(t, errCount)
+	assert.Equal(t, []bson.M{{"total": int32(4)}}, counted)
+}
+
+func TestEvaluatePipeline_InvalidMatchReturnsError(t *testing.T) {
+	// $match must be a document, mongo rejects an array as well
+	_, err := dal.EvaluatePipeline([]bson.M{{"name": "a"}}, []any{
+		bson.M{"$match": []any{bson.M{"name": "a"}}},
+	}, nil)
+
+	assert.NotNil(t, err)
+}
+
+func TestEvaluatePipeline_Slice(t *testing.T) {
+	// 1. ARRANGE
+	documents := []bson.M{{"tags": bson.A{"a", "b", "c"}}}
+
+	testCases := []struct {
+		name     string
+		slice    bson.A
+		expected bson.A
+	}{
+		{"first n", bson.A{"$tags", 2}, bson.A{"a", "b"}},
+		{"more than the length", bson.A{"$tags", 5}, bson.A{"a", "b", "c"}},
+		{"last n", bson.A{"$tags", -2}, bson.A{"b", "c"}},
+		{"from a position", bson.A{"$tags", 1, 5}, bson.A{"b", "c"}},
+		{"past the end", bson.A{"$tags", 4, 1}, bson.A{}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			// 2. ACT
+			result, err := dal.EvaluatePipeline(documents, []any{
+				bson.M{"$project": bson.M{"_id": 0, "tags": bson.M{"$slice": testCase.slice}}},
+			}, nil)
+
+			// 3. ASSERT
+			require.Nil(t, err)
+			assert.Equal(t, []bson.M{{"tags": testCase.expected}}, result)
+		})
+	}
+}
+
+func TestEvaluatePipeline_FirstN(t *testing.T) {
+	// 1. ARRANGE
+	documents := []bson.M{
+		{"group": "a", "name": "x"},
+		{"group": "a"},
+		{"group": "a", "name": "z"},
+		{"group": "b", "name": "w"},
+	}
+
+	// 2. ACT
+	result, err := dal.EvaluatePipeline(documents, []any{
+		bson.M{"$group": bson.M{"_id": "$group", "names": bson.M{"$firstN": bson.M{"input": "$name", "n": 2}}}},
+	}, nil)
+
+	// 3. ASSERT
+	require.Nil(t, err)
+	assert.Equal(t, []bson.M{
+		{"_id": "a", "names": bson.A{"x", nil}},
+		{"_id": "b", "names": bson.A{"w"}},
+	}, result)
+}
+
+func TestEvaluatePipeline_FirstN_InvalidArgumentsReturnError(t *testing.T) {
+	testCases := []struct {
+		name   string
+		firstN any
+	}{
+		{"not an object", "$name"},
+		{"no n", bson.M{"input": "$name"}},
+		{"no input", bson.M{"n": 2}},
+		{"n is not a number", bson.M{"input": "$name", "n": "2"}},
+		{"n is not positive", bson.M{"input": "$name", "n": 0}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			// 1. ACT
+			_, err := dal.EvaluatePipeline([]bson.M{{"name": "a"}}, []any{
+				bson.M{"$group": bson.M{"_id": nil, "names": bson.M{"$firstN": testCase.firstN}}},
+			}, nil)
+
+			// 2. ASSERT
+			assert.NotNil(t, err)
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package dal
+
+import (
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"strings"
+	"unicode"
+)
+
+// ScoreField is set by DAL.Search to the relevance of every document found, decode it with a `bson:"score"` field.
+const ScoreField = "score"
+
+// ErrNoTextIndex is returned by DAL.Search when the collection has no text index.
+var ErrNoTextIndex = errors.New("the collection has no text index")
+
+// SearchArguments are the arguments of DAL.Search. The text is split into terms with Tokenize, a document matches
+// when one of its text fields contains at least one of the terms, and Filter narrows the matching documents down.
+type SearchArguments struct {
+	Text   string
+	Filter any
+	Skip   *int64
+	Limit  *int64
+}
+
+func NewSearchArguments(text string) *SearchArguments {
+	return &SearchArguments{Text: text}
+}
+
+func (args *SearchArguments) WithFilter(filter any) *SearchArguments {
+	args.Filter = filter
+	return args
+}
+
+func (args *SearchArguments) WithSkip(skip int64) *SearchArguments {
+	args.Skip = &skip
+	return args
+}
+
+func (args *SearchArguments) WithLimit(limit int) *SearchArguments {
+	limit64 := int64(limit)
+	args.Limit = &limit64
+	return args
+}
+
+// ToMongoFilter combines the filter with the $text query of the terms. Only the terms are sent, so mongo's phrase and
+// negation syntax can't be used and every DAL implementation finds the same documents.
+func (args SearchArguments) ToMongoFilter() bson.M {
+	text := bson.M{"$text": bson.M{"$search": strings.Join(Tokenize(args.Text), " ")}}
+	if args.Filter == nil {
+		return text
+	}
+	return bson.M{"$and": bson.A{args.Filter, text}}
+}
+
+// Tokenize splits the text into lowercase terms at everything that is not a letter or a digit,
+// like mongo does for text indexes without a language, e.g. "Spider-Man 2" is split into spider, man and 2.
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// textScore computes the relevance of the document to the terms the way mongo scores text indexes without a language.
+// Every term found in a string adds the weight of its field: the first occurrence counts 1, every further one half
+// of the previous, scaled by how much of the string the term is, and a string that is only the term counts 10% more.
+// The values of array fields are scored one by one. A score of 0 means that the document doesn't match.
+func textScore(index Index, doc bson.M, terms []string) float64 {
+	searched := make(map[string]bool, len(terms))
+	for _, term := range terms {
+		searched[term] = true
+	}
+
+	var score float64
+	for _, key := range index.Keys {
+		if !key.Text {
+			continue
+		}
+		for _, value := range lookupPath(doc, key.FieldName) {
+			if text, ok := value.(string); ok {
+				score += index.weight(key.FieldName) * stringScore(text, searched)
+			}
+		}
+	}
+	return score
+}
+
+func stringScore(text string, searched map[string]bool) float64 {
+	tokens := Tokenize(text)
+	counts := make(map[string]int)
+	frequencies := make(map[string]float64)
+	for _, token := range tokens {
+		if !searched[token] {
+			continue
+		}
+		frequencies[token] += 1 / float64(int(1)<<counts[token])
+		counts[token]++
+	}
+
+	var score float64
+	for token, frequency := range frequencies {
+		coefficient := 0.5*float64(counts[token])/float64(len(tokens)) + 0.5
+		termScore := frequency * coefficient
+		if strings.ToLower(text) == token {
+			termScore *= 1.1
+		}
+		score += termScore
+	}
+	return score
+}
@@ -2,6 +2,7 @@ package dal
 
 type UpdateOptions struct {
 	Upsert bool
+	Multi  bool
 }
 
 type UpdateOptionsFunc func(o *UpdateOptions)
@@ -9,3 +10,8 @@ type UpdateOptionsFunc func(o *UpdateOptions)
 var InsertIfNotFound UpdateOptionsFunc = func(o *UpdateOptions) {
 	o.Upsert = true
 }
+
+// UpdateAllMatching updates every document matching the filter instead of only the first one.
+var UpdateAllMatching UpdateOptionsFunc = func(o *UpdateOptions) {
+	o.Multi = true
+}
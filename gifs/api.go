@@ -1,14 +1,21 @@
 package gifs
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"gifmanager-backend/authz"
 	"gifmanager-backend/dal"
 	"gifmanager-backend/httputil"
+	"gifmanager-backend/shares"
 	"github.com/gorilla/mux"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 type Api struct {
@@ -32,6 +39,10 @@ func (api Api) InitializeEndpoints(route *mux.Router) {
 		Path("/gifs/{id}").
 		Methods(http.MethodPut).
 		Handler(http.HandlerFunc(api.UpdateGifHandler))
+	route.
+		Path("/gifs/{id}").
+		Methods(http.MethodPatch).
+		Handler(http.HandlerFunc(api.PatchGifHandler))
 	route.
 		Path("/gifs/{id}").
 		Methods(http.MethodDelete).
@@ -41,40 +52,70 @@ func (api Api) InitializeEndpoints(route *mux.Router) {
 		Path("/gifs").
 		Methods(http.MethodGet).
 		Handler(http.HandlerFunc(api.GetGifsHandler))
+	// registered before /gifs/{id}, which would match search as an id
+	route.
+		Path("/gifs/search").
+		Methods(http.MethodGet).
+		Handler(http.HandlerFunc(api.SearchGifsHandler))
+	route.
+		Path("/gifs/{id}").
+		Methods(http.MethodGet).
+		Handler(http.HandlerFunc(api.GetGifHandler))
+	route.
+		Path("/feed").
+		Methods(http.MethodGet).
+		Handler(http.HandlerFunc(api.FeedHandler))
+	route.
+		Path("/tags").
+		Methods(http.MethodGet).
+		Handler(http.HandlerFunc(api.TagsHandler))
 }
 
+// IncludeParam lists the related documents that are embedded in the response, e.g. ?include=category
+const (
+	IncludeParam    = "include"
+	IncludeCategory = "category"
+)
+
 func (api Api) CreateGifHandler(writer http.ResponseWriter, request *http.Request) {
 	ctx := request.Context()
 	userID := ctx.Value("userID")
 
 	var gifRequest GifRequest
 
-	if decodeErr := json.NewDecoder(request.Body).
-		Decode(&gifRequest); decodeErr != nil {
-		httputil.WriteHttpError(writer, http.StatusBadRequest, fmt.Sprintf(ErrDecodingGifFmt, decodeErr.Error()))
+	if decodeErr := httputil.DecodeJSON(writer, request, &gifRequest); decodeErr != nil {
+		httputil.WriteError(writer, decodeErr)
 		return
 	}
 
 	gif := gifRequest.ToModel()
 	gif.ID = primitive.NewObjectID()
 	gif.UserId = userID.(primitive.ObjectID)
-	if _, errInsert := api.Dal.Insert(ctx, dal.CollGifs, []any{gif}); errInsert != nil {
-		fmt.Println(errInsert.Error())
-		httputil.WriteHttpError(writer, http.StatusInternalServerError, ErrInsertingGifs)
-		return
-	}
+	gif.Version = 1
 
-	update := bson.M{"$inc": bson.M{"gifCount": 1}}
-	if _, errUpdating := api.Dal.UpdateByID(ctx, dal.CollCategories, gif.CategoryId.Hex(), update); errUpdating != nil {
-		fmt.Println(errUpdating.Error())
-		httputil.WriteHttpError(writer, http.StatusInternalServerError, ErrUpdatingCategoriesCount)
+	// the gif and the counter of its category are written together, so the counter can't drift
+	errTransaction := api.Dal.WithTransaction(ctx, func(txCtx context.Context) error {
+		// the counter is increased first, so nothing is written when the category doesn't belong to the user
+		if err := api.addToCategory(txCtx, gif.CategoryId); err != nil {
+			return err
+		}
+
+		if _, errInsert := api.Dal.Insert(txCtx, dal.CollGifs, []any{gif}); errInsert != nil {
+			fmt.Println(errInsert.Error())
+			return ErrInsertingGifs
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		httputil.WriteError(writer, errTransaction)
 		return
 	}
 
 	dto := gif.ToDto()
+	writer.Header().Set("ETag", httputil.VersionETag(gif.Version))
 	if errEncode := json.NewEncoder(writer).Encode(&dto); errEncode != nil {
 		fmt.Println(errEncode.Error())
-		httputil.WriteHttpError(writer, http.StatusInternalServerError, ErrEncodingGifs)
+		httputil.WriteError(writer, ErrEncodingGifs)
 		return
 	}
 
@@ -85,60 +126,155 @@ func (api Api) GetGifsHandler(writer http.ResponseWriter, request *http.Request)
 	ctx := request.Context()
 	userID := ctx.Value("userID").(primitive.ObjectID)
 
-	api.QueryParamsParser.LoadValues(request.URL.Query())
+	query, err := api.QueryParamsParser.Parse(request.URL.Query())
+	if err != nil {
+		httputil.WriteError(writer, err)
+		return
+	}
 
 	filter := make(bson.M)
-	if api.QueryParamsParser.HasFilter() {
-		queryFilter, err := api.QueryParamsParser.GetFilter()
-		if err != nil {
-			httputil.WriteHttpError(writer, http.StatusBadRequest, err.Error())
-			return
-		}
-		filter = queryFilter.(bson.M)
+	if query.HasFilter() {
+		filter = query.Filter
 	}
 	filter["userId"] = userID
+	pagination := query.Pagination
 
 	findArgs := dal.NewFindArguments().
-		WithFilter(filter)
+		WithFilter(filter).
+		WithSorts(query.Sorts).
+		WithSkip(pagination.Skip()).
+		WithLimit(pagination.PageSize)
 
 	gifs := make(Gifs, 0)
 	if err := api.Dal.Find(ctx, dal.CollGifs, *findArgs, &gifs); err != nil {
 		fmt.Println(err)
-		httputil.WriteHttpError(writer, http.StatusInternalServerError, ErrFindingGifs)
+		httputil.WriteError(writer, ErrFindingGifs)
 		return
 	}
 
-	if err := json.NewEncoder(writer).Encode(&gifs); err != nil {
+	total, err := api.Dal.Count(ctx, dal.CollGifs, filter)
+	if err != nil {
 		fmt.Println(err)
-		httputil.WriteHttpError(writer, http.StatusInternalServerError, ErrEncodingGifs)
+		httputil.WriteError(writer, ErrFindingGifs)
 		return
 	}
+	httputil.WritePaginationHeaders(writer, request.URL, pagination, total)
 
-	writer.WriteHeader(http.StatusOK)
+	if err := httputil.WriteJSONWithETag(writer, request, &gifs); err != nil {
+		fmt.Println(err)
+		httputil.WriteError(writer, ErrEncodingGifs)
+	}
 }
 
-func (api Api) DeleteGifHandler(writer http.ResponseWriter, request *http.Request) {
+// GetGifHandler returns the gif with the ETag of its version. With ?include=category the gif's category is embedded,
+// the ETag of that response covers the category as well and can't be used with If-Match.
+// The members of a group can read the gifs shared with it, but only the owner can change them.
+func (api Api) GetGifHandler(writer http.ResponseWriter, request *http.Request) {
 	ctx := request.Context()
 	id := mux.Vars(request)["id"]
 
 	gifID, errObjId := primitive.ObjectIDFromHex(id)
 	if errObjId != nil {
-		httputil.WriteHttpError(writer, http.StatusBadRequest, fmt.Sprintf(ErrInvalidIDFmt, id))
+		httputil.WriteError(writer, httputil.ErrInvalidID.WithMessagef(ErrInvalidIDFmt, id))
 		return
 	}
 
-	var deletedGif Gif
-	if err := api.Dal.FindAndDeleteByID(ctx, dal.CollGifs, gifID.Hex(), &deletedGif); err != nil {
-		httputil.WriteHttpError(writer, http.StatusInternalServerError, ErrDeletingGif)
+	includeCategory, err := parseInclude(request.URL.Query().Get(IncludeParam))
+	if err != nil {
+		httputil.WriteError(writer, err)
 		return
 	}
 
-	if deletedGif.CategoryId.IsZero() == false {
-		update := bson.M{"$inc": bson.M{"gifCount": -1}}
-		if _, errUpdating := api.Dal.UpdateByID(ctx, dal.CollCategories, deletedGif.CategoryId.Hex(), update); errUpdating != nil {
-			httputil.WriteHttpError(writer, http.StatusInternalServerError, ErrUpdatingCategoriesCount)
-			return
+	gif, err := api.findVisibleGif(ctx, gifID)
+	if err != nil {
+		httputil.WriteError(writer, err)
+		return
+	}
+
+	dto := gif.ToDto()
+	if !includeCategory {
+		if err := httputil.WriteVersionedJSON(writer, request, gif.Version, &dto); err != nil {
+			fmt.Println(err.Error())
+			httputil.WriteError(writer, ErrEncodingGifs)
 		}
+		return
+	}
+
+	if dto.Category, err = api.findGifCategory(ctx, gif); err != nil {
+		httputil.WriteError(writer, err)
+		return
+	}
+	if err := httputil.WriteJSONWithETag(writer, request, &dto); err != nil {
+		fmt.Println(err.Error())
+		httputil.WriteError(writer, ErrEncodingGifs)
+	}
+}
+
+// parseInclude reports whether the category is included, category is the only document that can be included so far.
+func parseInclude(include string) (bool, error) {
+	includeCategory := false
+	for _, name := range strings.Split(include, ",") {
+		switch strings.TrimSpace(name) {
+		case "":
+		case IncludeCategory:
+			includeCategory = true
+		default:
+			return false, httputil.ErrInvalidQuery.WithMessagef("%s can't include '%s'", IncludeParam, name)
+		}
+	}
+	return includeCategory, nil
+}
+
+// findGifCategory returns the category of the gif or nil when the gif has none.
+// The category is looked up for the gif's owner, who isn't the caller when the gif was shared.
+func (api Api) findGifCategory(ctx context.Context, gif Gif) (*GifCategoryDto, error) {
+	if gif.CategoryId.IsZero() {
+		return nil, nil
+	}
+
+	filter := bson.M{"_id": gif.CategoryId, authz.OwnerField: gif.UserId}
+	found := make([]gifCategory, 0, 1)
+	if err := api.Dal.Find(ctx, dal.CollCategories, *dal.NewFindArguments().WithFilter(filter).WithLimit(1), &found); err != nil {
+		fmt.Println(err.Error())
+		return nil, ErrFindingGifs
+	}
+	if len(found) == 0 {
+		return nil, nil
+	}
+	return found[0].ToDto(), nil
+}
+
+func (api Api) DeleteGifHandler(writer http.ResponseWriter, request *http.Request) {
+	ctx := request.Context()
+	id := mux.Vars(request)["id"]
+
+	gifID, errObjId := primitive.ObjectIDFromHex(id)
+	if errObjId != nil {
+		httputil.WriteError(writer, httputil.ErrInvalidID.WithMessagef(ErrInvalidIDFmt, id))
+		return
+	}
+
+	ifMatch := httputil.ParseIfMatch(request)
+	errTransaction := api.Dal.WithTransaction(ctx, func(txCtx context.Context) error {
+		var deletedGif Gif
+		filter := ifMatch.AddToFilter(authz.OwnedByID(txCtx, authz.OwnerField, gifID), "version")
+		if err := api.Dal.FindAndDelete(txCtx, dal.CollGifs, filter, &deletedGif); err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return api.notFoundOrChanged(txCtx, gifID)
+			}
+			fmt.Println(err.Error())
+			return ErrDeletingGif
+		}
+
+		if err := shares.Unshare(txCtx, api.Dal, bson.M{shares.GifIDField: gifID}); err != nil {
+			fmt.Println(err.Error())
+			return ErrDeletingGif
+		}
+		return api.removeFromCategory(txCtx, deletedGif.CategoryId)
+	})
+	if errTransaction != nil {
+		httputil.WriteError(writer, errTransaction)
+		return
 	}
 
 	writer.WriteHeader(http.StatusNoContent)
@@ -151,32 +287,351 @@ func (api Api) UpdateGifHandler(writer http.ResponseWriter, request *http.Reques
 
 	gifID, errObjId := primitive.ObjectIDFromHex(id)
 	if errObjId != nil {
-		httputil.WriteHttpError(writer, http.StatusBadRequest, fmt.Sprintf(ErrInvalidIDFmt, errObjId.Error()))
+		httputil.WriteError(writer, httputil.ErrInvalidID.WithMessagef(ErrInvalidIDFmt, id))
 		return
 	}
 
 	var gifRequest GifRequest
-	if decodeErr := json.NewDecoder(request.Body).
-		Decode(&gifRequest); decodeErr != nil {
-		httputil.WriteHttpError(writer, http.StatusBadRequest, fmt.Sprintf(ErrDecodingGifFmt, decodeErr.Error()))
+	if decodeErr := httputil.DecodeJSON(writer, request, &gifRequest); decodeErr != nil {
+		httputil.WriteError(writer, decodeErr)
 		return
 	}
 
 	gif := gifRequest.ToModel()
 	gif.UserId = userID
-	update := bson.M{"$set": gif}
 
-	filter := bson.M{"_id": gifID}
-	result, errUpdating := api.Dal.Update(ctx, "gifs", filter, update)
-	if errUpdating != nil {
-		httputil.WriteHttpError(writer, http.StatusInternalServerError, ErrUpdatingGif)
+	// when the gif moves to another category both counters change together with the gif
+	ifMatch := httputil.ParseIfMatch(request)
+	errTransaction := api.Dal.WithTransaction(ctx, func(txCtx context.Context) error {
+		existing, err := api.findOwnedGif(txCtx, gifID)
+		if err != nil {
+			return err
+		}
+		if !ifMatch.Matches(existing.Version) {
+			return httputil.ErrPreconditionFailed
+		}
+
+		if err := api.moveToCategory(txCtx, existing.CategoryId, gif.CategoryId); err != nil {
+			return err
+		}
+
+		update := bson.M{"$set": gif, "$inc": bson.M{"version": 1}}
+		if err := api.updateVersion(txCtx, gifID, ifMatch, update); err != nil {
+			return err
+		}
+		gif.Version = existing.Version + 1
+		return nil
+	})
+	if errTransaction != nil {
+		httputil.WriteError(writer, errTransaction)
+		return
+	}
+
+	writer.Header().Set("ETag", httputil.VersionETag(gif.Version))
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// PatchGifHandler applies a JSON merge patch to the gif and returns the updated gif.
+func (api Api) PatchGifHandler(writer http.ResponseWriter, request *http.Request) {
+	ctx := request.Context()
+	id := mux.Vars(request)["id"]
+
+	gifID, errObjId := primitive.ObjectIDFromHex(id)
+	if errObjId != nil {
+		httputil.WriteError(writer, httputil.ErrInvalidID.WithMessagef(ErrInvalidIDFmt, id))
+		return
+	}
+
+	var patch GifPatch
+	if decodeErr := httputil.DecodeMergePatch(writer, request, &patch); decodeErr != nil {
+		httputil.WriteError(writer, decodeErr)
+		return
+	}
+
+	var patched Gif
+	ifMatch := httputil.ParseIfMatch(request)
+	errTransaction := api.Dal.WithTransaction(ctx, func(txCtx context.Context) error {
+		existing, err := api.findOwnedGif(txCtx, gifID)
+		if err != nil {
+			return err
+		}
+		if !ifMatch.Matches(existing.Version) {
+			return httputil.ErrPreconditionFailed
+		}
+
+		if patch.CategoryId.Set {
+			if err := api.moveToCategory(txCtx, existing.CategoryId, patch.CategoryId.Value); err != nil {
+				return err
+			}
+		}
+
+		if update := patch.ToUpdate(); len(update) > 0 {
+			update["$inc"] = bson.M{"version": 1}
+			if err := api.updateVersion(txCtx, gifID, ifMatch, update); err != nil {
+				return err
+			}
+		}
+
+		patched, err = api.findOwnedGif(txCtx, gifID)
+		return err
+	})
+	if errTransaction != nil {
+		httputil.WriteError(writer, errTransaction)
+		return
+	}
+
+	dto := patched.ToDto()
+	writer.Header().Set("ETag", httputil.VersionETag(patched.Version))
+	writer.Header().Set("Content-Type", "application/json")
+	if errEncode := json.NewEncoder(writer).Encode(&dto); errEncode != nil {
+		fmt.Println(errEncode.Error())
+		httputil.WriteError(writer, ErrEncodingGifs)
+	}
+}
+
+// FeedHandler returns the gifs other users shared with the caller's groups, newest first, paged like GET /gifs.
+func (api Api) FeedHandler(writer http.ResponseWriter, request *http.Request) {
+	ctx := request.Context()
+
+	pagination, err := httputil.ParsePagination(request.URL.Query())
+	if err != nil {
+		httputil.WriteError(writer, err)
 		return
 	}
 
+	filter, err := shares.SharedGifsFilter(ctx, api.Dal)
+	if err != nil {
+		fmt.Println(err.Error())
+		httputil.WriteError(writer, ErrFindingGifs)
+		return
+	}
+
+	gifs := make(Gifs, 0)
+	var total int64
+	if filter != nil {
+		findArgs := dal.NewFindArguments().
+			WithFilter(filter).
+			WithSorts(dal.Sorts{{FieldName: "_id", Ascending: false}}).
+			WithSkip(pagination.Skip()).
+			WithLimit(pagination.PageSize)
+		if err := api.Dal.Find(ctx, dal.CollGifs, *findArgs, &gifs); err != nil {
+			fmt.Println(err.Error())
+			httputil.WriteError(writer, ErrFindingGifs)
+			return
+		}
+
+		if total, err = api.Dal.Count(ctx, dal.CollGifs, filter); err != nil {
+			fmt.Println(err.Error())
+			httputil.WriteError(writer, ErrFindingGifs)
+			return
+		}
+	}
+	httputil.WritePaginationHeaders(writer, request.URL, pagination, total)
+
+	if err := httputil.WriteJSONWithETag(writer, request, gifs.ToSharedDto()); err != nil {
+		fmt.Println(err.Error())
+		httputil.WriteError(writer, ErrEncodingGifs)
+	}
+}
+
+// findVisibleGif returns the gif if the user in the context owns it or it was shared with one of the user's groups,
+// otherwise ErrGifNotFound.
+func (api Api) findVisibleGif(ctx context.Context, gifID primitive.ObjectID) (Gif, error) {
+	shared, err := shares.SharedGifsFilter(ctx, api.Dal)
+	if err != nil {
+		fmt.Println(err.Error())
+		return Gif{}, ErrFindingGifs
+	}
+	if shared == nil {
+		return api.findOwnedGif(ctx, gifID)
+	}
+
+	visible := bson.A{bson.M{authz.OwnerField: authz.UserID(ctx)}, shared}
+	filter := bson.M{"_id": gifID, "$or": visible}
+	found := make(Gifs, 0, 1)
+	if err := api.Dal.Find(ctx, dal.CollGifs, *dal.NewFindArguments().WithFilter(filter).WithLimit(1), &found); err != nil {
+		fmt.Println(err.Error())
+		return Gif{}, ErrFindingGifs
+	}
+	if len(found) == 0 {
+		return Gif{}, ErrGifNotFound.WithMessagef(ErrGifNotFoundFmt, gifID.Hex())
+	}
+	return found[0], nil
+}
+
+// findOwnedGif returns the gif if the user in the context owns it, otherwise ErrGifNotFound.
+func (api Api) findOwnedGif(ctx context.Context, gifID primitive.ObjectID) (Gif, error) {
+	filter := authz.OwnedByID(ctx, authz.OwnerField, gifID)
+	found := make(Gifs, 0, 1)
+	if err := api.Dal.Find(ctx, dal.CollGifs, *dal.NewFindArguments().WithFilter(filter).WithLimit(1), &found); err != nil {
+		fmt.Println(err.Error())
+		return Gif{}, ErrFindingGifs
+	}
+	if len(found) == 0 {
+		return Gif{}, ErrGifNotFound.WithMessagef(ErrGifNotFoundFmt, gifID.Hex())
+	}
+	return found[0], nil
+}
+
+// updateVersion updates the gif only if its version still matches the precondition. The version was checked when the gif
+// was read, so a gif that doesn't match anymore was changed by another request in the meantime.
+func (api Api) updateVersion(ctx context.Context, gifID primitive.ObjectID, ifMatch httputil.IfMatch, update bson.M) error {
+	filter := ifMatch.AddToFilter(authz.OwnedByID(ctx, authz.OwnerField, gifID), "version")
+	result, err := api.Dal.Update(ctx, dal.CollGifs, filter, update)
+	if err != nil {
+		fmt.Println(err.Error())
+		return ErrUpdatingGif
+	}
 	if result.MatchedCount == 0 {
-		httputil.WriteHttpError(writer, http.StatusNotFound, fmt.Sprintf(ErrGifNotFoundFmt, id))
+		return httputil.ErrPreconditionFailed
+	}
+	return nil
+}
+
+// notFoundOrChanged tells why a write that was scoped to the owner and the version didn't match the gif.
+func (api Api) notFoundOrChanged(ctx context.Context, gifID primitive.ObjectID) error {
+	if _, err := api.findOwnedGif(ctx, gifID); err != nil {
+		return err
+	}
+	return httputil.ErrPreconditionFailed
+}
+
+// moveToCategory moves the gif's count from one category to the other, a zero id stands for no category.
+func (api Api) moveToCategory(ctx context.Context, from primitive.ObjectID, to primitive.ObjectID) error {
+	if from == to {
+		return nil
+	}
+	if !to.IsZero() {
+		if err := api.addToCategory(ctx, to); err != nil {
+			return err
+		}
+	}
+	return api.removeFromCategory(ctx, from)
+}
+
+// addToCategory increases the gif count of the category, it fails with ErrCategoryDoesNotExist when the user doesn't own the category.
+func (api Api) addToCategory(ctx context.Context, categoryID primitive.ObjectID) error {
+	update := bson.M{"$inc": bson.M{"gifCount": 1}}
+	result, err := api.Dal.Update(ctx, dal.CollCategories, authz.OwnedByID(ctx, authz.OwnerField, categoryID), update)
+	if err != nil {
+		fmt.Println(err.Error())
+		return ErrUpdatingCategoriesCount
+	}
+	if result.MatchedCount == 0 {
+		return ErrCategoryDoesNotExist
+	}
+	return nil
+}
+
+// removeFromCategory decreases the gif count of the category the gif was in, if it had one.
+func (api Api) removeFromCategory(ctx context.Context, categoryID primitive.ObjectID) error {
+	if categoryID.IsZero() {
+		return nil
+	}
+
+	update := bson.M{"$inc": bson.M{"gifCount": -1}}
+	if _, err := api.Dal.Update(ctx, dal.CollCategories, authz.OwnedByID(ctx, authz.OwnerField, categoryID), update); err != nil {
+		fmt.Println(err.Error())
+		return ErrUpdatingCategoriesCount
+	}
+	return nil
+}
+
+// TagsLimitParam limits the number of tags returned by GET /tags, e.g. ?limit=20
+const (
+	TagsLimitParam   = "limit"
+	DefaultTagsLimit = 100
+	MaxTagsLimit     = 1000
+)
+
+// TagsHandler returns the tags of the user's gifs with the number of gifs having each tag, the most used tags first.
+func (api Api) TagsHandler(writer http.ResponseWriter, request *http.Request) {
+	ctx := request.Context()
+
+	limit, err := parseTagsLimit(request.URL.Query().Get(TagsLimitParam))
+	if err != nil {
+		httputil.WriteError(writer, err)
 		return
 	}
 
-	writer.WriteHeader(http.StatusNoContent)
+	tagCounts := make(TagCounts, 0)
+	pipeline := getTagCountsPipeline(authz.OwnedBy(ctx, authz.OwnerField, bson.M{}), limit)
+	if err := api.Dal.Aggregate(ctx, dal.CollGifs, pipeline, &tagCounts); err != nil {
+		fmt.Println(err.Error())
+		httputil.WriteError(writer, ErrFindingTags)
+		return
+	}
+
+	if err := httputil.WriteJSONWithETag(writer, request, tagCounts.ToDto()); err != nil {
+		fmt.Println(err.Error())
+		httputil.WriteError(writer, ErrEncodingGifs)
+	}
+}
+
+func parseTagsLimit(value string) (int, error) {
+	if value == "" {
+		return DefaultTagsLimit, nil
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 || limit > MaxTagsLimit {
+		return 0, httputil.ErrInvalidQuery.WithMessagef("invalid %s: %s, it should be a number between 1 and %d", TagsLimitParam, value, MaxTagsLimit)
+	}
+	return limit, nil
+}
+
+// getTagCountsPipeline counts the gifs matching the filter by tag. Ties are sorted by tag, so the order is stable.
+func getTagCountsPipeline(match bson.M, limit int) []any {
+	return []any{
+		bson.M{"$match": match},
+		bson.M{"$unwind": "$tags"},
+		bson.M{"$group": bson.M{
+			"_id":   "$tags",
+			"count": bson.M{"$sum": 1},
+		}},
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}},
+		bson.M{"$limit": limit},
+	}
+}
+
+// SearchQueryParam is the text searched by GET /gifs/search, e.g. ?q=funny cat
+const (
+	SearchQueryParam     = "q"
+	MaxSearchQueryLength = 200
+)
+
+// SearchGifsHandler returns the user's gifs whose name or tags contain any of the words of the query, the most
+// relevant first, paged like GET /gifs.
+func (api Api) SearchGifsHandler(writer http.ResponseWriter, request *http.Request) {
+	ctx := request.Context()
+
+	query := request.URL.Query().Get(SearchQueryParam)
+	if len(query) > MaxSearchQueryLength || len(dal.Tokenize(query)) == 0 {
+		httputil.WriteError(writer, httputil.ErrInvalidQuery.WithMessagef(ErrInvalidSearchQueryFmt, SearchQueryParam, MaxSearchQueryLength))
+		return
+	}
+
+	pagination, err := httputil.ParsePagination(request.URL.Query())
+	if err != nil {
+		httputil.WriteError(writer, err)
+		return
+	}
+
+	searchArgs := dal.NewSearchArguments(query).
+		WithFilter(authz.OwnedBy(ctx, authz.OwnerField, bson.M{})).
+		WithSkip(pagination.Skip()).
+		WithLimit(pagination.PageSize)
+
+	results := make(SearchResults, 0)
+	total, err := api.Dal.Search(ctx, dal.CollGifs, *searchArgs, &results)
+	if err != nil {
+		fmt.Println(err.Error())
+		httputil.WriteError(writer, ErrFindingGifs)
+		return
+	}
+	httputil.WritePaginationHeaders(writer, request.URL, pagination, total)
+
+	if err := httputil.WriteJSONWithETag(writer, request, results.ToDto()); err != nil {
+		fmt.Println(err.Error())
+		httputil.WriteError(writer, ErrEncodingGifs)
+	}
 }
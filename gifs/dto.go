@@ -1,12 +1,31 @@
 package gifs
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"gifmanager-backend/httputil"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	MaxGifNameLength = 100
+	MaxGifURLLength  = 2048
+)
 
 type GifRequest struct {
 	Name        string             ` json:"name"`
 	URL         string             ` json:"url"`
 	CategoryId  primitive.ObjectID ` json:"categoryId"`
 	IsFavourite bool               `json:"isFavourite"`
+	Tags        []string           `json:"tags"`
+}
+
+func (g GifRequest) Validate() httputil.ValidationErrors {
+	var errs httputil.ValidationErrors
+	errs.Length("name", g.Name, 1, MaxGifNameLength)
+	errs.URL("url", g.URL, MaxGifURLLength)
+	errs.ObjectID("categoryId", g.CategoryId)
+	validateTags(&errs, g.Tags)
+	return errs
 }
 
 func (g GifRequest) ToModel() Gif {
@@ -15,14 +34,87 @@ func (g GifRequest) ToModel() Gif {
 		URL:        g.URL,
 		IsFavorite: g.IsFavourite,
 		CategoryId: g.CategoryId,
+		Tags:       NormalizeTags(g.Tags),
+	}
+}
+
+// GifPatch is a JSON merge patch of a gif, only the members present in the patch are changed.
+// Null removes a gif from its category and resets isFavourite and tags, name and url can't be removed.
+// The tags of a patch replace all the tags of the gif.
+type GifPatch struct {
+	Name        httputil.Optional[string]             `json:"name"`
+	URL         httputil.Optional[string]             `json:"url"`
+	CategoryId  httputil.Optional[primitive.ObjectID] `json:"categoryId"`
+	IsFavourite httputil.Optional[bool]               `json:"isFavourite"`
+	Tags        httputil.Optional[[]string]           `json:"tags"`
+}
+
+func (p GifPatch) Validate() httputil.ValidationErrors {
+	var errs httputil.ValidationErrors
+	if p.Name.Set {
+		errs.Length("name", p.Name.Value, 1, MaxGifNameLength)
+	}
+	if p.URL.Set {
+		errs.URL("url", p.URL.Value, MaxGifURLLength)
+	}
+	if p.CategoryId.IsValue() {
+		errs.ObjectID("categoryId", p.CategoryId.Value)
+	}
+	if p.Tags.IsValue() {
+		validateTags(&errs, p.Tags.Value)
 	}
+	return errs
+}
+
+// ToUpdate translates the patch to an update that only touches the fields in the patch.
+func (p GifPatch) ToUpdate() bson.M {
+	update := bson.M{}
+	p.Name.AddToUpdate(update, "name")
+	p.URL.AddToUpdate(update, "url")
+	p.CategoryId.AddToUpdate(update, "categoryId")
+	p.IsFavourite.AddToUpdate(update, "isFavourite")
+	tags := p.Tags
+	if tags.IsValue() {
+		tags.Value = NormalizeTags(tags.Value)
+	}
+	tags.AddToUpdate(update, "tags")
+	return update
 }
 
 type GifDto struct {
-	ID         string `json:"id,omitempty"`
-	Name       string `json:"name"`
-	URL        string `json:"url"`
-	CategoryID string `json:"categoryId"`
+	ID          string   `json:"id,omitempty"`
+	Name        string   `json:"name"`
+	URL         string   `json:"url"`
+	CategoryID  string   `json:"categoryId"`
+	IsFavourite bool     `json:"isFavourite"`
+	Tags        []string `json:"tags"`
+	// Category is only set with ?include=category
+	Category *GifCategoryDto `json:"category,omitempty"`
+}
+
+// GifCategoryDto is the category embedded in a gif.
+type GifCategoryDto struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	GifsCount int    `json:"gifsCount"`
 }
 
 type GifDtos []GifDto
+
+// SharedGifDto is a gif of another user that was shared with one of the caller's groups.
+type SharedGifDto struct {
+	GifDto
+	OwnerID string `json:"ownerId"`
+}
+
+// SearchResultDto is a gif found by the search, the higher the score the more relevant the gif is.
+type SearchResultDto struct {
+	GifDto
+	Score float64 `json:"score"`
+}
+
+// TagCountDto is a tag of the tag cloud with the number of the user's gifs having it.
+type TagCountDto struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
@@ -1,14 +1,31 @@
 package gifs
 
-const (
-	ErrInsertingGifs           = "error encountered on inserting the gifs"
-	ErrUpdatingCategoriesCount = "error encountered on updating the categories count"
-	ErrEncodingGifs            = "error encountered on encoding gifs"
-	ErrDecodingGifFmt          = "error while decoding the request: %s"
-	ErrFindingGifs             = "error encountered while retrieving favorite gifs"
+import (
+	"gifmanager-backend/httputil"
+	"net/http"
+)
 
+const (
 	ErrInvalidIDFmt   = "invalid id: %s"
-	ErrDeletingGif    = "error encountered deleting the gif"
-	ErrUpdatingGif    = "error encountered on updating the gif"
 	ErrGifNotFoundFmt = "gif with id %s does not exist"
+
+	ErrInvalidSearchQueryFmt = "invalid %s, it should contain at least one word and be at most %d characters long"
+)
+
+const CodeGifNotFound = "gif_not_found"
+
+var (
+	ErrInsertingGifs           = httputil.NewError(http.StatusInternalServerError, httputil.CodeInternal, "error encountered on inserting the gifs")
+	ErrUpdatingCategoriesCount = httputil.NewError(http.StatusInternalServerError, httputil.CodeInternal, "error encountered on updating the categories count")
+	ErrEncodingGifs            = httputil.NewError(http.StatusInternalServerError, httputil.CodeInternal, "error encountered on encoding gifs")
+	ErrFindingGifs             = httputil.NewError(http.StatusInternalServerError, httputil.CodeInternal, "error encountered while retrieving favorite gifs")
+	ErrDeletingGif             = httputil.NewError(http.StatusInternalServerError, httputil.CodeInternal, "error encountered deleting the gif")
+	ErrUpdatingGif             = httputil.NewError(http.StatusInternalServerError, httputil.CodeInternal, "error encountered on updating the gif")
+	ErrFindingTags             = httputil.NewError(http.StatusInternalServerError, httputil.CodeInternal, "error encountered while retrieving the tags")
+
+	ErrGifNotFound = httputil.NewError(http.StatusNotFound, CodeGifNotFound, "gif does not exist")
+
+	ErrCategoryDoesNotExist = httputil.ErrValidationFailed.WithDetails(httputil.ValidationErrors{
+		{Field: "categoryId", Message: "does not exist"},
+	})
 )
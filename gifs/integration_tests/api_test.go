@@ -4,10 +4,13 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"gifmanager-backend/categories"
 	"gifmanager-backend/dal"
 	"gifmanager-backend/gifs"
+	"gifmanager-backend/groups"
 	"gifmanager-backend/httputil"
+	"gifmanager-backend/shares"
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 	testifyhttp "github.com/stretchr/testify/http"
@@ -17,6 +20,8 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 )
 
@@ -44,7 +49,7 @@ func TestCreateGifHandler_ExpectedStatusCreated(t *testing.T) {
 	// create the request body
 	requestBody := gifs.GifRequest{
 		Name:       t.Name(),
-		URL:        "gif-url",
+		URL:        "https://media.giphy.com/gif.gif",
 		CategoryId: categoryID,
 	}
 
@@ -212,7 +217,7 @@ func TestGetGifsHandler_FilterByFavourite_ExpectedOk(t *testing.T) {
 	require.Nil(t, errInsert)
 
 	// create the request
-	request := httptest.NewRequest(http.MethodGet, "/gifs?filter=isFavourite-$eq-true", nil)
+	request := httptest.NewRequest(http.MethodGet, "/gifs?filter=isFavourite=true", nil)
 	// set the userID in request's context
 	requestContext := context.WithValue(context.Background(), "userID", favouriteGif.UserId)
 	request = request.WithContext(requestContext)
@@ -314,3 +319,1198 @@ func TestDeleteGifHandler_StatusNoContent(t *testing.T) {
 	require.Nil(t, errFind)
 	assert.Equal(t, 0, dbCategory.GifCount)
 }
+
+func TestDeleteGifHandler_GifOfAnotherUser_ExpectedNotFound(t *testing.T) {
+	// 1.ARRANGE
+	ownerID := primitive.NewObjectID()
+	otherUserID := primitive.NewObjectID()
+
+	defer func() {
+		mongoDal.Delete(context.Background(), dal.CollGifs, bson.M{"userId": ownerID})
+	}()
+
+	ownersGif := gifs.Gif{
+		ID:     primitive.NewObjectID(),
+		Name:   t.Name(),
+		URL:    "gifUrl",
+		UserId: ownerID,
+	}
+	_, errInsertGif := mongoDal.Insert(context.Background(), dal.CollGifs, []any{ownersGif})
+	require.Nil(t, errInsertGif)
+
+	// the request is made by a different user than the owner of the gif
+	request := httptest.NewRequest(http.MethodDelete, "/gifs", nil)
+	request = request.WithContext(context.WithValue(context.Background(), "userID", otherUserID))
+	request = mux.SetURLVars(request, map[string]string{
+		"id": ownersGif.ID.Hex(),
+	})
+	responseRecorder := httptest.NewRecorder()
+	api := gifs.NewGifApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+	// 2.ACT
+	api.DeleteGifHandler(responseRecorder, request)
+
+	// 3.ASSERT
+	// 404 instead of 403, so the other user can't find out that the gif exists
+	assert.Equal(t, http.StatusNotFound, responseRecorder.Code)
+
+	var dbGif gifs.Gif
+	require.Nil(t, mongoDal.FindByID(context.Background(), dal.CollGifs, ownersGif.ID.Hex(), &dbGif))
+	assert.Equal(t, ownersGif, dbGif)
+}
+
+func TestUpdateGifHandler_GifOfAnotherUser_ExpectedNotFound(t *testing.T) {
+	// 1.ARRANGE
+	ownerID := primitive.NewObjectID()
+	otherUserID := primitive.NewObjectID()
+
+	defer func() {
+		mongoDal.Delete(context.Background(), dal.CollGifs, bson.M{"userId": ownerID})
+		mongoDal.Delete(context.Background(), dal.CollGifs, bson.M{"userId": otherUserID})
+	}()
+
+	ownersGif := gifs.Gif{
+		ID:     primitive.NewObjectID(),
+		Name:   t.Name(),
+		URL:    "gifUrl",
+		UserId: ownerID,
+	}
+	_, errInsertGif := mongoDal.Insert(context.Background(), dal.CollGifs, []any{ownersGif})
+	require.Nil(t, errInsertGif)
+
+	bts, _ := json.Marshal(gifs.GifRequest{Name: "overwritten", URL: "https://media.giphy.com/overwritten.gif", CategoryId: primitive.NewObjectID()})
+	request := httptest.NewRequest(http.MethodPut, "/gifs", bytes.NewReader(bts))
+	request = request.WithContext(context.WithValue(context.Background(), "userID", otherUserID))
+	request = mux.SetURLVars(request, map[string]string{
+		"id": ownersGif.ID.Hex(),
+	})
+	responseRecorder := httptest.NewRecorder()
+	api := gifs.NewGifApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+	// 2.ACT
+	api.UpdateGifHandler(responseRecorder, request)
+
+	// 3.ASSERT
+	assert.Equal(t, http.StatusNotFound, responseRecorder.Code)
+
+	var dbGif gifs.Gif
+	require.Nil(t, mongoDal.FindByID(context.Background(), dal.CollGifs, ownersGif.ID.Hex(), &dbGif))
+	assert.Equal(t, ownersGif, dbGif)
+}
+
+func TestGetGifsHandler_Paginated_ExpectedPageAndHeaders(t *testing.T) {
+	// 1.ARRANGE
+	userID := primitive.NewObjectID()
+
+	defer func() {
+		mongoDal.Delete(context.Background(), dal.CollGifs, bson.M{"userId": userID})
+	}()
+
+	// five gifs named e, d, c, b, a, so sorting by name reverses the insertion order
+	userGifs := make([]any, 0, 5)
+	for _, name := range []string{"e", "d", "c", "b", "a"} {
+		userGifs = append(userGifs, gifs.Gif{
+			ID:     primitive.NewObjectID(),
+			Name:   name,
+			URL:    "gifUrl",
+			UserId: userID,
+		})
+	}
+	_, errInsert := mongoDal.Insert(context.Background(), dal.CollGifs, userGifs)
+	require.Nil(t, errInsert)
+
+	request := httptest.NewRequest(http.MethodGet, "/gifs?sort=name&page=2&pageSize=2", nil)
+	request = request.WithContext(context.WithValue(context.Background(), "userID", userID))
+	responseRecorder := httptest.NewRecorder()
+	api := gifs.NewGifApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+	// 2.ACT
+	api.GetGifsHandler(responseRecorder, request)
+
+	// 3.ASSERT
+	require.Equal(t, http.StatusOK, responseRecorder.Code)
+
+	var gifDTOs gifs.GifDtos
+	require.Nil(t, json.NewDecoder(responseRecorder.Body).Decode(&gifDTOs))
+	require.Len(t, gifDTOs, 2)
+	assert.Equal(t, "c", gifDTOs[0].Name)
+	assert.Equal(t, "d", gifDTOs[1].Name)
+
+	assert.Equal(t, "5", responseRecorder.Header().Get("X-Total-Count"))
+	assert.Equal(t,
+		`</gifs?page=3&pageSize=2&sort=name>; rel="next", </gifs?page=1&pageSize=2&sort=name>; rel="prev"`,
+		responseRecorder.Header().Get("Link"))
+}
+
+func TestGetGifsHandler_InvalidPaginationOrSort_ExpectedBadRequest(t *testing.T) {
+	testCases := []string{
+		"/gifs?page=0",
+		"/gifs?pageSize=abc",
+		"/gifs?pageSize=100000",
+		"/gifs?sort=password",
+	}
+
+	for _, requestURL := range testCases {
+		t.Run(requestURL, func(t *testing.T) {
+			// 1.ARRANGE
+			request := httptest.NewRequest(http.MethodGet, requestURL, nil)
+			request = request.WithContext(context.WithValue(context.Background(), "userID", primitive.NewObjectID()))
+			responseRecorder := httptest.NewRecorder()
+			api := gifs.NewGifApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+			// 2.ACT
+			api.GetGifsHandler(responseRecorder, request)
+
+			// 3.ASSERT
+			assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+		})
+	}
+}
+
+func TestGetGifsHandler_FilterByCategoryId_ExpectedGifsOfCategory(t *testing.T) {
+	// 1.ARRANGE
+	userID := primitive.NewObjectID()
+	categoryID := primitive.NewObjectID()
+
+	defer func() {
+		mongoDal.Delete(context.Background(), dal.CollGifs, bson.M{"userId": userID})
+	}()
+
+	gifInCategory := gifs.Gif{ID: primitive.NewObjectID(), Name: "in category", URL: "gifUrl", UserId: userID, CategoryId: categoryID}
+	gifNotInCategory := gifs.Gif{ID: primitive.NewObjectID(), Name: "not in category", URL: "gifUrl", UserId: userID, CategoryId: primitive.NewObjectID()}
+	_, errInsert := mongoDal.Insert(context.Background(), dal.CollGifs, []any{gifInCategory, gifNotInCategory})
+	require.Nil(t, errInsert)
+
+	// the id is only found when the filter converts it to an ObjectID instead of comparing it as a string
+	request := httptest.NewRequest(http.MethodGet, "/gifs?filter=categoryId="+categoryID.Hex(), nil)
+	request = request.WithContext(context.WithValue(context.Background(), "userID", userID))
+	responseRecorder := httptest.NewRecorder()
+	api := gifs.NewGifApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+	// 2.ACT
+	api.GetGifsHandler(responseRecorder, request)
+
+	// 3.ASSERT
+	require.Equal(t, http.StatusOK, responseRecorder.Code)
+
+	var gifDTOs gifs.GifDtos
+	require.Nil(t, json.NewDecoder(responseRecorder.Body).Decode(&gifDTOs))
+	require.Len(t, gifDTOs, 1)
+	assert.Equal(t, gifInCategory.ID.Hex(), gifDTOs[0].ID)
+}
+
+func TestGetGifsHandler_FilterByUndeclaredFieldOrOperator_ExpectedBadRequest(t *testing.T) {
+	testCases := []string{
+		`userId=` + primitive.NewObjectID().Hex(),
+		`$where="sleep(1000)"`,
+		`isFavourite~true`,
+	}
+
+	for _, filter := range testCases {
+		t.Run(filter, func(t *testing.T) {
+			// 1.ARRANGE
+			request := httptest.NewRequest(http.MethodGet, "/gifs?filter="+url.QueryEscape(filter), nil)
+			request = request.WithContext(context.WithValue(context.Background(), "userID", primitive.NewObjectID()))
+			responseRecorder := httptest.NewRecorder()
+			api := gifs.NewGifApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+			// 2.ACT
+			api.GetGifsHandler(responseRecorder, request)
+
+			// 3.ASSERT
+			assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+		})
+	}
+}
+
+func TestCreateGifHandler_InvalidRequest_ExpectedUnprocessableEntity(t *testing.T) {
+	// 1.ARRANGE
+	userID := primitive.NewObjectID()
+
+	defer func() {
+		mongoDal.Delete(context.Background(), dal.CollGifs, bson.M{"userId": userID})
+	}()
+
+	// empty name, a url that isn't http and no category
+	bts, _ := json.Marshal(gifs.GifRequest{Name: "", URL: "not a url"})
+	request := httptest.NewRequest(http.MethodPost, "/gifs", bytes.NewReader(bts))
+	request = request.WithContext(context.WithValue(context.Background(), "userID", userID))
+	responseRecorder := httptest.NewRecorder()
+	api := gifs.NewGifApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+	// 2.ACT
+	api.CreateGifHandler(responseRecorder, request)
+
+	// 3.ASSERT
+	require.Equal(t, http.StatusUnprocessableEntity, responseRecorder.Code)
+
+	var errorResponse struct {
+		Code    string                    `json:"code"`
+		Details httputil.ValidationErrors `json:"details"`
+	}
+	require.Nil(t, json.NewDecoder(responseRecorder.Body).Decode(&errorResponse))
+	assert.Equal(t, httputil.CodeValidationFailed, errorResponse.Code)
+	assert.Equal(t, httputil.ValidationErrors{
+		{Field: "name", Message: "is required"},
+		{Field: "url", Message: "must be an http or https URL"},
+		{Field: "categoryId", Message: "is required"},
+	}, errorResponse.Details)
+
+	count, errCount := mongoDal.Count(context.Background(), dal.CollGifs, bson.M{"userId": userID})
+	require.Nil(t, errCount)
+	assert.Zero(t, count)
+}
+
+func TestCreateGifHandler_CategoryOfAnotherUser_ExpectedUnprocessableEntity(t *testing.T) {
+	// 1.ARRANGE
+	userID := primitive.NewObjectID()
+	ownerID := primitive.NewObjectID()
+	category := categories.Category{
+		ID:     primitive.NewObjectID(),
+		Name:   t.Name(),
+		UserId: ownerID,
+	}
+	_, errInsertCategory := mongoDal.Insert(context.Background(), dal.CollCategories, []any{category})
+	require.Nil(t, errInsertCategory)
+
+	defer func() {
+		mongoDal.Delete(context.Background(), dal.CollGifs, bson.M{"userId": userID})
+		mongoDal.Delete(context.Background(), dal.CollCategories, bson.M{"userId": ownerID})
+	}()
+
+	bts, _ := json.Marshal(gifs.GifRequest{Name: t.Name(), URL: "https://media.giphy.com/gif.gif", CategoryId: category.ID})
+	request := httptest.NewRequest(http.MethodPost, "/gifs", bytes.NewReader(bts))
+	request = request.WithContext(context.WithValue(context.Background(), "userID", userID))
+	responseRecorder := httptest.NewRecorder()
+	api := gifs.NewGifApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+	// 2.ACT
+	api.CreateGifHandler(responseRecorder, request)
+
+	// 3.ASSERT
+	assert.Equal(t, http.StatusUnprocessableEntity, responseRecorder.Code)
+
+	// neither the gif nor the counter of the other user's category were written
+	count, errCount := mongoDal.Count(context.Background(), dal.CollGifs, bson.M{"userId": userID})
+	require.Nil(t, errCount)
+	assert.Zero(t, count)
+
+	var dbCategory categories.Category
+	require.Nil(t, mongoDal.FindByID(context.Background(), dal.CollCategories, category.ID.Hex(), &dbCategory))
+	assert.Zero(t, dbCategory.GifCount)
+}
+
+func TestUpdateGifHandler_MovedToAnotherCategory_ExpectedCountersAdjusted(t *testing.T) {
+	// 1.ARRANGE
+	userID := primitive.NewObjectID()
+	from := categories.Category{ID: primitive.NewObjectID(), Name: "from", UserId: userID, GifCount: 1}
+	to := categories.Category{ID: primitive.NewObjectID(), Name: "to", UserId: userID}
+	gif := gifs.Gif{ID: primitive.NewObjectID(), Name: t.Name(), URL: "https://media.giphy.com/gif.gif", UserId: userID, CategoryId: from.ID}
+	_, errInsertCategories := mongoDal.Insert(context.Background(), dal.CollCategories, []any{from, to})
+	require.Nil(t, errInsertCategories)
+	_, errInsertGif := mongoDal.Insert(context.Background(), dal.CollGifs, []any{gif})
+	require.Nil(t, errInsertGif)
+
+	defer func() {
+		mongoDal.Delete(context.Background(), dal.CollGifs, bson.M{"userId": userID})
+		mongoDal.Delete(context.Background(), dal.CollCategories, bson.M{"userId": userID})
+	}()
+
+	bts, _ := json.Marshal(gifs.GifRequest{Name: gif.Name, URL: gif.URL, CategoryId: to.ID})
+	request := httptest.NewRequest(http.MethodPut, "/gifs", bytes.NewReader(bts))
+	request = request.WithContext(context.WithValue(context.Background(), "userID", userID))
+	request = mux.SetURLVars(request, map[string]string{
+		"id": gif.ID.Hex(),
+	})
+	responseRecorder := httptest.NewRecorder()
+	api := gifs.NewGifApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+	// 2.ACT
+	api.UpdateGifHandler(responseRecorder, request)
+
+	// 3.ASSERT
+	assert.Equal(t, http.StatusNoContent, responseRecorder.Code)
+
+	var dbGif gifs.Gif
+	require.Nil(t, mongoDal.FindByID(context.Background(), dal.CollGifs, gif.ID.Hex(), &dbGif))
+	assert.Equal(t, to.ID, dbGif.CategoryId)
+
+	var dbCategory categories.Category
+	require.Nil(t, mongoDal.FindByID(context.Background(), dal.CollCategories, from.ID.Hex(), &dbCategory))
+	assert.Equal(t, 0, dbCategory.GifCount)
+	require.Nil(t, mongoDal.FindByID(context.Background(), dal.CollCategories, to.ID.Hex(), &dbCategory))
+	assert.Equal(t, 1, dbCategory.GifCount)
+}
+
+func newPatchRequest(userID primitive.ObjectID, gifID primitive.ObjectID, body string) *http.Request {
+	request := httptest.NewRequest(http.MethodPatch, "/gifs", bytes.NewReader([]byte(body)))
+	request.Header.Set("Content-Type", httputil.MergePatchContentType)
+	request = request.WithContext(context.WithValue(context.Background(), "userID", userID))
+	return mux.SetURLVars(request, map[string]string{
+		"id": gifID.Hex(),
+	})
+}
+
+func TestPatchGifHandler_OnlyFavourite_ExpectedOtherFieldsKept(t *testing.T) {
+	// 1.ARRANGE
+	userID := primitive.NewObjectID()
+	gif := gifs.Gif{ID: primitive.NewObjectID(), Name: t.Name(), URL: "https://media.giphy.com/gif.gif", UserId: userID, CategoryId: primitive.NewObjectID()}
+	_, errInsertGif := mongoDal.Insert(context.Background(), dal.CollGifs, []any{gif})
+	require.Nil(t, errInsertGif)
+
+	defer func() {
+		mongoDal.Delete(context.Background(), dal.CollGifs, bson.M{"userId": userID})
+	}()
+
+	request := newPatchRequest(userID, gif.ID, `{"isFavourite": true}`)
+	responseRecorder := httptest.NewRecorder()
+	api := gifs.NewGifApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+	// 2.ACT
+	api.PatchGifHandler(responseRecorder, request)
+
+	// 3.ASSERT
+	require.Equal(t, http.StatusOK, responseRecorder.Code)
+
+	var gifDTO gifs.GifDto
+	require.Nil(t, json.NewDecoder(responseRecorder.Body).Decode(&gifDTO))
+	gif.IsFavorite = true
+	gif.Version = 1
+	assert.Equal(t, gif.ToDto(), gifDTO)
+	assert.Equal(t, `"1"`, responseRecorder.Header().Get("ETag"))
+
+	var dbGif gifs.Gif
+	require.Nil(t, mongoDal.FindByID(context.Background(), dal.CollGifs, gif.ID.Hex(), &dbGif))
+	assert.Equal(t, gif, dbGif)
+}
+
+func TestPatchGifHandler_NullCategory_ExpectedGifRemovedFromCategory(t *testing.T) {
+	// 1.ARRANGE
+	userID := primitive.NewObjectID()
+	category := categories.Category{ID: primitive.NewObjectID(), Name: t.Name(), UserId: userID, GifCount: 1}
+	gif := gifs.Gif{ID: primitive.NewObjectID(), Name: t.Name(), URL: "https://media.giphy.com/gif.gif", UserId: userID, CategoryId: category.ID, IsFavorite: true}
+	_, errInsertCategory := mongoDal.Insert(context.Background(), dal.CollCategories, []any{category})
+	require.Nil(t, errInsertCategory)
+	_, errInsertGif := mongoDal.Insert(context.Background(), dal.CollGifs, []any{gif})
+	require.Nil(t, errInsertGif)
+
+	defer func() {
+		mongoDal.Delete(context.Background(), dal.CollGifs, bson.M{"userId": userID})
+		mongoDal.Delete(context.Background(), dal.CollCategories, bson.M{"userId": userID})
+	}()
+
+	request := newPatchRequest(userID, gif.ID, `{"categoryId": null, "isFavourite": null, "name": "renamed"}`)
+	responseRecorder := httptest.NewRecorder()
+	api := gifs.NewGifApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+	// 2.ACT
+	api.PatchGifHandler(responseRecorder, request)
+
+	// 3.ASSERT
+	require.Equal(t, http.StatusOK, responseRecorder.Code)
+
+	var dbGif gifs.Gif
+	require.Nil(t, mongoDal.FindByID(context.Background(), dal.CollGifs, gif.ID.Hex(), &dbGif))
+	assert.Equal(t, "renamed", dbGif.Name)
+	assert.Equal(t, gif.URL, dbGif.URL)
+	assert.True(t, dbGif.CategoryId.IsZero())
+	assert.False(t, dbGif.IsFavorite)
+
+	var dbCategory categories.Category
+	require.Nil(t, mongoDal.FindByID(context.Background(), dal.CollCategories, category.ID.Hex(), &dbCategory))
+	assert.Equal(t, 0, dbCategory.GifCount)
+}
+
+func TestPatchGifHandler_InvalidPatch_ExpectedGifUnchanged(t *testing.T) {
+	testCases := []struct {
+		body           string
+		expectedStatus int
+	}{
+		{`{"name": null}`, http.StatusUnprocessableEntity},
+		{`{"url": "not a url"}`, http.StatusUnprocessableEntity},
+		{`{"userId": "` + primitive.NewObjectID().Hex() + `"}`, http.StatusBadRequest},
+		{`{"categoryId": "` + primitive.NewObjectID().Hex() + `"}`, http.StatusUnprocessableEntity},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.body, func(t *testing.T) {
+			// 1.ARRANGE
+			userID := primitive.NewObjectID()
+			gif := gifs.Gif{ID: primitive.NewObjectID(), Name: t.Name(), URL: "https://media.giphy.com/gif.gif", UserId: userID}
+			_, errInsertGif := mongoDal.Insert(context.Background(), dal.CollGifs, []any{gif})
+			require.Nil(t, errInsertGif)
+
+			defer func() {
+				mongoDal.Delete(context.Background(), dal.CollGifs, bson.M{"userId": userID})
+			}()
+
+			request := newPatchRequest(userID, gif.ID, testCase.body)
+			responseRecorder := httptest.NewRecorder()
+			api := gifs.NewGifApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+			// 2.ACT
+			api.PatchGifHandler(responseRecorder, request)
+
+			// 3.ASSERT
+			assert.Equal(t, testCase.expectedStatus, responseRecorder.Code)
+
+			var dbGif gifs.Gif
+			require.Nil(t, mongoDal.FindByID(context.Background(), dal.CollGifs, gif.ID.Hex(), &dbGif))
+			assert.Equal(t, gif, dbGif)
+		})
+	}
+}
+
+func TestUpdateGifHandler_StaleIfMatch_ExpectedPreconditionFailed(t *testing.T) {
+	// 1.ARRANGE
+	userID := primitive.NewObjectID()
+	gif := gifs.Gif{ID: primitive.NewObjectID(), Name: t.Name(), URL: "https://media.giphy.com/gif.gif", UserId: userID, Version: 2}
+	_, errInsertGif := mongoDal.Insert(context.Background(), dal.CollGifs, []any{gif})
+	require.Nil(t, errInsertGif)
+
+	defer func() {
+		mongoDal.Delete(context.Background(), dal.CollGifs, bson.M{"userId": userID})
+	}()
+
+	// the client read version 1, another tab has written version 2 since
+	request := newPatchRequest(userID, gif.ID, `{"name": "overwritten"}`)
+	request.Header.Set("If-Match", httputil.VersionETag(1))
+	responseRecorder := httptest.NewRecorder()
+	api := gifs.NewGifApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+	// 2.ACT
+	api.PatchGifHandler(responseRecorder, request)
+
+	// 3.ASSERT
+	assert.Equal(t, http.StatusPreconditionFailed, responseRecorder.Code)
+
+	var dbGif gifs.Gif
+	require.Nil(t, mongoDal.FindByID(context.Background(), dal.CollGifs, gif.ID.Hex(), &dbGif))
+	assert.Equal(t, gif, dbGif)
+}
+
+func TestUpdateGifHandler_MatchingIfMatch_ExpectedNewETag(t *testing.T) {
+	// 1.ARRANGE
+	userID := primitive.NewObjectID()
+	category := categories.Category{ID: primitive.NewObjectID(), Name: t.Name(), UserId: userID, GifCount: 1}
+	gif := gifs.Gif{ID: primitive.NewObjectID(), Name: t.Name(), URL: "https://media.giphy.com/gif.gif", UserId: userID, CategoryId: category.ID, Version: 2}
+	_, errInsertCategory := mongoDal.Insert(context.Background(), dal.CollCategories, []any{category})
+	require.Nil(t, errInsertCategory)
+	_, errInsertGif := mongoDal.Insert(context.Background(), dal.CollGifs, []any{gif})
+	require.Nil(t, errInsertGif)
+
+	defer func() {
+		mongoDal.Delete(context.Background(), dal.CollGifs, bson.M{"userId": userID})
+		mongoDal.Delete(context.Background(), dal.CollCategories, bson.M{"userId": userID})
+	}()
+
+	bts, _ := json.Marshal(gifs.GifRequest{Name: "renamed", URL: gif.URL, CategoryId: category.ID})
+	request := httptest.NewRequest(http.MethodPut, "/gifs", bytes.NewReader(bts))
+	request.Header.Set("If-Match", httputil.VersionETag(2))
+	request = request.WithContext(context.WithValue(context.Background(), "userID", userID))
+	request = mux.SetURLVars(request, map[string]string{
+		"id": gif.ID.Hex(),
+	})
+	responseRecorder := httptest.NewRecorder()
+	api := gifs.NewGifApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+	// 2.ACT
+	api.UpdateGifHandler(responseRecorder, request)
+
+	// 3.ASSERT
+	require.Equal(t, http.StatusNoContent, responseRecorder.Code)
+	assert.Equal(t, httputil.VersionETag(3), responseRecorder.Header().Get("ETag"))
+
+	var dbGif gifs.Gif
+	require.Nil(t, mongoDal.FindByID(context.Background(), dal.CollGifs, gif.ID.Hex(), &dbGif))
+	assert.Equal(t, "renamed", dbGif.Name)
+	assert.Equal(t, 3, dbGif.Version)
+}
+
+func TestDeleteGifHandler_StaleIfMatch_ExpectedPreconditionFailed(t *testing.T) {
+	// 1.ARRANGE
+	userID := primitive.NewObjectID()
+	gif := gifs.Gif{ID: primitive.NewObjectID(), Name: t.Name(), URL: "https://media.giphy.com/gif.gif", UserId: userID, Version: 2}
+	_, errInsertGif := mongoDal.Insert(context.Background(), dal.CollGifs, []any{gif})
+	require.Nil(t, errInsertGif)
+
+	defer func() {
+		mongoDal.Delete(context.Background(), dal.CollGifs, bson.M{"userId": userID})
+	}()
+
+	request := httptest.NewRequest(http.MethodDelete, "/gifs", nil)
+	request.Header.Set("If-Match", httputil.VersionETag(1))
+	request = request.WithContext(context.WithValue(context.Background(), "userID", userID))
+	request = mux.SetURLVars(request, map[string]string{
+		"id": gif.ID.Hex(),
+	})
+	responseRecorder := httptest.NewRecorder()
+	api := gifs.NewGifApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+	// 2.ACT
+	api.DeleteGifHandler(responseRecorder, request)
+
+	// 3.ASSERT
+	assert.Equal(t, http.StatusPreconditionFailed, responseRecorder.Code)
+
+	var dbGif gifs.Gif
+	require.Nil(t, mongoDal.FindByID(context.Background(), dal.CollGifs, gif.ID.Hex(), &dbGif))
+}
+
+func TestGetGifsHandler_IfNoneMatch_ExpectedNotModified(t *testing.T) {
+	// 1.ARRANGE
+	userID := primitive.NewObjectID()
+	gif := gifs.Gif{ID: primitive.NewObjectID(), Name: t.Name(), URL: "https://media.giphy.com/gif.gif", UserId: userID}
+	_, errInsertGif := mongoDal.Insert(context.Background(), dal.CollGifs, []any{gif})
+	require.Nil(t, errInsertGif)
+
+	defer func() {
+		mongoDal.Delete(context.Background(), dal.CollGifs, bson.M{"userId": userID})
+	}()
+
+	api := gifs.NewGifApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+	newRequest := func() *http.Request {
+		request := httptest.NewRequest(http.MethodGet, "/gifs", nil)
+		return request.WithContext(context.WithValue(context.Background(), "userID", userID))
+	}
+	first := httptest.NewRecorder()
+	api.GetGifsHandler(first, newRequest())
+	etag := first.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	request := newRequest()
+	request.Header.Set("If-None-Match", etag)
+	responseRecorder := httptest.NewRecorder()
+
+	// 2.ACT
+	api.GetGifsHandler(responseRecorder, request)
+
+	// 3.ASSERT
+	assert.Equal(t, http.StatusNotModified, responseRecorder.Code)
+	assert.Zero(t, responseRecorder.Body.Len())
+}
+
+func newGetGifRequest(userID primitive.ObjectID, gifID string, query string) *http.Request {
+	request := httptest.NewRequest(http.MethodGet, "/gifs/"+gifID+"?"+query, nil)
+	request = request.WithContext(context.WithValue(context.Background(), "userID", userID))
+	return mux.SetURLVars(request, map[string]string{
+		"id": gifID,
+	})
+}
+
+func TestGetGifHandler_ExpectedGifWithETag(t *testing.T) {
+	// 1.ARRANGE
+	userID := primitive.NewObjectID()
+	gif := gifs.Gif{ID: primitive.NewObjectID(), Name: t.Name(), URL: "https://media.giphy.com/gif.gif", UserId: userID, IsFavorite: true, Version: 4}
+	_, errInsertGif := mongoDal.Insert(context.Background(), dal.CollGifs, []any{gif})
+	require.Nil(t, errInsertGif)
+
+	defer func() {
+		mongoDal.Delete(context.Background(), dal.CollGifs, bson.M{"userId": userID})
+	}()
+
+	responseRecorder := httptest.NewRecorder()
+	api := gifs.NewGifApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+	// 2.ACT
+	api.GetGifHandler(responseRecorder, newGetGifRequest(userID, gif.ID.Hex(), ""))
+
+	// 3.ASSERT
+	require.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.Equal(t, httputil.VersionETag(4), responseRecorder.Header().Get("ETag"))
+
+	var gifDTO gifs.GifDto
+	require.Nil(t, json.NewDecoder(responseRecorder.Body).Decode(&gifDTO))
+	assert.Equal(t, gif.ToDto(), gifDTO)
+	assert.True(t, gifDTO.IsFavourite)
+	assert.Nil(t, gifDTO.Category)
+}
+
+func TestGetGifHandler_IncludeCategory_ExpectedCategoryEmbedded(t *testing.T) {
+	// 1.ARRANGE
+	userID := primitive.NewObjectID()
+	category := categories.Category{ID: primitive.NewObjectID(), Name: t.Name(), UserId: userID, GifCount: 1}
+	gif := gifs.Gif{ID: primitive.NewObjectID(), Name: t.Name(), URL: "https://media.giphy.com/gif.gif", UserId: userID, CategoryId: category.ID}
+	_, errInsertCategory := mongoDal.Insert(context.Background(), dal.CollCategories, []any{category})
+	require.Nil(t, errInsertCategory)
+	_, errInsertGif := mongoDal.Insert(context.Background(), dal.CollGifs, []any{gif})
+	require.Nil(t, errInsertGif)
+
+	defer func() {
+		mongoDal.Delete(context.Background(), dal.CollGifs, bson.M{"userId": userID})
+		mongoDal.Delete(context.Background(), dal.CollCategories, bson.M{"userId": userID})
+	}()
+
+	responseRecorder := httptest.NewRecorder()
+	api := gifs.NewGifApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+	// 2.ACT
+	api.GetGifHandler(responseRecorder, newGetGifRequest(userID, gif.ID.Hex(), "include=category"))
+
+	// 3.ASSERT
+	require.Equal(t, http.StatusOK, responseRecorder.Code)
+
+	var gifDTO gifs.GifDto
+	require.Nil(t, json.NewDecoder(responseRecorder.Body).Decode(&gifDTO))
+	assert.Equal(t, &gifs.GifCategoryDto{ID: category.ID.Hex(), Name: category.Name, GifsCount: 1}, gifDTO.Category)
+}
+
+func TestGetGifHandler_InvalidRequests(t *testing.T) {
+	// 1.ARRANGE
+	ownerID := primitive.NewObjectID()
+	gif := gifs.Gif{ID: primitive.NewObjectID(), Name: t.Name(), URL: "https://media.giphy.com/gif.gif", UserId: ownerID}
+	_, errInsertGif := mongoDal.Insert(context.Background(), dal.CollGifs, []any{gif})
+	require.Nil(t, errInsertGif)
+
+	defer func() {
+		mongoDal.Delete(context.Background(), dal.CollGifs, bson.M{"userId": ownerID})
+	}()
+
+	testCases := []struct {
+		name           string
+		request        *http.Request
+		expectedStatus int
+	}{
+		{"gif of another user", newGetGifRequest(primitive.NewObjectID(), gif.ID.Hex(), ""), http.StatusNotFound},
+		{"missing gif", newGetGifRequest(ownerID, primitive.NewObjectID().Hex(), ""), http.StatusNotFound},
+		{"invalid id", newGetGifRequest(ownerID, "abc", ""), http.StatusBadRequest},
+		{"unknown include", newGetGifRequest(ownerID, gif.ID.Hex(), "include=user"), http.StatusBadRequest},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			responseRecorder := httptest.NewRecorder()
+			api := gifs.NewGifApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+			// 2.ACT
+			api.GetGifHandler(responseRecorder, testCase.request)
+
+			// 3.ASSERT
+			assert.Equal(t, testCase.expectedStatus, responseRecorder.Code)
+		})
+	}
+}
+
+// sharedGifs is an owner who shared a category and one gif without a category with a group that has one member
+type sharedGifs struct {
+	ownerID      primitive.ObjectID
+	memberID     primitive.ObjectID
+	category     categories.Category
+	inCategory   gifs.Gif
+	sharedAlone  gifs.Gif
+	notShared    gifs.Gif
+	groupID      primitive.ObjectID
+	gifShareID   primitive.ObjectID
+	categoryGifs int
+}
+
+func insertSharedGifs(t *testing.T) sharedGifs {
+	fixture := sharedGifs{ownerID: primitive.NewObjectID(), memberID: primitive.NewObjectID(), groupID: primitive.NewObjectID(), gifShareID: primitive.NewObjectID()}
+	fixture.category = categories.Category{ID: primitive.NewObjectID(), Name: t.Name(), UserId: fixture.ownerID, GifCount: 1, Version: 1}
+	fixture.inCategory = gifs.Gif{ID: primitive.NewObjectID(), Name: "in category", URL: "https://media.giphy.com/1.gif", UserId: fixture.ownerID, CategoryId: fixture.category.ID, Version: 1}
+	fixture.sharedAlone = gifs.Gif{ID: primitive.NewObjectID(), Name: "shared alone", URL: "https://media.giphy.com/2.gif", UserId: fixture.ownerID, Version: 1}
+	fixture.notShared = gifs.Gif{ID: primitive.NewObjectID(), Name: "not shared", URL: "https://media.giphy.com/3.gif", UserId: fixture.ownerID, Version: 1}
+
+	ctx := context.Background()
+	_, err := mongoDal.Insert(ctx, dal.CollCategories, []any{fixture.category})
+	require.Nil(t, err)
+	_, err = mongoDal.Insert(ctx, dal.CollGifs, []any{fixture.inCategory, fixture.sharedAlone, fixture.notShared})
+	require.Nil(t, err)
+	_, err = mongoDal.Insert(ctx, dal.CollGroups, []any{groups.Group{ID: fixture.groupID, Name: t.Name(), UserId: fixture.ownerID, Members: []groups.Member{{UserId: fixture.memberID, Role: groups.RoleMember}}}})
+	require.Nil(t, err)
+	_, err = mongoDal.Insert(ctx, dal.CollShares, []any{
+		shares.Share{ID: primitive.NewObjectID(), UserId: fixture.ownerID, GroupId: fixture.groupID, CategoryId: fixture.category.ID},
+		shares.Share{ID: fixture.gifShareID, UserId: fixture.ownerID, GroupId: fixture.groupID, GifId: fixture.sharedAlone.ID},
+	})
+	require.Nil(t, err)
+
+	t.Cleanup(func() {
+		mongoDal.Delete(ctx, dal.CollCategories, bson.M{"userId": fixture.ownerID})
+		mongoDal.Delete(ctx, dal.CollGifs, bson.M{"userId": fixture.ownerID})
+		mongoDal.Delete(ctx, dal.CollGroups, bson.M{"_id": fixture.groupID})
+		mongoDal.Delete(ctx, dal.CollShares, bson.M{"userId": fixture.ownerID})
+	})
+	return fixture
+}
+
+func newFeedRequest(userID primitive.ObjectID, query string) *http.Request {
+	request := httptest.NewRequest(http.MethodGet, "/feed?"+query, nil)
+	return request.WithContext(context.WithValue(context.Background(), "userID", userID))
+}
+
+func TestFeedHandler_Member_ExpectedSharedGifsNewestFirst(t *testing.T) {
+	// 1.ARRANGE
+	fixture := insertSharedGifs(t)
+	responseRecorder := httptest.NewRecorder()
+	api := gifs.NewGifApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+	// 2.ACT
+	api.FeedHandler(responseRecorder, newFeedRequest(fixture.memberID, ""))
+
+	// 3.ASSERT
+	require.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.Equal(t, "2", responseRecorder.Header().Get("X-Total-Count"))
+
+	var feed []gifs.SharedGifDto
+	require.Nil(t, json.Unmarshal(responseRecorder.Body.Bytes(), &feed))
+	require.Len(t, feed, 2)
+	assert.Equal(t, fixture.sharedAlone.ID.Hex(), feed[0].ID)
+	assert.Equal(t, fixture.inCategory.ID.Hex(), feed[1].ID)
+	assert.Equal(t, fixture.ownerID.Hex(), feed[0].OwnerID)
+}
+
+func TestFeedHandler_OwnerAndOutsider_ExpectedEmptyFeed(t *testing.T) {
+	fixture := insertSharedGifs(t)
+
+	// the owner's own gifs are not in the owner's feed, and a user outside of the group sees nothing
+	for name, userID := range map[string]primitive.ObjectID{"owner": fixture.ownerID, "outsider": primitive.NewObjectID()} {
+		t.Run(name, func(t *testing.T) {
+			// 1.ARRANGE
+			responseRecorder := httptest.NewRecorder()
+			api := gifs.NewGifApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+			// 2.ACT
+			api.FeedHandler(responseRecorder, newFeedRequest(userID, ""))
+
+			// 3.ASSERT
+			require.Equal(t, http.StatusOK, responseRecorder.Code)
+			assert.Equal(t, "0", responseRecorder.Header().Get("X-Total-Count"))
+			assert.JSONEq(t, "[]", responseRecorder.Body.String())
+		})
+	}
+}
+
+func TestFeedHandler_Paginated_ExpectedPageAndHeaders(t *testing.T) {
+	// 1.ARRANGE
+	fixture := insertSharedGifs(t)
+	responseRecorder := httptest.NewRecorder()
+	api := gifs.NewGifApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+	// 2.ACT
+	api.FeedHandler(responseRecorder, newFeedRequest(fixture.memberID, "page=2&pageSize=1"))
+
+	// 3.ASSERT
+	require.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.Equal(t, "2", responseRecorder.Header().Get("X-Total-Count"))
+	assert.Contains(t, responseRecorder.Header().Get("Link"), `rel="prev"`)
+
+	var feed []gifs.SharedGifDto
+	require.Nil(t, json.Unmarshal(responseRecorder.Body.Bytes(), &feed))
+	require.Len(t, feed, 1)
+	assert.Equal(t, fixture.inCategory.ID.Hex(), feed[0].ID)
+}
+
+func TestGetGifHandler_SharedGif(t *testing.T) {
+	fixture := insertSharedGifs(t)
+	api := gifs.NewGifApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+	t.Run("member reads a gif of a shared category with its category", func(t *testing.T) {
+		// 1.ARRANGE
+		responseRecorder := httptest.NewRecorder()
+
+		// 2.ACT
+		api.GetGifHandler(responseRecorder, newGetGifRequest(fixture.memberID, fixture.inCategory.ID.Hex(), "include=category"))
+
+		// 3.ASSERT
+		require.Equal(t, http.StatusOK, responseRecorder.Code)
+		var gifDto gifs.GifDto
+		require.Nil(t, json.Unmarshal(responseRecorder.Body.Bytes(), &gifDto))
+		assert.Equal(t, fixture.inCategory.Name, gifDto.Name)
+		require.NotNil(t, gifDto.Category)
+		assert.Equal(t, fixture.category.Name, gifDto.Category.Name)
+	})
+
+	t.Run("member can't read a gif that isn't shared", func(t *testing.T) {
+		// 1.ARRANGE
+		responseRecorder := httptest.NewRecorder()
+
+		// 2.ACT
+		api.GetGifHandler(responseRecorder, newGetGifRequest(fixture.memberID, fixture.notShared.ID.Hex(), ""))
+
+		// 3.ASSERT
+		assert.Equal(t, http.StatusNotFound, responseRecorder.Code)
+	})
+
+	t.Run("outsider can't read a shared gif", func(t *testing.T) {
+		// 1.ARRANGE
+		responseRecorder := httptest.NewRecorder()
+
+		// 2.ACT
+		api.GetGifHandler(responseRecorder, newGetGifRequest(primitive.NewObjectID(), fixture.sharedAlone.ID.Hex(), ""))
+
+		// 3.ASSERT
+		assert.Equal(t, http.StatusNotFound, responseRecorder.Code)
+	})
+}
+
+func TestSharedGif_MemberChangesIt_ExpectedNotFoundAndGifUnchanged(t *testing.T) {
+	// 1.ARRANGE
+	fixture := insertSharedGifs(t)
+	api := gifs.NewGifApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+	patchRecorder := httptest.NewRecorder()
+	deleteRecorder := httptest.NewRecorder()
+	deleteRequest := newPatchRequest(fixture.memberID, fixture.sharedAlone.ID, "")
+
+	// 2.ACT
+	api.PatchGifHandler(patchRecorder, newPatchRequest(fixture.memberID, fixture.sharedAlone.ID, `{"name":"changed"}`))
+	api.DeleteGifHandler(deleteRecorder, deleteRequest)
+
+	// 3.ASSERT
+	assert.Equal(t, http.StatusNotFound, patchRecorder.Code)
+	assert.Equal(t, http.StatusNotFound, deleteRecorder.Code)
+
+	var dbGif gifs.Gif
+	require.Nil(t, mongoDal.FindByID(context.Background(), dal.CollGifs, fixture.sharedAlone.ID.Hex(), &dbGif))
+	assert.Equal(t, fixture.sharedAlone.Name, dbGif.Name)
+}
+
+func TestDeleteGifHandler_SharedGif_ExpectedShareRemoved(t *testing.T) {
+	// 1.ARRANGE
+	fixture := insertSharedGifs(t)
+	responseRecorder := httptest.NewRecorder()
+	api := gifs.NewGifApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+	// 2.ACT
+	api.DeleteGifHandler(responseRecorder, newPatchRequest(fixture.ownerID, fixture.sharedAlone.ID, ""))
+
+	// 3.ASSERT
+	require.Equal(t, http.StatusNoContent, responseRecorder.Code)
+	count, err := mongoDal.Count(context.Background(), dal.CollShares, bson.M{"_id": fixture.gifShareID})
+	require.Nil(t, err)
+	assert.Zero(t, count)
+}
+
+func TestCreateGifHandler_Tags_ExpectedTagsNormalized(t *testing.T) {
+	// 1.ARRANGE
+	userID := primitive.NewObjectID()
+	category := categories.Category{ID: primitive.NewObjectID(), Name: t.Name(), UserId: userID}
+	_, errInsertCategory := mongoDal.Insert(context.Background(), dal.CollCategories, []any{category})
+	require.Nil(t, errInsertCategory)
+
+	defer func() {
+		mongoDal.Delete(context.Background(), dal.CollGifs, bson.M{"userId": userID})
+		mongoDal.Delete(context.Background(), dal.CollCategories, bson.M{"userId": userID})
+	}()
+
+	bts, _ := json.Marshal(gifs.GifRequest{
+		Name:       t.Name(),
+		URL:        "https://media.giphy.com/gif.gif",
+		CategoryId: category.ID,
+		Tags:       []string{" Funny ", "cat", "FUNNY", ""},
+	})
+	request := httptest.NewRequest(http.MethodPost, "/gifs", bytes.NewReader(bts))
+	request = request.WithContext(context.WithValue(context.Background(), "userID", userID))
+	responseRecorder := testifyhttp.TestResponseWriter{}
+	api := gifs.NewGifApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+	// 2.ACT
+	api.CreateGifHandler(&responseRecorder, request)
+
+	// 3.ASSERT
+	require.Equal(t, http.StatusCreated, responseRecorder.StatusCode)
+
+	var gifDTO gifs.GifDto
+	require.Nil(t, json.Unmarshal([]byte(responseRecorder.Output), &gifDTO))
+	assert.Equal(t, []string{"funny", "cat"}, gifDTO.Tags)
+
+	var dbGif gifs.Gif
+	require.Nil(t, mongoDal.FindByID(context.Background(), dal.CollGifs, gifDTO.ID, &dbGif))
+	assert.Equal(t, []string{"funny", "cat"}, dbGif.Tags)
+}
+
+func TestCreateGifHandler_InvalidTags_ExpectedUnprocessableEntity(t *testing.T) {
+	tooMany := make([]string, 0, gifs.MaxGifTags+1)
+	for i := 0; i <= gifs.MaxGifTags; i++ {
+		tooMany = append(tooMany, fmt.Sprintf("tag%d", i))
+	}
+	tooLong := strings.Repeat("a", gifs.MaxTagLength+1)
+
+	testCases := []struct {
+		name     string
+		tags     []string
+		expected httputil.ValidationErrors
+	}{
+		{"too many tags", tooMany, httputil.ValidationErrors{{Field: "tags", Message: "must have at most 20 tags"}}},
+		{"too long tag", []string{"cat", tooLong}, httputil.ValidationErrors{{Field: "tags[1]", Message: "must be at most 50 characters long"}}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			// 1.ARRANGE
+			bts, _ := json.Marshal(gifs.GifRequest{
+				Name:       t.Name(),
+				URL:        "https://media.giphy.com/gif.gif",
+				CategoryId: primitive.NewObjectID(),
+				Tags:       testCase.tags,
+			})
+			request := httptest.NewRequest(http.MethodPost, "/gifs", bytes.NewReader(bts))
+			request = request.WithContext(context.WithValue(context.Background(), "userID", primitive.NewObjectID()))
+			responseRecorder := httptest.NewRecorder()
+			api := gifs.NewGifApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+			// 2.ACT
+			api.CreateGifHandler(responseRecorder, request)
+
+			// 3.ASSERT
+			require.Equal(t, http.StatusUnprocessableEntity, responseRecorder.Code)
+
+			var errorResponse struct {
+				Details httputil.ValidationErrors `json:"details"`
+			}
+			require.Nil(t, json.NewDecoder(responseRecorder.Body).Decode(&errorResponse))
+			assert.Equal(t, testCase.expected, errorResponse.Details)
+		})
+	}
+}
+
+func TestPatchGifHandler_Tags_ExpectedTagsReplaced(t *testing.T) {
+	// 1.ARRANGE
+	userID := primitive.NewObjectID()
+	gif := gifs.Gif{ID: primitive.NewObjectID(), Name: t.Name(), URL: "https://media.giphy.com/gif.gif", UserId: userID, Tags: []string{"cat"}}
+	_, errInsertGif := mongoDal.Insert(context.Background(), dal.CollGifs, []any{gif})
+	require.Nil(t, errInsertGif)
+
+	defer func() {
+		mongoDal.Delete(context.Background(), dal.CollGifs, bson.M{"userId": userID})
+	}()
+
+	request := newPatchRequest(userID, gif.ID, `{"tags": ["Dog", "dog ", "Party"]}`)
+	responseRecorder := httptest.NewRecorder()
+	api := gifs.NewGifApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+	// 2.ACT
+	api.PatchGifHandler(responseRecorder, request)
+
+	// 3.ASSERT
+	require.Equal(t, http.StatusOK, responseRecorder.Code)
+
+	var gifDTO gifs.GifDto
+	require.Nil(t, json.NewDecoder(responseRecorder.Body).Decode(&gifDTO))
+	assert.Equal(t, []string{"dog", "party"}, gifDTO.Tags)
+
+	var dbGif gifs.Gif
+	require.Nil(t, mongoDal.FindByID(context.Background(), dal.CollGifs, gif.ID.Hex(), &dbGif))
+	assert.Equal(t, []string{"dog", "party"}, dbGif.Tags)
+}
+
+func TestGetGifsHandler_FilterByTags(t *testing.T) {
+	userID := primitive.NewObjectID()
+	funnyCat := gifs.Gif{ID: primitive.NewObjectID(), Name: "funny cat", URL: "gifUrl", UserId: userID, Tags: []string{"funny", "cat"}}
+	funnyDog := gifs.Gif{ID: primitive.NewObjectID(), Name: "funny dog", URL: "gifUrl", UserId: userID, Tags: []string{"funny", "dog"}}
+	untagged := gifs.Gif{ID: primitive.NewObjectID(), Name: "untagged", URL: "gifUrl", UserId: userID}
+	otherUsersGif := gifs.Gif{ID: primitive.NewObjectID(), Name: "other", URL: "gifUrl", UserId: primitive.NewObjectID(), Tags: []string{"cat"}}
+	_, errInsert := mongoDal.Insert(context.Background(), dal.CollGifs, []any{funnyCat, funnyDog, untagged, otherUsersGif})
+	require.Nil(t, errInsert)
+
+	defer func() {
+		mongoDal.Delete(context.Background(), dal.CollGifs, bson.M{"_id": bson.M{"$in": bson.A{funnyCat.ID, funnyDog.ID, untagged.ID, otherUsersGif.ID}}})
+	}()
+
+	testCases := []struct {
+		filter   string
+		expected []string
+	}{
+		{`tags="Cat"`, []string{"funny cat"}},
+		{`tags in (cat, dog)`, []string{"funny cat", "funny dog"}},
+		{`tags all (funny, dog)`, []string{"funny dog"}},
+		{`tags all (cat, dog)`, []string{}},
+		{`tags not in (funny)`, []string{"untagged"}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.filter, func(t *testing.T) {
+			// 1.ARRANGE
+			request := httptest.NewRequest(http.MethodGet, "/gifs?sort=name&filter="+url.QueryEscape(testCase.filter), nil)
+			request = request.WithContext(context.WithValue(context.Background(), "userID", userID))
+			responseRecorder := httptest.NewRecorder()
+			api := gifs.NewGifApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+			// 2.ACT
+			api.GetGifsHandler(responseRecorder, request)
+
+			// 3.ASSERT
+			require.Equal(t, http.StatusOK, responseRecorder.Code)
+
+			var gifDTOs gifs.GifDtos
+			require.Nil(t, json.NewDecoder(responseRecorder.Body).Decode(&gifDTOs))
+			names := make([]string, 0, len(gifDTOs))
+			for _, gifDTO := range gifDTOs {
+				names = append(names, gifDTO.Name)
+			}
+			assert.Equal(t, testCase.expected, names)
+		})
+	}
+}
+
+func newTagsRequest(userID primitive.ObjectID, query string) *http.Request {
+	request := httptest.NewRequest(http.MethodGet, "/tags?"+query, nil)
+	return request.WithContext(context.WithValue(context.Background(), "userID", userID))
+}
+
+func TestTagsHandler_ExpectedTagCountsOfUser(t *testing.T) {
+	// 1.ARRANGE
+	userID := primitive.NewObjectID()
+	_, errInsert := mongoDal.Insert(context.Background(), dal.CollGifs, []any{
+		gifs.Gif{ID: primitive.NewObjectID(), Name: "1", URL: "gifUrl", UserId: userID, Tags: []string{"funny", "cat"}},
+		gifs.Gif{ID: primitive.NewObjectID(), Name: "2", URL: "gifUrl", UserId: userID, Tags: []string{"funny", "dog"}},
+		gifs.Gif{ID: primitive.NewObjectID(), Name: "3", URL: "gifUrl", UserId: userID},
+		gifs.Gif{ID: primitive.NewObjectID(), Name: "4", URL: "gifUrl", UserId: primitive.NewObjectID(), Tags: []string{"cat", "cat-lovers"}},
+	})
+	require.Nil(t, errInsert)
+
+	defer func() {
+		mongoDal.Delete(context.Background(), dal.CollGifs, bson.M{"name": bson.M{"$in": bson.A{"1", "2", "3", "4"}}})
+	}()
+
+	responseRecorder := httptest.NewRecorder()
+	api := gifs.NewGifApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+	// 2.ACT
+	api.TagsHandler(responseRecorder, newTagsRequest(userID, ""))
+
+	// 3.ASSERT
+	require.Equal(t, http.StatusOK, responseRecorder.Code)
+
+	var tagCounts []gifs.TagCountDto
+	require.Nil(t, json.NewDecoder(responseRecorder.Body).Decode(&tagCounts))
+	assert.Equal(t, []gifs.TagCountDto{{Tag: "funny", Count: 2}, {Tag: "cat", Count: 1}, {Tag: "dog", Count: 1}}, tagCounts)
+}
+
+func TestTagsHandler_Limit(t *testing.T) {
+	userID := primitive.NewObjectID()
+	_, errInsert := mongoDal.Insert(context.Background(), dal.CollGifs, []any{
+		gifs.Gif{ID: primitive.NewObjectID(), Name: t.Name(), URL: "gifUrl", UserId: userID, Tags: []string{"funny", "cat"}},
+	})
+	require.Nil(t, errInsert)
+
+	defer func() {
+		mongoDal.Delete(context.Background(), dal.CollGifs, bson.M{"userId": userID})
+	}()
+
+	t.Run("limited", func(t *testing.T) {
+		// 1.ARRANGE
+		responseRecorder := httptest.NewRecorder()
+		api := gifs.NewGifApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+		// 2.ACT
+		api.TagsHandler(responseRecorder, newTagsRequest(userID, "limit=1"))
+
+		// 3.ASSERT
+		require.Equal(t, http.StatusOK, responseRecorder.Code)
+
+		var tagCounts []gifs.TagCountDto
+		require.Nil(t, json.NewDecoder(responseRecorder.Body).Decode(&tagCounts))
+		assert.Equal(t, []gifs.TagCountDto{{Tag: "cat", Count: 1}}, tagCounts)
+	})
+
+	for _, limit := range []string{"0", "abc", "1001"} {
+		t.Run("invalid "+limit, func(t *testing.T) {
+			// 1.ARRANGE
+			responseRecorder := httptest.NewRecorder()
+			api := gifs.NewGifApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+			// 2.ACT
+			api.TagsHandler(responseRecorder, newTagsRequest(userID, "limit="+limit))
+
+			// 3.ASSERT
+			assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+		})
+	}
+}
+
+func newSearchRequest(userID primitive.ObjectID, query string) *http.Request {
+	request := httptest.NewRequest(http.MethodGet, "/gifs/search?"+query, nil)
+	return request.WithContext(context.WithValue(context.Background(), "userID", userID))
+}
+
+func TestSearchGifsHandler_ExpectedMostRelevantFirst(t *testing.T) {
+	// 1.ARRANGE
+	userID := primitive.NewObjectID()
+	taggedCat := gifs.Gif{ID: primitive.NewObjectID(), Name: "dancing", URL: "gifUrl", UserId: userID, Tags: []string{"cat"}}
+	namedCat := gifs.Gif{ID: primitive.NewObjectID(), Name: "Funny cat", URL: "gifUrl", UserId: userID}
+	funnyCat := gifs.Gif{ID: primitive.NewObjectID(), Name: "funny cat", URL: "gifUrl", UserId: userID, Tags: []string{"funny"}}
+	dog := gifs.Gif{ID: primitive.NewObjectID(), Name: "dog", URL: "gifUrl", UserId: userID}
+	otherUsersCat := gifs.Gif{ID: primitive.NewObjectID(), Name: "cat", URL: "gifUrl", UserId: primitive.NewObjectID()}
+	_, errInsert := mongoDal.Insert(context.Background(), dal.CollGifs, []any{taggedCat, namedCat, funnyCat, dog, otherUsersCat})
+	require.Nil(t, errInsert)
+
+	defer func() {
+		mongoDal.Delete(context.Background(), dal.CollGifs, bson.M{"_id": bson.M{"$in": bson.A{taggedCat.ID, namedCat.ID, funnyCat.ID, dog.ID, otherUsersCat.ID}}})
+	}()
+
+	responseRecorder := httptest.NewRecorder()
+	api := gifs.NewGifApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+	// 2.ACT
+	api.SearchGifsHandler(responseRecorder, newSearchRequest(userID, "q="+url.QueryEscape("Funny CAT")))
+
+	// 3.ASSERT
+	require.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.Equal(t, "3", responseRecorder.Header().Get("X-Total-Count"))
+
+	var results []gifs.SearchResultDto
+	require.Nil(t, json.NewDecoder(responseRecorder.Body).Decode(&results))
+	require.Len(t, results, 3)
+	// the terms found in the name and the tags count more than the ones found only in the name or only in the tags
+	assert.Equal(t, funnyCat.ID.Hex(), results[0].ID)
+	assert.Equal(t, namedCat.ID.Hex(), results[1].ID)
+	assert.Equal(t, taggedCat.ID.Hex(), results[2].ID)
+	assert.Equal(t, []string{"cat"}, results[2].Tags)
+	assert.Greater(t, results[0].Score, results[1].Score)
+	assert.Greater(t, results[1].Score, results[2].Score)
+}
+
+func TestSearchGifsHandler_Paginated_ExpectedPageAndHeaders(t *testing.T) {
+	// 1.ARRANGE
+	userID := primitive.NewObjectID()
+	documents := make([]any, 0, 3)
+	for i := 0; i < 3; i++ {
+		documents = append(documents, gifs.Gif{ID: primitive.NewObjectID(), Name: fmt.Sprintf("party %d", i), URL: "gifUrl", UserId: userID})
+	}
+	_, errInsert := mongoDal.Insert(context.Background(), dal.CollGifs, documents)
+	require.Nil(t, errInsert)
+
+	defer func() {
+		mongoDal.Delete(context.Background(), dal.CollGifs, bson.M{"userId": userID})
+	}()
+
+	responseRecorder := httptest.NewRecorder()
+	api := gifs.NewGifApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+	// 2.ACT
+	api.SearchGifsHandler(responseRecorder, newSearchRequest(userID, "q=party&page=2&pageSize=2"))
+
+	// 3.ASSERT
+	require.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.Equal(t, "3", responseRecorder.Header().Get("X-Total-Count"))
+	assert.Contains(t, responseRecorder.Header().Get("Link"), `rel="prev"`)
+
+	var results []gifs.SearchResultDto
+	require.Nil(t, json.NewDecoder(responseRecorder.Body).Decode(&results))
+	assert.Len(t, results, 1)
+}
+
+func TestSearchGifsHandler_InvalidQuery_ExpectedBadRequest(t *testing.T) {
+	testCases := []string{
+		"",
+		"q=",
+		"q=" + url.QueryEscape(" - ! "),
+		"q=" + strings.Repeat("a", gifs.MaxSearchQueryLength+1),
+		"q=cat&pageSize=0",
+	}
+
+	for _, query := range testCases {
+		t.Run(query, func(t *testing.T) {
+			// 1.ARRANGE
+			responseRecorder := httptest.NewRecorder()
+			api := gifs.NewGifApi(mongoDal, httputil.NewGifsApiQueryParamParser())
+
+			// 2.ACT
+			api.SearchGifsHandler(responseRecorder, newSearchRequest(primitive.NewObjectID(), query))
+
+			// 3.ASSERT
+			assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package integration_tests
 
 import (
 	"context"
+	"gifmanager-backend/config"
 	"gifmanager-backend/dal"
 	"os"
 	"testing"
@@ -26,13 +27,24 @@ func TestMain(m *testing.M) {
 
 func setUp() func() {
 
+	// when MONGO_URI is not set the tests run against the in-memory DAL, so no database is needed
+	cfg, err := config.LoadForTests(os.LookupEnv)
+	if err != nil {
+		panic(err)
+	}
+	if cfg.Mongo.InMemory {
+		mongoDal = dal.NewMemoryDal()
+		ensureIndexes()
+		return func() {}
+	}
+
 	// connect to the mongo but not to the gif-manager database
 	// connect to a new database which we are going to use for the tests
-	var err error
-	mongoDal, err = dal.NewMongoDal(context.Background(), "mongodb://localhost:27017", dal.TestDbName)
+	mongoDal, err = dal.NewMongoDal(context.Background(), cfg.Mongo.URI, cfg.Mongo.Database)
 	if err != nil {
 		panic(err)
 	}
+	ensureIndexes()
 
 	// return a tearDown() func
 	return func() {
@@ -42,3 +54,10 @@ func setUp() func() {
 		}
 	}
 }
+
+// ensureIndexes creates the indexes of dal.Indexes, the search needs the text index of the gifs
+func ensureIndexes() {
+	if _, err := dal.EnsureIndexes(context.Background(), mongoDal, dal.Indexes); err != nil {
+		panic(err)
+	}
+}
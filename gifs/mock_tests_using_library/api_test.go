@@ -20,6 +20,11 @@ import (
 	"testing"
 )
 
+// runWithoutTransaction is returned by the mocked WithTransaction, mockery calls it instead of returning a value
+func runWithoutTransaction(ctx context.Context, fn func(context.Context) error) error {
+	return fn(ctx)
+}
+
 func TestCreateGifHandler_ExpectedStatusCreated(t *testing.T) {
 	// 1.ARRANGE
 	userID := primitive.NewObjectID()
@@ -27,7 +32,7 @@ func TestCreateGifHandler_ExpectedStatusCreated(t *testing.T) {
 
 	expectedGif := gifs.Gif{
 		Name:       t.Name(),
-		URL:        "gif-url",
+		URL:        "https://media.giphy.com/gif.gif",
 		CategoryId: categoryID,
 		UserId:     userID,
 		IsFavorite: false,
@@ -72,18 +77,23 @@ func TestCreateGifHandler_ExpectedStatusCreated(t *testing.T) {
 	expectedUpdateResult := &dal.UpdateResult{
 		MatchedCount: 1,
 	}
+	// the category counter is only increased when the category belongs to the same user
 	mockedDal.On(
-		"UpdateByID",
+		"Update",
 		mock.Anything,
 		dal.CollCategories,
-		expectedGif.CategoryId.Hex(),
+		bson.M{"_id": expectedGif.CategoryId, "userId": userID},
 		bson.M{"$inc": bson.M{"gifCount": 1}},
 	).Return(expectedUpdateResult, nil)
 
+	// the insert and the update run in a transaction, the mocked transaction just calls the function it gets
+	mockedDal.On("WithTransaction", mock.Anything, mock.Anything).
+		Return(runWithoutTransaction)
+
 	// create the request body
 	requestBody := gifs.GifRequest{
 		Name:       t.Name(),
-		URL:        "gif-url",
+		URL:        "https://media.giphy.com/gif.gif",
 		CategoryId: categoryID,
 	}
 
@@ -126,18 +136,22 @@ func TestGetGifsHandler_ExpectedOk(t *testing.T) {
 		CategoryId: categoryID,
 	}
 
-	expectedFindArgs := dal.FindArguments{
-		Filter: bson.M{"userId": expectedGif.UserId},
-	}
+	expectedFindArgs := dal.NewFindArguments().
+		WithFilter(bson.M{"userId": expectedGif.UserId}).
+		WithSorts(dal.Sorts{{FieldName: "_id", Ascending: true}}).
+		WithSkip(0).
+		WithLimit(httputil.DefaultPageSize)
 
 	mockedDal := dal.NewMockDAL(t)
 	mockedDal.On("Find",
 		mock.Anything,
 		dal.CollGifs,
-		expectedFindArgs,
+		*expectedFindArgs,
 		mock.Anything,
 	).
 		Return(nil)
+	mockedDal.On("Count", mock.Anything, dal.CollGifs, bson.M{"userId": expectedGif.UserId}).
+		Return(int64(0), nil)
 
 	request := httptest.NewRequest(http.MethodGet, "/gifs", nil)
 	requestContext := context.WithValue(context.Background(), "userID", expectedGif.UserId)
@@ -174,17 +188,28 @@ func TestGetGifsHandler_FindReturnsError_ExpectedInternalServerError(t *testing.
 	api.GetGifsHandler(responseRecorder, request)
 
 	require.Equal(t, http.StatusInternalServerError, responseRecorder.Code)
-	assert.Equal(t, gifs.ErrFindingGifs, responseRecorder.Body.String())
+	var errorResponse httputil.ErrorResponse
+	require.Nil(t, json.NewDecoder(responseRecorder.Body).Decode(&errorResponse))
+	assert.Equal(t, gifs.ErrFindingGifs.Code, errorResponse.Code)
+	assert.Equal(t, gifs.ErrFindingGifs.Message, errorResponse.Message)
+	assert.Equal(t, "application/json", responseRecorder.Header().Get("Content-Type"))
 }
 
 func TestDeleteGifHandler_StatusNoContent(t *testing.T) {
 	gifID := primitive.NewObjectID()
+	userID := primitive.NewObjectID()
 	mockedDal := dal.NewMockDAL(t)
-	mockedDal.On("FindAndDeleteByID", mock.Anything, dal.CollGifs, gifID.Hex(), mock.Anything).
+	// the delete is scoped to the gifs of the user making the request
+	mockedDal.On("FindAndDelete", mock.Anything, dal.CollGifs, bson.M{"_id": gifID, "userId": userID}, mock.Anything).
 		Return(nil)
+	// the shares of the gif are deleted with it
+	mockedDal.On("Delete", mock.Anything, dal.CollShares, bson.M{"gifId": gifID}).
+		Return(&dal.DeleteResult{DeletedCount: 0}, nil)
+	mockedDal.On("WithTransaction", mock.Anything, mock.Anything).
+		Return(runWithoutTransaction)
 
 	request := httptest.NewRequest(http.MethodDelete, "/gifs", nil)
-	requestContext := context.WithValue(context.Background(), "userID", primitive.NewObjectID())
+	requestContext := context.WithValue(context.Background(), "userID", userID)
 	request = request.WithContext(requestContext)
 	request = mux.SetURLVars(request, map[string]string{
 		"id": gifID.Hex(),
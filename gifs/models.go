@@ -9,14 +9,43 @@ type Gif struct {
 	IsFavorite bool               `bson:"isFavourite"`
 	UserId     primitive.ObjectID `bson:"userId"`
 	CategoryId primitive.ObjectID `bson:"categoryId"`
+	Tags       []string           `bson:"tags"`
+	// Version is incremented by every write, it is omitted when empty so that a $set of the whole gif doesn't touch it
+	Version int `bson:"version,omitempty"`
 }
 
 func (gif Gif) ToDto() GifDto {
 	return GifDto{
-		ID:         gif.ID.Hex(),
-		Name:       gif.Name,
-		URL:        gif.URL,
-		CategoryID: gif.CategoryId.Hex(),
+		ID:          gif.ID.Hex(),
+		Name:        gif.Name,
+		URL:         gif.URL,
+		CategoryID:  gif.CategoryId.Hex(),
+		IsFavourite: gif.IsFavorite,
+		Tags:        gif.tags(),
+	}
+}
+
+// tags returns an empty list for the gifs stored before they had tags.
+func (gif Gif) tags() []string {
+	if gif.Tags == nil {
+		return []string{}
+	}
+	return gif.Tags
+}
+
+// gifCategory is the part of a category that is embedded in a gif. The categories package can't be imported here,
+// since it imports this package, so the bson names are repeated from categories.Category.
+type gifCategory struct {
+	ID       primitive.ObjectID `bson:"_id"`
+	Name     string             `bson:"name"`
+	GifCount int                `bson:"gifCount"`
+}
+
+func (c gifCategory) ToDto() *GifCategoryDto {
+	return &GifCategoryDto{
+		ID:        c.ID.Hex(),
+		Name:      c.Name,
+		GifsCount: c.GifCount,
 	}
 }
 
@@ -24,8 +53,48 @@ type Gifs []Gif
 
 func (gifs Gifs) ToDto() GifDtos {
 	dtos := make(GifDtos, 0, len(gifs))
-	for i, gif := range gifs {
-		dtos[i] = gif.ToDto()
+	for _, gif := range gifs {
+		dtos = append(dtos, gif.ToDto())
+	}
+	return dtos
+}
+
+func (gifs Gifs) ToSharedDto() []SharedGifDto {
+	dtos := make([]SharedGifDto, 0, len(gifs))
+	for _, gif := range gifs {
+		dtos = append(dtos, SharedGifDto{GifDto: gif.ToDto(), OwnerID: gif.UserId.Hex()})
+	}
+	return dtos
+}
+
+// TagCount is a result of the tag counts pipeline, the _id is the tag.
+type TagCount struct {
+	Tag   string `bson:"_id"`
+	Count int    `bson:"count"`
+}
+
+type TagCounts []TagCount
+
+func (counts TagCounts) ToDto() []TagCountDto {
+	dtos := make([]TagCountDto, 0, len(counts))
+	for _, count := range counts {
+		dtos = append(dtos, TagCountDto{Tag: count.Tag, Count: count.Count})
+	}
+	return dtos
+}
+
+// SearchResult is a gif found by the search with its relevance.
+type SearchResult struct {
+	Gif   `bson:",inline"`
+	Score float64 `bson:"score"`
+}
+
+type SearchResults []SearchResult
+
+func (results SearchResults) ToDto() []SearchResultDto {
+	dtos := make([]SearchResultDto, 0, len(results))
+	for _, result := range results {
+		dtos = append(dtos, SearchResultDto{GifDto: result.Gif.ToDto(), Score: result.Score})
 	}
 	return dtos
 }
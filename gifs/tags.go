@@ -0,0 +1,42 @@
+package gifs
+
+import (
+	"fmt"
+	"gifmanager-backend/httputil"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	MaxGifTags   = 20
+	MaxTagLength = 50
+)
+
+// NormalizeTags lowercases and trims the tags and drops the empty and repeated ones, keeping the order of the first
+// occurrence. The result is never nil, so a gif without tags is stored with an empty array.
+func NormalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// validateTags checks the tags as they are stored, so differently written repetitions of a tag count once.
+func validateTags(errs *httputil.ValidationErrors, tags []string) {
+	if len(NormalizeTags(tags)) > MaxGifTags {
+		errs.Add("tags", "must have at most %d tags", MaxGifTags)
+		return
+	}
+	for i, tag := range tags {
+		if utf8.RuneCountInString(strings.TrimSpace(tag)) > MaxTagLength {
+			errs.Add(fmt.Sprintf("tags[%d]", i), "must be at most %d characters long", MaxTagLength)
+		}
+	}
+}
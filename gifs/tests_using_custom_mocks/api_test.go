@@ -29,13 +29,15 @@ func TestCreateGifHandler_ExpectedStatusCreated(t *testing.T) {
 		InsertedDocumentsCount: 1,
 	}
 	// then create a new instance of our custom MockDal and set the InsertResult property
+	// and the UpdateResult of increasing the counter of the gif's category
 	mockedDal := NewMockDal().
-		WithMockedInsertResult(mockedInsertResult)
+		WithMockedInsertResult(mockedInsertResult).
+		WithMockedUpdateResult(&dal.UpdateResult{MatchedCount: 1})
 
 	// create the request body
 	requestBody := gifs.GifRequest{
 		Name:       t.Name(),
-		URL:        "gif-url",
+		URL:        "https://media.giphy.com/gif.gif",
 		CategoryId: categoryID,
 	}
 
@@ -149,7 +151,11 @@ func TestGetGifsHandler_FindReturnsError_ExpectedInternalServerError(t *testing.
 
 	// 3. ASSERT
 	require.Equal(t, http.StatusInternalServerError, responseRecorder.Code)
-	assert.Equal(t, gifs.ErrFindingGifs, responseRecorder.Body.String())
+	var errorResponse httputil.ErrorResponse
+	require.Nil(t, json.NewDecoder(responseRecorder.Body).Decode(&errorResponse))
+	assert.Equal(t, gifs.ErrFindingGifs.Code, errorResponse.Code)
+	assert.Equal(t, gifs.ErrFindingGifs.Message, errorResponse.Message)
+	assert.Equal(t, "application/json", responseRecorder.Header().Get("Content-Type"))
 }
 
 func TestDeleteGifHandler_StatusNoContent(t *testing.T) {
@@ -13,6 +13,7 @@ type MockDal struct {
 	deleteResult *dal.DeleteResult
 	updateResult *dal.UpdateResult
 	findResult   interface{}
+	countResult  int64
 }
 
 func NewMockDal() *MockDal {
@@ -34,6 +35,16 @@ func (m *MockDal) WithMockedFindResult(result any) *MockDal {
 	return m
 }
 
+func (m *MockDal) WithMockedCountResult(count int64) *MockDal {
+	m.countResult = count
+	return m
+}
+
+func (m *MockDal) WithMockedUpdateResult(result *dal.UpdateResult) *MockDal {
+	m.updateResult = result
+	return m
+}
+
 func (m *MockDal) WithError(err error) *MockDal {
 	m.err = err
 	return m
@@ -63,6 +74,10 @@ func (m *MockDal) Find(ctx context.Context, collection string, findArguments dal
 	return setFindResult(result, m.findResult)
 }
 
+func (m *MockDal) Count(ctx context.Context, collection string, filter any) (int64, error) {
+	return m.countResult, m.err
+}
+
 func (m *MockDal) Delete(ctx context.Context, collection string, filter any) (*dal.DeleteResult, error) {
 	return m.deleteResult, m.err
 }
@@ -75,6 +90,14 @@ func (m *MockDal) FindAndDeleteByID(ctx context.Context, collection string, id s
 	return setFindResult(result, m.findResult)
 }
 
+func (m *MockDal) FindAndDelete(ctx context.Context, collection string, filter any, result any) error {
+	if m.err != nil {
+		return m.err
+	}
+
+	return setFindResult(result, m.findResult)
+}
+
 func (m *MockDal) Aggregate(ctx context.Context, collection string, pipeline []any, result any) error {
 	if m.err != nil {
 		return m.err
@@ -91,6 +114,31 @@ func (m *MockDal) UpdateByID(ctx context.Context, collection string, id string,
 	return m.updateResult, m.err
 }
 
+func (m *MockDal) EnsureIndex(ctx context.Context, collection string, index dal.Index) error {
+	return m.err
+}
+
+func (m *MockDal) DropIndex(ctx context.Context, collection string, name string) error {
+	return m.err
+}
+
+func (m *MockDal) ListIndexes(ctx context.Context, collection string) ([]dal.Index, error) {
+	return nil, m.err
+}
+
+func (m *MockDal) Search(ctx context.Context, collection string, searchArguments dal.SearchArguments, result any) (int64, error) {
+	if m.err != nil {
+		return 0, m.err
+	}
+
+	return m.countResult, setFindResult(result, m.findResult)
+}
+
+// WithTransaction runs fn without a transaction, the mock has nothing to roll back
+func (m *MockDal) WithTransaction(ctx context.Context, fn func(txCtx context.Context) error) error {
+	return fn(ctx)
+}
+
 func setFindResult(obj any, findResult any) error {
 	resultValue := reflect.ValueOf(obj)
 	if resultValue.Kind() != reflect.Ptr || resultValue.IsNil() {
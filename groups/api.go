@@ -1,9 +1,14 @@
 package groups
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"gifmanager-backend/authz"
 	"gifmanager-backend/dal"
+	"gifmanager-backend/httputil"
+	"gifmanager-backend/shares"
+	"gifmanager-backend/users"
 	"github.com/gorilla/mux"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -38,152 +43,481 @@ func (api Api) InitializeEndpoints(route *mux.Router) {
 		Path("/groups").
 		Methods(http.MethodGet).
 		Handler(http.HandlerFunc(api.GetGroupHandler))
+	route.
+		Path("/groups/{id}").
+		Methods(http.MethodGet).
+		Handler(http.HandlerFunc(api.GetGroupByIdHandler))
 
+	route.
+		Path("/groups/{id}/members").
+		Methods(http.MethodPost).
+		Handler(http.HandlerFunc(api.AddMemberHandler))
+	route.
+		Path("/groups/{id}/members/{userId}").
+		Methods(http.MethodPut).
+		Handler(http.HandlerFunc(api.UpdateMemberHandler))
+	route.
+		Path("/groups/{id}/members/{userId}").
+		Methods(http.MethodDelete).
+		Handler(http.HandlerFunc(api.RemoveMemberHandler))
+	route.
+		Path("/groups/{id}/leave").
+		Methods(http.MethodPost).
+		Handler(http.HandlerFunc(api.LeaveGroupHandler))
 }
 
 func (api Api) CreateGroupHandler(writer http.ResponseWriter, request *http.Request) {
 	ctx := request.Context()
-	userID := ctx.Value("userID")
+	userID := authz.UserID(ctx)
 
 	var groupRequest GroupRequest
-	if decodeErr := json.NewDecoder(request.Body).
-		Decode(&groupRequest); decodeErr != nil {
-
-		writer.WriteHeader(http.StatusBadRequest)
-		_, errWrite := writer.Write([]byte(fmt.Sprintf("error while decoding the request: %s", decodeErr.Error())))
-		if errWrite != nil {
-			fmt.Println(errWrite.Error())
-		}
+	if decodeErr := httputil.DecodeJSON(writer, request, &groupRequest); decodeErr != nil {
+		httputil.WriteError(writer, decodeErr)
 		return
 	}
 
 	group := groupRequest.ToModel()
-	group.UserId = userID.(primitive.ObjectID)
-	_, errInsert := api.Dal.Insert(ctx, "groups", []any{group})
-	if errInsert != nil {
-		writer.WriteHeader(http.StatusInternalServerError)
-		_, errWrite := writer.Write([]byte("error encountered on inserting the groups"))
-		if errWrite != nil {
-			fmt.Println(errWrite.Error())
-		}
+	group.ID = primitive.NewObjectID()
+	group.UserId = userID
+
+	emails := make([]string, 0, len(groupRequest.Members))
+	for _, member := range groupRequest.Members {
+		emails = append(emails, member.NormalizedEmail())
+	}
+	invited, err := users.FindByUserNames(ctx, api.Dal, emails)
+	if err != nil {
+		fmt.Println(err.Error())
+		httputil.WriteError(writer, ErrFindingUsers)
 		return
 	}
 
-	if errEncode := json.NewEncoder(writer).Encode(group); errEncode != nil {
-		writer.WriteHeader(http.StatusInternalServerError)
-		_, errWrite := writer.Write([]byte("error encountered on encoding the group"))
-		if errWrite != nil {
-			fmt.Println(errWrite.Error())
+	var errs httputil.ValidationErrors
+	for i, member := range groupRequest.Members {
+		field := fmt.Sprintf("members[%d].email", i)
+		user, ok := invited[member.NormalizedEmail()]
+		switch {
+		case !ok:
+			errs.Add(field, "no user is registered with this email")
+		case user.ID == userID:
+			errs.Add(field, "is the owner of the group")
+		default:
+			group.Members = append(group.Members, Member{UserId: user.ID, Role: member.RoleOrDefault()})
 		}
+	}
+	if len(errs) > 0 {
+		httputil.WriteError(writer, httputil.ErrValidationFailed.WithDetails(errs))
 		return
 	}
 
-	writer.WriteHeader(http.StatusCreated)
+	if _, errInsert := api.Dal.Insert(ctx, dal.CollGroups, []any{group}); errInsert != nil {
+		fmt.Println(errInsert.Error())
+		httputil.WriteError(writer, ErrInsertingGroups)
+		return
+	}
+
+	api.writeGroup(writer, request, http.StatusCreated, group)
 }
 
+// GetGroupHandler returns the groups the caller owns or is a member of.
 func (api Api) GetGroupHandler(writer http.ResponseWriter, request *http.Request) {
 	ctx := request.Context()
 
 	groups := make([]Group, 0)
-	findArgs := dal.FindArguments{}
+	findArgs := dal.NewFindArguments().
+		WithFilter(authz.MemberOfGroups(ctx)).
+		WithSorts(dal.Sorts{{FieldName: "name", Ascending: true}, {FieldName: "_id", Ascending: true}})
 
-	if err := api.Dal.Find(ctx, "groups", findArgs, &groups); err != nil {
-		writer.WriteHeader(http.StatusInternalServerError)
-		_, errWrite := writer.Write([]byte("error encountered on retrieving the groups"))
+	if err := api.Dal.Find(ctx, dal.CollGroups, *findArgs, &groups); err != nil {
+		fmt.Println(err.Error())
+		httputil.WriteError(writer, ErrFindingGroups)
+		return
+	}
 
-		if errWrite != nil {
-			fmt.Println(errWrite.Error())
-		}
+	userIDs := make([]primitive.ObjectID, 0)
+	for _, group := range groups {
+		userIDs = append(userIDs, group.UserIDs()...)
+	}
+	emails, err := api.findEmails(ctx, userIDs)
+	if err != nil {
+		httputil.WriteError(writer, err)
 		return
 	}
 
-	if errEncode := json.NewEncoder(writer).Encode(groups); errEncode != nil {
-		writer.WriteHeader(http.StatusInternalServerError)
-		_, errWrite := writer.Write([]byte("error encountered on encoding the groups"))
-		if errWrite != nil {
-			fmt.Println(errWrite.Error())
-		}
+	dtos := make([]GroupDto, 0, len(groups))
+	for _, group := range groups {
+		dtos = append(dtos, group.ToDto(authz.UserID(ctx), emails))
+	}
+	if err := httputil.WriteJSONWithETag(writer, request, dtos); err != nil {
+		fmt.Println(err.Error())
+		httputil.WriteError(writer, ErrEncodingGroups)
+	}
+}
+
+func (api Api) GetGroupByIdHandler(writer http.ResponseWriter, request *http.Request) {
+	ctx := request.Context()
+
+	groupID, err := parseID(mux.Vars(request)["id"])
+	if err != nil {
+		httputil.WriteError(writer, err)
 		return
 	}
 
-	writer.WriteHeader(http.StatusOK)
+	group, _, err := api.findMemberGroup(ctx, groupID)
+	if err != nil {
+		httputil.WriteError(writer, err)
+		return
+	}
+
+	api.writeGroup(writer, request, http.StatusOK, group)
 }
 
+// DeleteGroupHandler deletes the group and what was shared with it, only the owner can delete a group.
 func (api Api) DeleteGroupHandler(writer http.ResponseWriter, request *http.Request) {
 	ctx := request.Context()
-	params := mux.Vars(request)
-	groupID, err := primitive.ObjectIDFromHex(params["id"])
+
+	groupID, err := parseID(mux.Vars(request)["id"])
 	if err != nil {
-		writer.WriteHeader(http.StatusBadRequest)
-		_, errWrite := writer.Write([]byte("invalid group ID"))
-		if errWrite != nil {
-			fmt.Println(errWrite.Error())
-		}
+		httputil.WriteError(writer, err)
 		return
 	}
 
-	_, err = api.Dal.Delete(ctx, "groups", bson.M{"_id": groupID})
-	if err != nil {
-		writer.WriteHeader(http.StatusInternalServerError)
-		_, errWrite := writer.Write([]byte("error encountered while deleting the group"))
-		if errWrite != nil {
-			fmt.Println(errWrite.Error())
+	// the shares of the group are removed together with it
+	errTransaction := api.Dal.WithTransaction(ctx, func(txCtx context.Context) error {
+		if _, err := api.findGroupWithRole(txCtx, groupID, RoleOwner); err != nil {
+			return err
 		}
+
+		if _, err := api.Dal.Delete(txCtx, dal.CollGroups, authz.OwnedByID(txCtx, authz.OwnerField, groupID)); err != nil {
+			fmt.Println(err.Error())
+			return ErrDeletingGroup
+		}
+
+		if err := shares.Unshare(txCtx, api.Dal, bson.M{shares.GroupIDField: groupID}); err != nil {
+			fmt.Println(err.Error())
+			return ErrDeletingGroup
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		httputil.WriteError(writer, errTransaction)
 		return
 	}
 
 	writer.WriteHeader(http.StatusNoContent)
-	_, _ = writer.Write([]byte("group deleted successfully"))
 }
 
+// UpdateGroupHandler renames the group, the owner and the admins can rename it.
 func (api Api) UpdateGroupHandler(writer http.ResponseWriter, request *http.Request) {
 	ctx := request.Context()
-	id := mux.Vars(request)["id"]
 
-	ObjId, errObjId := primitive.ObjectIDFromHex(id)
-	if errObjId != nil {
+	groupID, err := parseID(mux.Vars(request)["id"])
+	if err != nil {
+		httputil.WriteError(writer, err)
+		return
+	}
 
-		writer.WriteHeader(http.StatusBadRequest)
-		_, errWrite := writer.Write([]byte(fmt.Sprintf("invalid id specified: %s", id)))
-		if errWrite != nil {
-			fmt.Println(errWrite.Error())
-		}
+	var updateRequest GroupUpdateRequest
+	if decodeErr := httputil.DecodeJSON(writer, request, &updateRequest); decodeErr != nil {
+		httputil.WriteError(writer, decodeErr)
 		return
 	}
 
-	var groupRequest GroupRequest
-	if decodeErr := json.NewDecoder(request.Body).
-		Decode(&groupRequest); decodeErr != nil {
-		writer.WriteHeader(http.StatusBadRequest)
-		_, errWrite := writer.Write([]byte(fmt.Sprintf("error while decoding the request: %s", decodeErr.Error())))
-		if errWrite != nil {
+	if _, err := api.findGroupWithRole(ctx, groupID, RoleOwner, RoleAdmin); err != nil {
+		httputil.WriteError(writer, err)
+		return
+	}
+
+	update := bson.M{"$set": bson.M{"name": updateRequest.Name}}
+	result, errUpdating := api.Dal.Update(ctx, dal.CollGroups, bson.M{"_id": groupID}, update)
+	if errUpdating != nil {
+		fmt.Println(errUpdating.Error())
+		httputil.WriteError(writer, ErrUpdatingGroup)
+		return
+	}
+
+	if result.MatchedCount == 0 {
+		httputil.WriteError(writer, ErrGroupNotFound.WithMessagef(ErrGroupNotFoundFmt, groupID.Hex()))
+		return
+	}
+
+	writer.WriteHeader(http.StatusOK)
+}
+
+// AddMemberHandler invites a registered user by email. The owner can add admins and members, admins only members.
+func (api Api) AddMemberHandler(writer http.ResponseWriter, request *http.Request) {
+	ctx := request.Context()
+
+	groupID, err := parseID(mux.Vars(request)["id"])
+	if err != nil {
+		httputil.WriteError(writer, err)
+		return
+	}
+
+	var memberRequest MemberRequest
+	if decodeErr := httputil.DecodeJSON(writer, request, &memberRequest); decodeErr != nil {
+		httputil.WriteError(writer, decodeErr)
+		return
+	}
+
+	group, callerRole, err := api.findMemberGroup(ctx, groupID)
+	if err != nil {
+		httputil.WriteError(writer, err)
+		return
+	}
+	role := memberRequest.RoleOrDefault()
+	if !callerRole.CanManage(role) {
+		httputil.WriteError(writer, ErrRoleForbidden.WithMessagef(ErrRoleForbiddenFmt, callerRole))
+		return
+	}
+
+	invited, err := users.FindByUserNames(ctx, api.Dal, []string{memberRequest.NormalizedEmail()})
+	if err != nil {
+		fmt.Println(err.Error())
+		httputil.WriteError(writer, ErrFindingUsers)
+		return
+	}
+	user, ok := invited[memberRequest.NormalizedEmail()]
+	if !ok {
+		httputil.WriteError(writer, httputil.ErrValidationFailed.WithDetails(httputil.ValidationErrors{
+			{Field: "email", Message: "no user is registered with this email"},
+		}))
+		return
+	}
+	if group.RoleOf(user.ID) != "" {
+		httputil.WriteError(writer, ErrMemberExists)
+		return
+	}
+
+	// the filter keeps a concurrent request from adding the same user twice
+	filter := bson.M{"_id": groupID, authz.OwnerField: bson.M{"$ne": user.ID}, authz.GroupMemberField: bson.M{"$ne": user.ID}}
+	update := bson.M{"$push": bson.M{"members": Member{UserId: user.ID, Role: role}}}
+	result, err := api.Dal.Update(ctx, dal.CollGroups, filter, update)
+	if err != nil {
+		fmt.Println(err.Error())
+		httputil.WriteError(writer, ErrUpdatingGroup)
+		return
+	}
+	if result.MatchedCount == 0 {
+		httputil.WriteError(writer, ErrMemberExists)
+		return
+	}
+
+	group.Members = append(group.Members, Member{UserId: user.ID, Role: role})
+	api.writeGroup(writer, request, http.StatusCreated, group)
+}
+
+// UpdateMemberHandler changes the role of a member, only the owner can promote members to admins and demote admins.
+func (api Api) UpdateMemberHandler(writer http.ResponseWriter, request *http.Request) {
+	ctx := request.Context()
 
-			fmt.Println(errWrite.Error())
+	groupID, memberID, err := parseMemberIDs(mux.Vars(request))
+	if err != nil {
+		httputil.WriteError(writer, err)
+		return
+	}
+
+	var roleRequest RoleRequest
+	if decodeErr := httputil.DecodeJSON(writer, request, &roleRequest); decodeErr != nil {
+		httputil.WriteError(writer, decodeErr)
+		return
+	}
+
+	// the member is pulled and pushed again with the new role, both writes are made together
+	errTransaction := api.Dal.WithTransaction(ctx, func(txCtx context.Context) error {
+		group, callerRole, err := api.findMemberGroup(txCtx, groupID)
+		if err != nil {
+			return err
+		}
+		if err := checkCanManage(group, callerRole, memberID); err != nil {
+			return err
+		}
+		if !callerRole.CanManage(roleRequest.Role) {
+			return ErrRoleForbidden.WithMessagef(ErrRoleForbiddenFmt, callerRole)
 		}
+
+		if err := api.pullMember(txCtx, groupID, memberID); err != nil {
+			return err
+		}
+		update := bson.M{"$push": bson.M{"members": Member{UserId: memberID, Role: roleRequest.Role}}}
+		if _, err := api.Dal.Update(txCtx, dal.CollGroups, bson.M{"_id": groupID}, update); err != nil {
+			fmt.Println(err.Error())
+			return ErrUpdatingGroup
+		}
+		return nil
+	})
+	if errTransaction != nil {
+		httputil.WriteError(writer, errTransaction)
 		return
 	}
 
-	group := groupRequest.ToModel()
+	writer.WriteHeader(http.StatusNoContent)
+}
 
-	group.UserId = ObjId
+// RemoveMemberHandler removes a member. The owner can remove anyone, admins only members.
+// Removing oneself is the same as leaving the group.
+func (api Api) RemoveMemberHandler(writer http.ResponseWriter, request *http.Request) {
+	ctx := request.Context()
 
-	update := bson.M{"$set": group}
+	groupID, memberID, err := parseMemberIDs(mux.Vars(request))
+	if err != nil {
+		httputil.WriteError(writer, err)
+		return
+	}
 
-	filter := bson.M{"_id": id}
+	if err := api.removeMember(ctx, groupID, memberID); err != nil {
+		httputil.WriteError(writer, err)
+		return
+	}
 
-	result, errUpdating := api.Dal.Update(ctx, "groups", filter, update)
-	if errUpdating != nil {
-		writer.WriteHeader(http.StatusInternalServerError)
-		_, _ = writer.Write([]byte("error encountered on updating the group"))
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// LeaveGroupHandler removes the caller from the group. The owner can't leave, the group has to be deleted instead.
+func (api Api) LeaveGroupHandler(writer http.ResponseWriter, request *http.Request) {
+	ctx := request.Context()
+
+	groupID, err := parseID(mux.Vars(request)["id"])
+	if err != nil {
+		httputil.WriteError(writer, err)
+		return
+	}
+
+	if err := api.removeMember(ctx, groupID, authz.UserID(ctx)); err != nil {
+		httputil.WriteError(writer, err)
 		return
 	}
 
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// removeMember removes the member and what the member shared with the group.
+func (api Api) removeMember(ctx context.Context, groupID primitive.ObjectID, memberID primitive.ObjectID) error {
+	return api.Dal.WithTransaction(ctx, func(txCtx context.Context) error {
+		group, callerRole, err := api.findMemberGroup(txCtx, groupID)
+		if err != nil {
+			return err
+		}
+		if memberID == authz.UserID(txCtx) {
+			if callerRole == RoleOwner {
+				return ErrOwnerCannotLeave
+			}
+		} else if err := checkCanManage(group, callerRole, memberID); err != nil {
+			return err
+		}
+
+		if err := api.pullMember(txCtx, groupID, memberID); err != nil {
+			return err
+		}
+
+		filter := bson.M{shares.GroupIDField: groupID, authz.OwnerField: memberID}
+		if err := shares.Unshare(txCtx, api.Dal, filter); err != nil {
+			fmt.Println(err.Error())
+			return ErrUpdatingGroup
+		}
+		return nil
+	})
+}
+
+// checkCanManage checks that the member belongs to the group and that the caller's role can manage the member's role.
+func checkCanManage(group Group, callerRole Role, memberID primitive.ObjectID) error {
+	memberRole := group.RoleOf(memberID)
+	if memberRole == "" {
+		return ErrMemberNotFound.WithMessagef(ErrMemberNotFoundFmt, memberID.Hex())
+	}
+	if !callerRole.CanManage(memberRole) {
+		return ErrRoleForbidden.WithMessagef(ErrRoleForbiddenFmt, callerRole)
+	}
+	return nil
+}
+
+func (api Api) pullMember(ctx context.Context, groupID primitive.ObjectID, memberID primitive.ObjectID) error {
+	update := bson.M{"$pull": bson.M{"members": bson.M{"userId": memberID}}}
+	result, err := api.Dal.Update(ctx, dal.CollGroups, bson.M{"_id": groupID, authz.GroupMemberField: memberID}, update)
+	if err != nil {
+		fmt.Println(err.Error())
+		return ErrUpdatingGroup
+	}
 	if result.MatchedCount == 0 {
-		writer.WriteHeader(http.StatusNotFound)
-		_, _ = writer.Write([]byte(fmt.Sprintf("group with id %s does not exist", id)))
+		return ErrMemberNotFound.WithMessagef(ErrMemberNotFoundFmt, memberID.Hex())
+	}
+	return nil
+}
+
+// findMemberGroup returns the group and the caller's role in it. Groups the caller doesn't belong to are not found.
+func (api Api) findMemberGroup(ctx context.Context, groupID primitive.ObjectID) (Group, Role, error) {
+	filter := bson.M{"$and": bson.A{bson.M{"_id": groupID}, authz.MemberOfGroups(ctx)}}
+	found := make([]Group, 0, 1)
+	if err := api.Dal.Find(ctx, dal.CollGroups, *dal.NewFindArguments().WithFilter(filter).WithLimit(1), &found); err != nil {
+		fmt.Println(err.Error())
+		return Group{}, "", ErrFindingGroups
+	}
+	if len(found) == 0 {
+		return Group{}, "", ErrGroupNotFound.WithMessagef(ErrGroupNotFoundFmt, groupID.Hex())
+	}
+	return found[0], found[0].RoleOf(authz.UserID(ctx)), nil
+}
+
+// findGroupWithRole returns the group if the caller has one of the roles in it, otherwise ErrRoleForbidden.
+func (api Api) findGroupWithRole(ctx context.Context, groupID primitive.ObjectID, roles ...Role) (Group, error) {
+	group, callerRole, err := api.findMemberGroup(ctx, groupID)
+	if err != nil {
+		return Group{}, err
+	}
+	for _, role := range roles {
+		if callerRole == role {
+			return group, nil
+		}
+	}
+	return Group{}, ErrRoleForbidden.WithMessagef(ErrRoleForbiddenFmt, callerRole)
+}
+
+// findEmails maps the user IDs to the email addresses the users registered with.
+func (api Api) findEmails(ctx context.Context, userIDs []primitive.ObjectID) (map[primitive.ObjectID]string, error) {
+	found, err := users.FindByIDs(ctx, api.Dal, userIDs)
+	if err != nil {
+		fmt.Println(err.Error())
+		return nil, ErrFindingUsers
+	}
+
+	emails := make(map[primitive.ObjectID]string, len(found))
+	for id, user := range found {
+		emails[id] = user.UserName
+	}
+	return emails, nil
+}
+
+// writeGroup writes the group with the emails of its owner and members.
+func (api Api) writeGroup(writer http.ResponseWriter, request *http.Request, status int, group Group) {
+	ctx := request.Context()
+	emails, err := api.findEmails(ctx, group.UserIDs())
+	if err != nil {
+		httputil.WriteError(writer, err)
 		return
 	}
 
-	writer.WriteHeader(http.StatusOK)
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	if errEncode := json.NewEncoder(writer).Encode(group.ToDto(authz.UserID(ctx), emails)); errEncode != nil {
+		fmt.Println(errEncode.Error())
+	}
+}
 
+func parseID(id string) (primitive.ObjectID, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return primitive.NilObjectID, httputil.ErrInvalidID.WithMessagef(ErrInvalidIDFmt, id)
+	}
+	return objID, nil
+}
+
+func parseMemberIDs(params map[string]string) (primitive.ObjectID, primitive.ObjectID, error) {
+	groupID, err := parseID(params["id"])
+	if err != nil {
+		return primitive.NilObjectID, primitive.NilObjectID, err
+	}
+	memberID, err := parseID(params["userId"])
+	if err != nil {
+		return primitive.NilObjectID, primitive.NilObjectID, err
+	}
+	return groupID, memberID, nil
 }
@@ -1,14 +1,123 @@
 package groups
 
+import (
+	"fmt"
+	"gifmanager-backend/httputil"
+	"strings"
+)
+
+const (
+	MaxGroupNameLength = 100
+	MaxGroupMembers    = 100
+	MaxEmailLength     = 254
+)
+
+// GroupRequest creates a group, the members are invited by the email addresses they registered with.
 type GroupRequest struct {
-	Name     string   `json:"name"`
-	Contacts []string `json:"contacts"`
+	Name    string          `json:"name"`
+	Members []MemberRequest `json:"members"`
+}
+
+// Validate checks the name and that every member is invited once with a role other than owner.
+func (g GroupRequest) Validate() httputil.ValidationErrors {
+	var errs httputil.ValidationErrors
+	errs.Length("name", g.Name, 1, MaxGroupNameLength)
+
+	if len(g.Members) > MaxGroupMembers {
+		errs.Add("members", "must not have more than %d members", MaxGroupMembers)
+	}
+
+	seen := make(map[string]bool, len(g.Members))
+	for i, member := range g.Members {
+		field := fmt.Sprintf("members[%d]", i)
+		member.validate(&errs, field+".")
+		if email := member.NormalizedEmail(); seen[email] {
+			errs.Add(field+".email", "is a duplicate")
+		} else {
+			seen[email] = true
+		}
+	}
+	return errs
 }
 
 func (g GroupRequest) ToModel() Group {
 	return Group{
+		Name:    g.Name,
+		Members: make([]Member, 0, len(g.Members)),
+	}
+}
+
+// GroupUpdateRequest renames a group, its members are changed through the members endpoints.
+type GroupUpdateRequest struct {
+	Name string `json:"name"`
+}
+
+func (g GroupUpdateRequest) Validate() httputil.ValidationErrors {
+	var errs httputil.ValidationErrors
+	errs.Length("name", g.Name, 1, MaxGroupNameLength)
+	return errs
+}
+
+// MemberRequest invites a user to a group. Without a role the user becomes a member.
+type MemberRequest struct {
+	Email string `json:"email"`
+	Role  Role   `json:"role"`
+}
 
-		Name:     g.Name,
-		Contacts: g.Contacts,
+func (m MemberRequest) Validate() httputil.ValidationErrors {
+	var errs httputil.ValidationErrors
+	m.validate(&errs, "")
+	return errs
+}
+
+func (m MemberRequest) validate(errs *httputil.ValidationErrors, prefix string) {
+	errs.Length(prefix+"email", m.Email, 1, MaxEmailLength)
+	validateRole(errs, prefix+"role", m.Role, true)
+}
+
+// NormalizedEmail is the email the user is looked up by, emails are stored as they were registered.
+func (m MemberRequest) NormalizedEmail() string {
+	return strings.TrimSpace(m.Email)
+}
+
+// RoleOrDefault returns the requested role, RoleMember when none was requested.
+func (m MemberRequest) RoleOrDefault() Role {
+	if m.Role == "" {
+		return RoleMember
+	}
+	return m.Role
+}
+
+// RoleRequest changes the role of a member.
+type RoleRequest struct {
+	Role Role `json:"role"`
+}
+
+func (r RoleRequest) Validate() httputil.ValidationErrors {
+	var errs httputil.ValidationErrors
+	validateRole(&errs, "role", r.Role, false)
+	return errs
+}
+
+// validateRole accepts admin and member, a group has exactly one owner who can't be assigned.
+func validateRole(errs *httputil.ValidationErrors, field string, role Role, optional bool) {
+	if role == RoleAdmin || role == RoleMember || (optional && role == "") {
+		return
 	}
+	errs.Add(field, "must be %s or %s", RoleAdmin, RoleMember)
+}
+
+type GroupDto struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	OwnerID string `json:"ownerId"`
+	// Role is the role of the caller in the group
+	Role    Role        `json:"role"`
+	Members []MemberDto `json:"members"`
+}
+
+type MemberDto struct {
+	UserID string `json:"userId"`
+	Email  string `json:"email"`
+	Role   Role   `json:"role"`
 }
@@ -0,0 +1,36 @@
+package groups
+
+import (
+	"gifmanager-backend/httputil"
+	"net/http"
+)
+
+const (
+	ErrInvalidIDFmt      = "invalid id specified: %s"
+	ErrGroupNotFoundFmt  = "group with id %s does not exist"
+	ErrMemberNotFoundFmt = "user with id %s is not a member of the group"
+	ErrRoleForbiddenFmt  = "the %s of a group can't do this"
+)
+
+const (
+	CodeGroupNotFound    = "group_not_found"
+	CodeMemberNotFound   = "member_not_found"
+	CodeMemberExists     = "member_exists"
+	CodeRoleForbidden    = "role_forbidden"
+	CodeOwnerCannotLeave = "owner_cannot_leave"
+)
+
+var (
+	ErrInsertingGroups = httputil.NewError(http.StatusInternalServerError, httputil.CodeInternal, "error encountered on inserting the groups")
+	ErrEncodingGroups  = httputil.NewError(http.StatusInternalServerError, httputil.CodeInternal, "error encountered on encoding the groups")
+	ErrFindingGroups   = httputil.NewError(http.StatusInternalServerError, httputil.CodeInternal, "error encountered on retrieving the groups")
+	ErrDeletingGroup   = httputil.NewError(http.StatusInternalServerError, httputil.CodeInternal, "error encountered while deleting the group")
+	ErrUpdatingGroup   = httputil.NewError(http.StatusInternalServerError, httputil.CodeInternal, "error encountered on updating the group")
+	ErrFindingUsers    = httputil.NewError(http.StatusInternalServerError, httputil.CodeInternal, "error encountered on retrieving the members")
+
+	ErrGroupNotFound    = httputil.NewError(http.StatusNotFound, CodeGroupNotFound, "group does not exist")
+	ErrMemberNotFound   = httputil.NewError(http.StatusNotFound, CodeMemberNotFound, "the user is not a member of the group")
+	ErrMemberExists     = httputil.NewError(http.StatusConflict, CodeMemberExists, "the user already belongs to the group")
+	ErrRoleForbidden    = httputil.NewError(http.StatusForbidden, CodeRoleForbidden, "your role in the group doesn't allow this")
+	ErrOwnerCannotLeave = httputil.NewError(http.StatusConflict, CodeOwnerCannotLeave, "the owner can't leave the group, delete it instead")
+)
@@ -0,0 +1,504 @@
+package integration_tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"gifmanager-backend/dal"
+	"gifmanager-backend/groups"
+	"gifmanager-backend/httputil"
+	"gifmanager-backend/shares"
+	"gifmanager-backend/users"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// insertUser stores a user registered with the email and removes it after the test
+func insertUser(t *testing.T, email string) users.User {
+	user := users.User{ID: primitive.NewObjectID(), UserName: primitive.NewObjectID().Hex() + email, Password: "hash"}
+	_, errInsert := mongoDal.Insert(context.Background(), dal.CollUsers, []any{user})
+	require.Nil(t, errInsert)
+
+	t.Cleanup(func() {
+		mongoDal.Delete(context.Background(), dal.CollUsers, bson.M{"_id": user.ID})
+	})
+	return user
+}
+
+// insertGroup stores a group owned by ownerID and removes it after the test
+func insertGroup(t *testing.T, ownerID primitive.ObjectID, members ...groups.Member) groups.Group {
+	group := groups.Group{
+		ID:      primitive.NewObjectID(),
+		Name:    t.Name(),
+		UserId:  ownerID,
+		Members: append([]groups.Member{}, members...),
+	}
+	_, errInsert := mongoDal.Insert(context.Background(), dal.CollGroups, []any{group})
+	require.Nil(t, errInsert)
+
+	t.Cleanup(func() {
+		mongoDal.Delete(context.Background(), dal.CollGroups, bson.M{"_id": group.ID})
+	})
+	return group
+}
+
+func findGroup(t *testing.T, groupID primitive.ObjectID) groups.Group {
+	var dbGroup groups.Group
+	require.Nil(t, mongoDal.FindByID(context.Background(), dal.CollGroups, groupID.Hex(), &dbGroup))
+	return dbGroup
+}
+
+func newGroupRequest(method string, userID primitive.ObjectID, groupID primitive.ObjectID, body any) *http.Request {
+	bts, _ := json.Marshal(body)
+	request := httptest.NewRequest(method, "/groups", bytes.NewReader(bts))
+	request = request.WithContext(context.WithValue(context.Background(), "userID", userID))
+	return mux.SetURLVars(request, map[string]string{
+		"id": groupID.Hex(),
+	})
+}
+
+func newMemberRequest(method string, userID primitive.ObjectID, groupID primitive.ObjectID, memberID primitive.ObjectID, body any) *http.Request {
+	request := newGroupRequest(method, userID, groupID, body)
+	return mux.SetURLVars(request, map[string]string{
+		"id":     groupID.Hex(),
+		"userId": memberID.Hex(),
+	})
+}
+
+func decodeValidationErrors(t *testing.T, responseRecorder *httptest.ResponseRecorder) httputil.ValidationErrors {
+	var errorResponse struct {
+		Details httputil.ValidationErrors `json:"details"`
+	}
+	require.Nil(t, json.NewDecoder(responseRecorder.Body).Decode(&errorResponse))
+	return errorResponse.Details
+}
+
+func TestCreateGroupHandler_MembersByEmail_ExpectedCreated(t *testing.T) {
+	// 1.ARRANGE
+	owner := insertUser(t, "owner@example.com")
+	admin := insertUser(t, "admin@example.com")
+	member := insertUser(t, "member@example.com")
+	body := groups.GroupRequest{Name: "friends", Members: []groups.MemberRequest{
+		{Email: admin.UserName, Role: groups.RoleAdmin},
+		{Email: " " + member.UserName},
+	}}
+	request := newGroupRequest(http.MethodPost, owner.ID, primitive.NilObjectID, body)
+	responseRecorder := httptest.NewRecorder()
+	api := groups.NewGroupApi(mongoDal)
+
+	// 2.ACT
+	api.CreateGroupHandler(responseRecorder, request)
+
+	// 3.ASSERT
+	require.Equal(t, http.StatusCreated, responseRecorder.Code)
+
+	var groupDto groups.GroupDto
+	require.Nil(t, json.Unmarshal(responseRecorder.Body.Bytes(), &groupDto))
+	t.Cleanup(func() {
+		mongoDal.Delete(context.Background(), dal.CollGroups, bson.M{"userId": owner.ID})
+	})
+	assert.Equal(t, groups.RoleOwner, groupDto.Role)
+	assert.Equal(t, []groups.MemberDto{
+		{UserID: owner.ID.Hex(), Email: owner.UserName, Role: groups.RoleOwner},
+		{UserID: admin.ID.Hex(), Email: admin.UserName, Role: groups.RoleAdmin},
+		{UserID: member.ID.Hex(), Email: member.UserName, Role: groups.RoleMember},
+	}, groupDto.Members)
+
+	groupID, _ := primitive.ObjectIDFromHex(groupDto.ID)
+	dbGroup := findGroup(t, groupID)
+	assert.Equal(t, owner.ID, dbGroup.UserId)
+	assert.Equal(t, []groups.Member{
+		{UserId: admin.ID, Role: groups.RoleAdmin},
+		{UserId: member.ID, Role: groups.RoleMember},
+	}, dbGroup.Members)
+}
+
+func TestCreateGroupHandler_InvalidMembers_ExpectedUnprocessableEntity(t *testing.T) {
+	// 1.ARRANGE
+	owner := insertUser(t, "owner@example.com")
+	member := insertUser(t, "member@example.com")
+	body := groups.GroupRequest{Name: "friends", Members: []groups.MemberRequest{
+		{Email: member.UserName},
+		{Email: member.UserName},
+		{Email: "nobody@example.com", Role: groups.RoleOwner},
+	}}
+	request := newGroupRequest(http.MethodPost, owner.ID, primitive.NilObjectID, body)
+	responseRecorder := httptest.NewRecorder()
+	api := groups.NewGroupApi(mongoDal)
+
+	// 2.ACT
+	api.CreateGroupHandler(responseRecorder, request)
+
+	// 3.ASSERT
+	require.Equal(t, http.StatusUnprocessableEntity, responseRecorder.Code)
+	assert.Equal(t, httputil.ValidationErrors{
+		{Field: "members[1].email", Message: "is a duplicate"},
+		{Field: "members[2].role", Message: "must be admin or member"},
+	}, decodeValidationErrors(t, responseRecorder))
+}
+
+func TestCreateGroupHandler_UnknownOrOwnEmail_ExpectedUnprocessableEntity(t *testing.T) {
+	// 1.ARRANGE
+	owner := insertUser(t, "owner@example.com")
+	body := groups.GroupRequest{Name: "friends", Members: []groups.MemberRequest{
+		{Email: "nobody@example.com"},
+		{Email: owner.UserName},
+	}}
+	request := newGroupRequest(http.MethodPost, owner.ID, primitive.NilObjectID, body)
+	responseRecorder := httptest.NewRecorder()
+	api := groups.NewGroupApi(mongoDal)
+
+	// 2.ACT
+	api.CreateGroupHandler(responseRecorder, request)
+
+	// 3.ASSERT
+	require.Equal(t, http.StatusUnprocessableEntity, responseRecorder.Code)
+	assert.Equal(t, httputil.ValidationErrors{
+		{Field: "members[0].email", Message: "no user is registered with this email"},
+		{Field: "members[1].email", Message: "is the owner of the group"},
+	}, decodeValidationErrors(t, responseRecorder))
+
+	count, err := mongoDal.Count(context.Background(), dal.CollGroups, bson.M{"userId": owner.ID})
+	require.Nil(t, err)
+	assert.Zero(t, count)
+}
+
+func TestGetGroupHandler_ExpectedOnlyGroupsOfTheCaller(t *testing.T) {
+	// 1.ARRANGE
+	callerID := primitive.NewObjectID()
+	owned := insertGroup(t, callerID)
+	joined := insertGroup(t, primitive.NewObjectID(), groups.Member{UserId: callerID, Role: groups.RoleAdmin})
+	insertGroup(t, primitive.NewObjectID(), groups.Member{UserId: primitive.NewObjectID(), Role: groups.RoleMember})
+
+	request := newGroupRequest(http.MethodGet, callerID, primitive.NilObjectID, nil)
+	responseRecorder := httptest.NewRecorder()
+	api := groups.NewGroupApi(mongoDal)
+
+	// 2.ACT
+	api.GetGroupHandler(responseRecorder, request)
+
+	// 3.ASSERT
+	require.Equal(t, http.StatusOK, responseRecorder.Code)
+
+	var groupDtos []groups.GroupDto
+	require.Nil(t, json.Unmarshal(responseRecorder.Body.Bytes(), &groupDtos))
+	roles := make(map[string]groups.Role)
+	for _, groupDto := range groupDtos {
+		roles[groupDto.ID] = groupDto.Role
+	}
+	assert.Equal(t, map[string]groups.Role{
+		owned.ID.Hex():  groups.RoleOwner,
+		joined.ID.Hex(): groups.RoleAdmin,
+	}, roles)
+}
+
+func TestGetGroupByIdHandler(t *testing.T) {
+	memberID := primitive.NewObjectID()
+	group := insertGroup(t, primitive.NewObjectID(), groups.Member{UserId: memberID, Role: groups.RoleMember})
+
+	tests := []struct {
+		name           string
+		userID         primitive.ObjectID
+		expectedStatus int
+	}{
+		{"member", memberID, http.StatusOK},
+		{"outsider", primitive.NewObjectID(), http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// 1.ARRANGE
+			responseRecorder := httptest.NewRecorder()
+			api := groups.NewGroupApi(mongoDal)
+
+			// 2.ACT
+			api.GetGroupByIdHandler(responseRecorder, newGroupRequest(http.MethodGet, test.userID, group.ID, nil))
+
+			// 3.ASSERT
+			assert.Equal(t, test.expectedStatus, responseRecorder.Code)
+		})
+	}
+}
+
+func TestUpdateGroupHandler_ExpectedOk(t *testing.T) {
+	// 1.ARRANGE
+	ownerID := primitive.NewObjectID()
+	adminID := primitive.NewObjectID()
+	group := insertGroup(t, ownerID, groups.Member{UserId: adminID, Role: groups.RoleAdmin})
+	request := newGroupRequest(http.MethodPut, adminID, group.ID, groups.GroupUpdateRequest{Name: "renamed"})
+	responseRecorder := httptest.NewRecorder()
+	api := groups.NewGroupApi(mongoDal)
+
+	// 2.ACT
+	api.UpdateGroupHandler(responseRecorder, request)
+
+	// 3.ASSERT
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+
+	// the group keeps its owner and members, only the name changes
+	dbGroup := findGroup(t, group.ID)
+	assert.Equal(t, "renamed", dbGroup.Name)
+	assert.Equal(t, ownerID, dbGroup.UserId)
+	assert.Equal(t, group.Members, dbGroup.Members)
+}
+
+func TestUpdateGroupHandler_MemberOrOutsider_ExpectedGroupUnchanged(t *testing.T) {
+	memberID := primitive.NewObjectID()
+	group := insertGroup(t, primitive.NewObjectID(), groups.Member{UserId: memberID, Role: groups.RoleMember})
+
+	tests := []struct {
+		name           string
+		userID         primitive.ObjectID
+		expectedStatus int
+	}{
+		{"member", memberID, http.StatusForbidden},
+		{"outsider", primitive.NewObjectID(), http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// 1.ARRANGE
+			request := newGroupRequest(http.MethodPut, test.userID, group.ID, groups.GroupUpdateRequest{Name: "overwritten"})
+			responseRecorder := httptest.NewRecorder()
+			api := groups.NewGroupApi(mongoDal)
+
+			// 2.ACT
+			api.UpdateGroupHandler(responseRecorder, request)
+
+			// 3.ASSERT
+			assert.Equal(t, test.expectedStatus, responseRecorder.Code)
+			assert.Equal(t, group, findGroup(t, group.ID))
+		})
+	}
+}
+
+func TestDeleteGroupHandler_AdminOrOutsider_ExpectedGroupKept(t *testing.T) {
+	adminID := primitive.NewObjectID()
+	group := insertGroup(t, primitive.NewObjectID(), groups.Member{UserId: adminID, Role: groups.RoleAdmin})
+
+	tests := []struct {
+		name           string
+		userID         primitive.ObjectID
+		expectedStatus int
+	}{
+		{"admin", adminID, http.StatusForbidden},
+		{"outsider", primitive.NewObjectID(), http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// 1.ARRANGE
+			responseRecorder := httptest.NewRecorder()
+			api := groups.NewGroupApi(mongoDal)
+
+			// 2.ACT
+			api.DeleteGroupHandler(responseRecorder, newGroupRequest(http.MethodDelete, test.userID, group.ID, nil))
+
+			// 3.ASSERT
+			assert.Equal(t, test.expectedStatus, responseRecorder.Code)
+			findGroup(t, group.ID)
+		})
+	}
+}
+
+func TestDeleteGroupHandler_ExpectedNoContent(t *testing.T) {
+	// 1.ARRANGE
+	ownerID := primitive.NewObjectID()
+	group := insertGroup(t, ownerID)
+	share := shares.Share{ID: primitive.NewObjectID(), UserId: ownerID, GroupId: group.ID, GifId: primitive.NewObjectID()}
+	_, errInsert := mongoDal.Insert(context.Background(), dal.CollShares, []any{share})
+	require.Nil(t, errInsert)
+	request := newGroupRequest(http.MethodDelete, ownerID, group.ID, nil)
+	responseRecorder := httptest.NewRecorder()
+	api := groups.NewGroupApi(mongoDal)
+
+	// 2.ACT
+	api.DeleteGroupHandler(responseRecorder, request)
+
+	// 3.ASSERT
+	assert.Equal(t, http.StatusNoContent, responseRecorder.Code)
+
+	var dbGroup groups.Group
+	require.NotNil(t, mongoDal.FindByID(context.Background(), dal.CollGroups, group.ID.Hex(), &dbGroup))
+
+	// the shares of the group are deleted with it
+	count, err := mongoDal.Count(context.Background(), dal.CollShares, bson.M{"_id": share.ID})
+	require.Nil(t, err)
+	assert.Zero(t, count)
+}
+
+func TestAddMemberHandler(t *testing.T) {
+	ownerID := primitive.NewObjectID()
+	adminID := primitive.NewObjectID()
+	memberID := primitive.NewObjectID()
+	group := insertGroup(t, ownerID,
+		groups.Member{UserId: adminID, Role: groups.RoleAdmin},
+		groups.Member{UserId: memberID, Role: groups.RoleMember},
+	)
+	invited := insertUser(t, "invited@example.com")
+
+	tests := []struct {
+		name           string
+		userID         primitive.ObjectID
+		body           groups.MemberRequest
+		expectedStatus int
+		expectedCode   string
+	}{
+		{"member can't invite", memberID, groups.MemberRequest{Email: invited.UserName}, http.StatusForbidden, groups.CodeRoleForbidden},
+		{"admin can't add admins", adminID, groups.MemberRequest{Email: invited.UserName, Role: groups.RoleAdmin}, http.StatusForbidden, groups.CodeRoleForbidden},
+		{"unknown email", ownerID, groups.MemberRequest{Email: "nobody@example.com"}, http.StatusUnprocessableEntity, httputil.CodeValidationFailed},
+		{"admin adds a member", adminID, groups.MemberRequest{Email: invited.UserName}, http.StatusCreated, ""},
+		{"already a member", ownerID, groups.MemberRequest{Email: invited.UserName}, http.StatusConflict, groups.CodeMemberExists},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// 1.ARRANGE
+			responseRecorder := httptest.NewRecorder()
+			api := groups.NewGroupApi(mongoDal)
+
+			// 2.ACT
+			api.AddMemberHandler(responseRecorder, newGroupRequest(http.MethodPost, test.userID, group.ID, test.body))
+
+			// 3.ASSERT
+			require.Equal(t, test.expectedStatus, responseRecorder.Code)
+			if test.expectedCode != "" {
+				var errorResponse httputil.ErrorResponse
+				require.Nil(t, json.NewDecoder(responseRecorder.Body).Decode(&errorResponse))
+				assert.Equal(t, test.expectedCode, errorResponse.Code)
+			}
+		})
+	}
+
+	assert.Equal(t, groups.RoleMember, findGroup(t, group.ID).RoleOf(invited.ID))
+}
+
+func TestUpdateMemberHandler(t *testing.T) {
+	ownerID := primitive.NewObjectID()
+	adminID := primitive.NewObjectID()
+	memberID := primitive.NewObjectID()
+	group := insertGroup(t, ownerID,
+		groups.Member{UserId: adminID, Role: groups.RoleAdmin},
+		groups.Member{UserId: memberID, Role: groups.RoleMember},
+	)
+
+	tests := []struct {
+		name           string
+		userID         primitive.ObjectID
+		memberID       primitive.ObjectID
+		role           groups.Role
+		expectedStatus int
+	}{
+		{"admin can't promote", adminID, memberID, groups.RoleAdmin, http.StatusForbidden},
+		{"admin can't demote admins", adminID, adminID, groups.RoleMember, http.StatusForbidden},
+		{"nobody becomes owner", ownerID, memberID, groups.RoleOwner, http.StatusUnprocessableEntity},
+		{"not a member", ownerID, primitive.NewObjectID(), groups.RoleAdmin, http.StatusNotFound},
+		{"owner promotes", ownerID, memberID, groups.RoleAdmin, http.StatusNoContent},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// 1.ARRANGE
+			request := newMemberRequest(http.MethodPut, test.userID, group.ID, test.memberID, groups.RoleRequest{Role: test.role})
+			responseRecorder := httptest.NewRecorder()
+			api := groups.NewGroupApi(mongoDal)
+
+			// 2.ACT
+			api.UpdateMemberHandler(responseRecorder, request)
+
+			// 3.ASSERT
+			assert.Equal(t, test.expectedStatus, responseRecorder.Code)
+		})
+	}
+
+	dbGroup := findGroup(t, group.ID)
+	assert.Equal(t, groups.RoleAdmin, dbGroup.RoleOf(memberID))
+	assert.Equal(t, groups.RoleAdmin, dbGroup.RoleOf(adminID))
+	assert.Len(t, dbGroup.Members, 2)
+}
+
+func TestRemoveMemberHandler(t *testing.T) {
+	ownerID := primitive.NewObjectID()
+	adminID := primitive.NewObjectID()
+	memberID := primitive.NewObjectID()
+	group := insertGroup(t, ownerID,
+		groups.Member{UserId: adminID, Role: groups.RoleAdmin},
+		groups.Member{UserId: memberID, Role: groups.RoleMember},
+	)
+	memberShare := shares.Share{ID: primitive.NewObjectID(), UserId: memberID, GroupId: group.ID, GifId: primitive.NewObjectID()}
+	_, errInsert := mongoDal.Insert(context.Background(), dal.CollShares, []any{memberShare})
+	require.Nil(t, errInsert)
+
+	tests := []struct {
+		name           string
+		userID         primitive.ObjectID
+		memberID       primitive.ObjectID
+		expectedStatus int
+	}{
+		{"member can't remove others", memberID, adminID, http.StatusForbidden},
+		{"admin can't remove the owner", adminID, ownerID, http.StatusForbidden},
+		{"admin removes a member", adminID, memberID, http.StatusNoContent},
+		{"removed member is not found", ownerID, memberID, http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// 1.ARRANGE
+			request := newMemberRequest(http.MethodDelete, test.userID, group.ID, test.memberID, nil)
+			responseRecorder := httptest.NewRecorder()
+			api := groups.NewGroupApi(mongoDal)
+
+			// 2.ACT
+			api.RemoveMemberHandler(responseRecorder, request)
+
+			// 3.ASSERT
+			assert.Equal(t, test.expectedStatus, responseRecorder.Code)
+		})
+	}
+
+	// what the member shared with the group is removed with the member
+	assert.Equal(t, []groups.Member{{UserId: adminID, Role: groups.RoleAdmin}}, findGroup(t, group.ID).Members)
+	count, err := mongoDal.Count(context.Background(), dal.CollShares, bson.M{"_id": memberShare.ID})
+	require.Nil(t, err)
+	assert.Zero(t, count)
+}
+
+func TestLeaveGroupHandler(t *testing.T) {
+	ownerID := primitive.NewObjectID()
+	memberID := primitive.NewObjectID()
+	group := insertGroup(t, ownerID, groups.Member{UserId: memberID, Role: groups.RoleMember})
+
+	tests := []struct {
+		name           string
+		userID         primitive.ObjectID
+		expectedStatus int
+	}{
+		{"owner can't leave", ownerID, http.StatusConflict},
+		{"member leaves", memberID, http.StatusNoContent},
+		{"former member", memberID, http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// 1.ARRANGE
+			responseRecorder := httptest.NewRecorder()
+			api := groups.NewGroupApi(mongoDal)
+
+			// 2.ACT
+			api.LeaveGroupHandler(responseRecorder, newGroupRequest(http.MethodPost, test.userID, group.ID, nil))
+
+			// 3.ASSERT
+			assert.Equal(t, test.expectedStatus, responseRecorder.Code)
+		})
+	}
+
+	dbGroup := findGroup(t, group.ID)
+	assert.Equal(t, ownerID, dbGroup.UserId)
+	assert.Empty(t, dbGroup.Members)
+}
@@ -2,10 +2,77 @@ package groups
 
 import "go.mongodb.org/mongo-driver/bson/primitive"
 
+// Role is what a user may do in a group. The owner created the group and is the only one who can delete it,
+// admins can rename it and add or remove members, members can see what is shared with the group and leave it.
+type Role string
+
+const (
+	RoleOwner  Role = "owner"
+	RoleAdmin  Role = "admin"
+	RoleMember Role = "member"
+)
+
+// CanManage reports whether a user with the role can add, remove or assign users with the other role.
+func (r Role) CanManage(other Role) bool {
+	switch r {
+	case RoleOwner:
+		return other != RoleOwner
+	case RoleAdmin:
+		return other == RoleMember
+	}
+	return false
+}
+
 type Group struct {
-	ID     primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
-	Name   string             `bson:"name" json:"name"`
-	UserId primitive.ObjectID `bson:"user_id" json:"user_id"`
-	// store the Users IDs
-	Contacts []string `bson:"contacts" json:"contacts"`
+	ID     primitive.ObjectID `bson:"_id,omitempty"`
+	Name   string             `bson:"name"`
+	UserId primitive.ObjectID `bson:"userId"`
+	// Members are the users added to the group, the owner isn't one of them
+	Members []Member `bson:"members"`
+}
+
+type Member struct {
+	UserId primitive.ObjectID `bson:"userId"`
+	Role   Role               `bson:"role"`
+}
+
+// RoleOf returns the role of the user in the group, or "" when the user doesn't belong to it.
+func (g Group) RoleOf(userID primitive.ObjectID) Role {
+	if g.UserId == userID {
+		return RoleOwner
+	}
+	for _, member := range g.Members {
+		if member.UserId == userID {
+			return member.Role
+		}
+	}
+	return ""
+}
+
+// UserIDs returns the IDs of the owner and the members.
+func (g Group) UserIDs() []primitive.ObjectID {
+	ids := make([]primitive.ObjectID, 0, len(g.Members)+1)
+	ids = append(ids, g.UserId)
+	for _, member := range g.Members {
+		ids = append(ids, member.UserId)
+	}
+	return ids
+}
+
+// ToDto lists the owner first and then the members. emails maps the user IDs to the users' email addresses,
+// users that were deleted have an empty email.
+func (g Group) ToDto(callerID primitive.ObjectID, emails map[primitive.ObjectID]string) GroupDto {
+	members := make([]MemberDto, 0, len(g.Members)+1)
+	members = append(members, MemberDto{UserID: g.UserId.Hex(), Email: emails[g.UserId], Role: RoleOwner})
+	for _, member := range g.Members {
+		members = append(members, MemberDto{UserID: member.UserId.Hex(), Email: emails[member.UserId], Role: member.Role})
+	}
+
+	return GroupDto{
+		ID:      g.ID.Hex(),
+		Name:    g.Name,
+		OwnerID: g.UserId.Hex(),
+		Role:    g.RoleOf(callerID),
+		Members: members,
+	}
 }
@@ -1,44 +1,94 @@
 package httputil
 
 import (
-	"fmt"
+	"gifmanager-backend/dal"
 	"go.mongodb.org/mongo-driver/bson"
 	"net/url"
+	"strconv"
 	"strings"
 )
 
-type handleFieldFilterFunc func(fieldName, operator, value string, filter bson.M) error
-
+// baseQueryParamsParser parses the query params every list endpoint supports, the schema and the sort fields
+// are those of the listed documents.
 type baseQueryParamsParser struct {
-	values url.Values
+	filterSchema FilterSchema
+	sortFields   map[string]string
 }
 
-func (b *baseQueryParamsParser) LoadValues(values url.Values) {
-	b.values = values
+func (b baseQueryParamsParser) Parse(values url.Values) (ParsedQuery, error) {
+	filter, err := parseFilter(values, b.filterSchema)
+	if err != nil {
+		return ParsedQuery{}, err
+	}
+	pagination, err := ParsePagination(values)
+	if err != nil {
+		return ParsedQuery{}, err
+	}
+	sorts, err := parseSorts(values, b.sortFields)
+	if err != nil {
+		return ParsedQuery{}, err
+	}
+	return ParsedQuery{Filter: filter, Pagination: pagination, Sorts: sorts}, nil
 }
 
-func (b baseQueryParamsParser) HasFilter() bool {
-	skip := b.values.Get("filter")
-	return len(skip) > 0
+// parseFilter parses the filter query param with ParseFilter and compiles it to a mongo filter
+// that only uses the fields and operators declared in the schema. Without the param the filter is nil.
+func parseFilter(values url.Values, schema FilterSchema) (bson.M, error) {
+	filter := values.Get("filter")
+	if filter == "" {
+		return nil, nil
+	}
+	expression, err := ParseFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	return CompileFilter(expression, schema)
 }
 
-func (b baseQueryParamsParser) GetFilter(handleFieldFilter handleFieldFilterFunc) (interface{}, error) {
-	filterParam := b.values.Get("filter")
-	filters := strings.Split(filterParam, ";")
+// ParsePagination parses the page and pageSize query params, for the endpoints that are paged but not filtered or sorted.
+func ParsePagination(values url.Values) (Pagination, error) {
+	pagination := Pagination{Page: 1, PageSize: DefaultPageSize}
 
-	bsonFilter := make(bson.M, 0)
-	for _, filter := range filters {
-		filterParts := strings.Split(filter, "-")
-		if len(filterParts) != 3 {
-			return nil, fmt.Errorf("invalid filter format: %s", filter)
+	if page := values.Get("page"); page != "" {
+		pageNumber, err := strconv.Atoi(page)
+		if err != nil || pageNumber < 1 || pageNumber > MaxPage {
+			return Pagination{}, ErrInvalidQuery.WithMessagef("invalid page: %s, it should be a number between 1 and %d", page, MaxPage)
 		}
-		fieldName := filterParts[0]
-		operator := filterParts[1]
-		value := filterParts[2]
+		pagination.Page = pageNumber
+	}
 
-		if err := handleFieldFilter(fieldName, operator, value, bsonFilter); err != nil {
-			return nil, err
+	if pageSize := values.Get("pageSize"); pageSize != "" {
+		size, err := strconv.Atoi(pageSize)
+		if err != nil || size < 1 || size > MaxPageSize {
+			return Pagination{}, ErrInvalidQuery.WithMessagef("invalid pageSize: %s, it should be a number between 1 and %d", pageSize, MaxPageSize)
 		}
+		pagination.PageSize = size
+	}
+
+	return pagination, nil
+}
+
+// parseSorts parses the comma separated sort query param, e.g. sort=-isFavourite,name.
+// A leading "-" sorts descending. sortFields maps the allowed query param names to the document fields.
+// The sorts always end with the _id, so the order is stable between pages.
+func parseSorts(values url.Values, sortFields map[string]string) (dal.Sorts, error) {
+	sorts := make(dal.Sorts, 0)
+	sortsID := false
+
+	if sortParam := values.Get("sort"); sortParam != "" {
+		for _, field := range strings.Split(sortParam, ",") {
+			fieldName, descending := strings.CutPrefix(field, "-")
+			documentField, ok := sortFields[fieldName]
+			if !ok {
+				return nil, ErrInvalidQuery.WithMessagef("invalid sort field: %s", fieldName)
+			}
+			sorts = append(sorts, dal.Sort{FieldName: documentField, Ascending: !descending})
+			sortsID = sortsID || documentField == "_id"
+		}
+	}
+
+	if !sortsID {
+		sorts = append(sorts, dal.Sort{FieldName: "_id", Ascending: true})
 	}
-	return bsonFilter, nil
+	return sorts, nil
 }
@@ -0,0 +1,58 @@
+package httputil
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// MaxBodyBytes is the largest request body DecodeJSON accepts.
+const MaxBodyBytes = 1 << 20
+
+const CodeBodyTooLarge = "body_too_large"
+
+var ErrBodyTooLarge = NewError(http.StatusRequestEntityTooLarge, CodeBodyTooLarge, fmt.Sprintf("the request body must not be larger than %d bytes", MaxBodyBytes))
+
+// DecodeJSON decodes the request body into target and validates it when it implements Validator.
+// Unknown fields, trailing data and bodies larger than MaxBodyBytes are rejected.
+// The returned errors are *Error values that can be written with WriteError.
+func DecodeJSON(writer http.ResponseWriter, request *http.Request, target any) error {
+	return decodeAndValidate(http.MaxBytesReader(writer, request.Body, MaxBodyBytes), target)
+}
+
+func decodeAndValidate(reader io.Reader, target any) error {
+	decoder := json.NewDecoder(reader)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(target); err != nil {
+		return decodeError(err)
+	}
+	if err := decoder.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		if err != nil {
+			return decodeError(err)
+		}
+		return ErrInvalidJSON.WithMessagef("the request body must contain a single JSON value")
+	}
+
+	validator, ok := target.(Validator)
+	if !ok {
+		return nil
+	}
+	if fieldErrors := validator.Validate(); len(fieldErrors) > 0 {
+		return ErrValidationFailed.WithDetails(fieldErrors)
+	}
+	return nil
+}
+
+func decodeError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return ErrBodyTooLarge
+	}
+	if errors.Is(err, io.EOF) {
+		return ErrInvalidJSON.WithMessagef("the request body is empty")
+	}
+	return ErrInvalidJSON.WithMessagef("error while decoding the request: %s", err.Error())
+}
@@ -0,0 +1,100 @@
+package httputil_test
+
+import (
+	"encoding/json"
+	"errors"
+	"gifmanager-backend/httputil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type testRequest struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+func (r testRequest) Validate() httputil.ValidationErrors {
+	var errs httputil.ValidationErrors
+	errs.Length("name", r.Name, 1, 5)
+	errs.URL("url", r.URL, 100)
+	return errs
+}
+
+func decode(body string) (testRequest, error) {
+	request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	var target testRequest
+	err := httputil.DecodeJSON(httptest.NewRecorder(), request, &target)
+	return target, err
+}
+
+func TestDecodeJSON_ValidRequest(t *testing.T) {
+	// 1. ACT
+	target, err := decode(`{"name": "cat", "url": "https://media.giphy.com/cat.gif"}`)
+
+	// 2. ASSERT
+	require.Nil(t, err)
+	assert.Equal(t, testRequest{Name: "cat", URL: "https://media.giphy.com/cat.gif"}, target)
+}
+
+func TestDecodeJSON_ReportsAllFieldErrors(t *testing.T) {
+	// 1. ACT
+	_, err := decode(`{"name": "   ", "url": "javascript:alert(1)"}`)
+
+	// 2. ASSERT
+	var httpErr *httputil.Error
+	require.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, http.StatusUnprocessableEntity, httpErr.Status)
+	assert.Equal(t, httputil.CodeValidationFailed, httpErr.Code)
+	assert.Equal(t, httputil.ValidationErrors{
+		{Field: "name", Message: "is required"},
+		{Field: "url", Message: "must be an http or https URL"},
+	}, httpErr.Details)
+}
+
+func TestDecodeJSON_RejectsMalformedBodies(t *testing.T) {
+	testCases := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{"empty body", ``, http.StatusBadRequest},
+		{"syntax error", `{"name": "cat"`, http.StatusBadRequest},
+		{"unknown field", `{"name": "cat", "url": "https://giphy.com", "userId": "abc"}`, http.StatusBadRequest},
+		{"trailing data", `{"name": "cat", "url": "https://giphy.com"} {}`, http.StatusBadRequest},
+		{"too large", `{"name": "` + strings.Repeat("a", httputil.MaxBodyBytes) + `"}`, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			// 1. ACT
+			_, err := decode(testCase.body)
+
+			// 2. ASSERT
+			var httpErr *httputil.Error
+			require.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, testCase.expectedStatus, httpErr.Status)
+		})
+	}
+}
+
+func TestWriteError_ValidationErrorsAsDetails(t *testing.T) {
+	// 1. ARRANGE
+	_, err := decode(`{"name": "too long", "url": "https://giphy.com"}`)
+	recorder := httptest.NewRecorder()
+
+	// 2. ACT
+	httputil.WriteError(recorder, err)
+
+	// 3. ASSERT
+	assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	assert.JSONEq(t,
+		`{"code": "validation_failed", "message": "the request is invalid", "details": [{"field": "name", "message": "must be at most 5 characters long"}]}`,
+		recorder.Body.String())
+
+	var response httputil.ErrorResponse
+	require.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+}
@@ -0,0 +1,150 @@
+package httputil
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const CodePreconditionFailed = "precondition_failed"
+
+var ErrPreconditionFailed = NewError(http.StatusPreconditionFailed, CodePreconditionFailed, "the resource was changed since it was read, reload it and try again")
+
+// VersionETag returns the ETag of a version of a document, documents start with version 1 and every write increments it.
+func VersionETag(version int) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// IfMatch is the If-Match precondition of a request. Without the header every version matches.
+type IfMatch struct {
+	present  bool
+	any      bool
+	versions []int
+}
+
+// ParseIfMatch reads the If-Match header. If-Match uses the strong comparison, so weak and malformed ETags never match.
+func ParseIfMatch(request *http.Request) IfMatch {
+	header := request.Header.Get("If-Match")
+	if header == "" {
+		return IfMatch{}
+	}
+
+	ifMatch := IfMatch{present: true, versions: make([]int, 0)}
+	for _, tag := range splitETags(header) {
+		if tag == "*" {
+			ifMatch.any = true
+			continue
+		}
+		if !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) || len(tag) < 2 {
+			continue
+		}
+		if version, err := strconv.Atoi(tag[1 : len(tag)-1]); err == nil {
+			ifMatch.versions = append(ifMatch.versions, version)
+		}
+	}
+	return ifMatch
+}
+
+func (m IfMatch) Matches(version int) bool {
+	if !m.present || m.any {
+		return true
+	}
+	for _, expected := range m.versions {
+		if expected == version {
+			return true
+		}
+	}
+	return false
+}
+
+// AddToFilter returns a copy of the filter that only matches the versions in the precondition, so the version is checked
+// by the same write that changes the document. The documents written before versions were added have no version field
+// and match version 0.
+func (m IfMatch) AddToFilter(filter bson.M, field string) bson.M {
+	if !m.present || m.any {
+		return filter
+	}
+
+	scoped := bson.M{}
+	for key, value := range filter {
+		scoped[key] = value
+	}
+
+	versions := bson.A{}
+	for _, version := range m.versions {
+		versions = append(versions, version)
+		if version == 0 {
+			versions = append(versions, nil)
+		}
+	}
+	scoped[field] = bson.M{"$in": versions}
+	return scoped
+}
+
+// WriteJSONWithETag writes the value with an ETag computed from its encoding. When the request's If-None-Match
+// contains the ETag, only 304 Not Modified is written.
+func WriteJSONWithETag(writer http.ResponseWriter, request *http.Request, value any) error {
+	body, err := encodeJSON(value)
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(body)
+	return writeJSON(writer, request, `"`+hex.EncodeToString(sum[:16])+`"`, body)
+}
+
+// WriteVersionedJSON writes a single document with the ETag of its version, so the ETag can be sent back as If-Match.
+// When the request's If-None-Match contains the ETag, only 304 Not Modified is written.
+func WriteVersionedJSON(writer http.ResponseWriter, request *http.Request, version int, value any) error {
+	body, err := encodeJSON(value)
+	if err != nil {
+		return err
+	}
+	return writeJSON(writer, request, VersionETag(version), body)
+}
+
+func encodeJSON(value any) ([]byte, error) {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(value); err != nil {
+		return nil, err
+	}
+	return body.Bytes(), nil
+}
+
+func writeJSON(writer http.ResponseWriter, request *http.Request, etag string, body []byte) error {
+	writer.Header().Set("ETag", etag)
+	if ifNoneMatch(request.Header.Get("If-None-Match"), etag) {
+		writer.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	_, err := writer.Write(body)
+	return err
+}
+
+// ifNoneMatch uses the weak comparison, as required for If-None-Match.
+func ifNoneMatch(header, etag string) bool {
+	for _, tag := range splitETags(header) {
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func splitETags(header string) []string {
+	tags := make([]string, 0)
+	for _, tag := range strings.Split(header, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,67 @@
+package httputil_test
+
+import (
+	"gifmanager-backend/httputil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	testCases := []struct {
+		header          string
+		version         int
+		expectedMatches bool
+		expectedFilter  bson.M
+	}{
+		{header: "", version: 3, expectedMatches: true, expectedFilter: bson.M{"_id": 1}},
+		{header: "*", version: 3, expectedMatches: true, expectedFilter: bson.M{"_id": 1}},
+		{header: `"3"`, version: 3, expectedMatches: true, expectedFilter: bson.M{"_id": 1, "version": bson.M{"$in": bson.A{3}}}},
+		{header: `"2", "3"`, version: 3, expectedMatches: true, expectedFilter: bson.M{"_id": 1, "version": bson.M{"$in": bson.A{2, 3}}}},
+		{header: `"2"`, version: 3, expectedMatches: false, expectedFilter: bson.M{"_id": 1, "version": bson.M{"$in": bson.A{2}}}},
+		{header: `W/"3"`, version: 3, expectedMatches: false, expectedFilter: bson.M{"_id": 1, "version": bson.M{"$in": bson.A{}}}},
+		{header: `"0"`, version: 0, expectedMatches: true, expectedFilter: bson.M{"_id": 1, "version": bson.M{"$in": bson.A{0, nil}}}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.header, func(t *testing.T) {
+			// 1. ARRANGE
+			request := httptest.NewRequest(http.MethodPut, "/", nil)
+			request.Header.Set("If-Match", testCase.header)
+
+			// 2. ACT
+			ifMatch := httputil.ParseIfMatch(request)
+
+			// 3. ASSERT
+			assert.Equal(t, testCase.expectedMatches, ifMatch.Matches(testCase.version))
+			assert.Equal(t, testCase.expectedFilter, ifMatch.AddToFilter(bson.M{"_id": 1}, "version"))
+		})
+	}
+}
+
+func TestWriteJSONWithETag_IfNoneMatch_ReturnsNotModified(t *testing.T) {
+	// 1. ARRANGE
+	value := []string{"cat", "dog"}
+	first := httptest.NewRecorder()
+	require.Nil(t, httputil.WriteJSONWithETag(first, httptest.NewRequest(http.MethodGet, "/", nil), value))
+	etag := first.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set("If-None-Match", `"other", W/`+etag)
+	second := httptest.NewRecorder()
+
+	// 2. ACT
+	err := httputil.WriteJSONWithETag(second, request, value)
+
+	// 3. ASSERT
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.JSONEq(t, `["cat", "dog"]`, first.Body.String())
+	assert.Equal(t, http.StatusNotModified, second.Code)
+	assert.Empty(t, second.Body.String())
+	assert.Equal(t, etag, second.Header().Get("ETag"))
+}
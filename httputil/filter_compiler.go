@@ -0,0 +1,102 @@
+package httputil
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"regexp"
+)
+
+var filterMongoOperators = map[string]string{
+	OpEqual:          "$eq",
+	OpNotEqual:       "$ne",
+	OpGreater:        "$gt",
+	OpGreaterOrEqual: "$gte",
+	OpLess:           "$lt",
+	OpLessOrEqual:    "$lte",
+	OpIn:             "$in",
+	OpNotIn:          "$nin",
+	OpAll:            "$all",
+}
+
+// CompileFilter converts the parsed filter to a mongo filter. Only the fields and operators declared in the schema
+// are accepted, and the values are converted to the types of their fields.
+func CompileFilter(expression FilterExpression, schema FilterSchema) (bson.M, error) {
+	switch e := expression.(type) {
+	case FilterAnd:
+		return compileLogical("$and", e.Expressions, schema)
+	case FilterOr:
+		return compileLogical("$or", e.Expressions, schema)
+	case FilterComparison:
+		return compileComparison(e, schema)
+	case FilterIn:
+		return compileIn(e, schema)
+	}
+	return nil, newFilterError(expression.Position(), "unsupported expression")
+}
+
+func compileLogical(operator string, expressions []FilterExpression, schema FilterSchema) (bson.M, error) {
+	compiled := make(bson.A, 0, len(expressions))
+	for _, expression := range expressions {
+		filter, err := CompileFilter(expression, schema)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, filter)
+	}
+	return bson.M{operator: compiled}, nil
+}
+
+// lookupFilterField returns the declaration of the field if the operator may be used with it.
+func lookupFilterField(schema FilterSchema, fieldName, operator string, pos int) (FilterField, error) {
+	field, ok := schema[fieldName]
+	if !ok {
+		return FilterField{}, newFilterError(pos, "unknown field '%s'", fieldName)
+	}
+	if !field.allows(operator) {
+		return FilterField{}, newFilterError(pos, "operator '%s' can't be used with '%s'", operator, fieldName)
+	}
+	return field, nil
+}
+
+func compileComparison(comparison FilterComparison, schema FilterSchema) (bson.M, error) {
+	field, err := lookupFilterField(schema, comparison.Field, comparison.Operator, comparison.Pos)
+	if err != nil {
+		return nil, err
+	}
+
+	// ~ is a case-insensitive "contains", the value is matched literally and not as a regular expression
+	if comparison.Operator == OpContains {
+		return bson.M{field.DocumentField: bson.M{
+			"$regex": regexp.QuoteMeta(comparison.Value.Raw), "$options": "i",
+		}}, nil
+	}
+
+	value, err := field.convert(comparison.Field, comparison.Value)
+	if err != nil {
+		return nil, err
+	}
+	return bson.M{field.DocumentField: bson.M{filterMongoOperators[comparison.Operator]: value}}, nil
+}
+
+func compileIn(in FilterIn, schema FilterSchema) (bson.M, error) {
+	operator := OpIn
+	if in.Negated {
+		operator = OpNotIn
+	} else if in.All {
+		operator = OpAll
+	}
+
+	field, err := lookupFilterField(schema, in.Field, operator, in.Pos)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(bson.A, 0, len(in.Values))
+	for _, filterValue := range in.Values {
+		value, err := field.convert(in.Field, filterValue)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return bson.M{field.DocumentField: bson.M{filterMongoOperators[operator]: values}}, nil
+}
@@ -0,0 +1,134 @@
+package httputil
+
+import (
+	"fmt"
+	"strings"
+)
+
+type filterTokenKind int
+
+const (
+	tokenEOF filterTokenKind = iota
+	tokenWord
+	tokenString
+	tokenOperator
+	tokenLeftParen
+	tokenRightParen
+	tokenComma
+)
+
+// filterToken is a token of the filter query param. Pos is the 1-based position of its first character.
+type filterToken struct {
+	kind  filterTokenKind
+	value string
+	pos   int
+}
+
+func (t filterToken) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of filter"
+	case tokenString:
+		return fmt.Sprintf("%q", t.value)
+	}
+	return fmt.Sprintf("'%s'", t.value)
+}
+
+// FilterError is returned for malformed filters, Pos is the 1-based position in the filter where the error was found.
+type FilterError struct {
+	Pos     int
+	Message string
+}
+
+func (e *FilterError) Error() string {
+	return fmt.Sprintf("invalid filter at position %d: %s", e.Pos, e.Message)
+}
+
+func newFilterError(pos int, format string, args ...any) *FilterError {
+	return &FilterError{Pos: pos, Message: fmt.Sprintf(format, args...)}
+}
+
+// isWordDelimiter reports whether the character ends a bare word. Hyphens, dots, colons and slashes are part of
+// words, so urls, dates and names like spider-man don't need quoting.
+func isWordDelimiter(char byte) bool {
+	return strings.IndexByte(" \t\r\n()=,!~<>\"", char) >= 0
+}
+
+// lexFilter splits the filter into tokens. Strings are double-quoted and support the \" and \\ escapes.
+func lexFilter(input string) ([]filterToken, error) {
+	tokens := make([]filterToken, 0)
+
+	for i := 0; i < len(input); {
+		char := input[i]
+		pos := i + 1
+
+		switch {
+		case char == ' ' || char == '\t' || char == '\r' || char == '\n':
+			i++
+		case char == '(':
+			tokens = append(tokens, filterToken{kind: tokenLeftParen, value: "(", pos: pos})
+			i++
+		case char == ')':
+			tokens = append(tokens, filterToken{kind: tokenRightParen, value: ")", pos: pos})
+			i++
+		case char == ',':
+			tokens = append(tokens, filterToken{kind: tokenComma, value: ",", pos: pos})
+			i++
+		case char == '=' || char == '~':
+			tokens = append(tokens, filterToken{kind: tokenOperator, value: string(char), pos: pos})
+			i++
+		case char == '!' || char == '<' || char == '>':
+			if i+1 < len(input) && input[i+1] == '=' {
+				tokens = append(tokens, filterToken{kind: tokenOperator, value: input[i : i+2], pos: pos})
+				i += 2
+				continue
+			}
+			if char == '!' {
+				return nil, newFilterError(pos, "unexpected '!', did you mean '!='?")
+			}
+			tokens = append(tokens, filterToken{kind: tokenOperator, value: string(char), pos: pos})
+			i++
+		case char == '"':
+			value, end, err := lexString(input, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, filterToken{kind: tokenString, value: value, pos: pos})
+			i = end
+		default:
+			end := i
+			for end < len(input) && !isWordDelimiter(input[end]) {
+				end++
+			}
+			tokens = append(tokens, filterToken{kind: tokenWord, value: input[i:end], pos: pos})
+			i = end
+		}
+	}
+
+	return append(tokens, filterToken{kind: tokenEOF, pos: len(input) + 1}), nil
+}
+
+// lexString reads the quoted string starting at start and returns its unescaped value and the index after the closing quote.
+func lexString(input string, start int) (string, int, error) {
+	var value strings.Builder
+
+	for i := start + 1; i < len(input); i++ {
+		switch input[i] {
+		case '"':
+			return value.String(), i + 1, nil
+		case '\\':
+			if i+1 >= len(input) {
+				return "", 0, newFilterError(i+1, "unfinished escape sequence")
+			}
+			if input[i+1] != '"' && input[i+1] != '\\' {
+				return "", 0, newFilterError(i+1, "invalid escape sequence '\\%c', only \\\" and \\\\ are supported", input[i+1])
+			}
+			value.WriteByte(input[i+1])
+			i++
+		default:
+			value.WriteByte(input[i])
+		}
+	}
+
+	return "", 0, newFilterError(start+1, "unterminated string")
+}
@@ -0,0 +1,256 @@
+package httputil
+
+import (
+	"strings"
+)
+
+// FilterExpression is a node of a parsed filter, one of FilterAnd, FilterOr, FilterComparison or FilterIn.
+type FilterExpression interface {
+	Position() int
+}
+
+// FilterAnd matches when all of its expressions match.
+type FilterAnd struct {
+	Expressions []FilterExpression
+	Pos         int
+}
+
+// FilterOr matches when any of its expressions matches.
+type FilterOr struct {
+	Expressions []FilterExpression
+	Pos         int
+}
+
+// FilterComparison compares a field with a value, e.g. name~"cat" or isFavourite=true.
+type FilterComparison struct {
+	Field    string
+	Operator string
+	Value    FilterValue
+	Pos      int
+}
+
+// FilterIn matches when the field is one of the values, e.g. categoryId in (a,b). Negated is set for "not in",
+// All for "all", which matches arrays that contain every value, e.g. tags all (cat,funny).
+type FilterIn struct {
+	Field   string
+	Values  []FilterValue
+	Negated bool
+	All     bool
+	Pos     int
+}
+
+func (e FilterAnd) Position() int        { return e.Pos }
+func (e FilterOr) Position() int         { return e.Pos }
+func (e FilterComparison) Position() int { return e.Pos }
+func (e FilterIn) Position() int         { return e.Pos }
+
+type FilterValueKind int
+
+const (
+	FilterString FilterValueKind = iota
+	FilterBool
+)
+
+// FilterValue is a literal of the filter. Quoted values are always strings,
+// bare true and false are booleans.
+// The values are converted to the type of their field by CompileFilter.
+type FilterValue struct {
+	Kind   FilterValueKind
+	Raw    string
+	Quoted bool
+	Pos    int
+}
+
+var filterComparisonOperators = map[string]bool{
+	OpEqual: true, OpNotEqual: true, OpContains: true, OpGreater: true, OpGreaterOrEqual: true, OpLess: true, OpLessOrEqual: true,
+}
+
+// ParseFilter parses expressions like `name~"cat" and isFavourite=true or categoryId in (a,b) or tags all (cat,funny)`.
+// "and" binds stronger than "or", parentheses can be used for grouping.
+func ParseFilter(input string) (FilterExpression, error) {
+	tokens, err := lexFilter(input)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := filterParser{tokens: tokens}
+	expression, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if next := parser.peek(); next.kind != tokenEOF {
+		return nil, newFilterError(next.pos, "expected 'and', 'or' or end of filter, found %s", next)
+	}
+	return expression, nil
+}
+
+type filterParser struct {
+	tokens []filterToken
+	index  int
+}
+
+func (p *filterParser) peek() filterToken {
+	return p.tokens[p.index]
+}
+
+func (p *filterParser) next() filterToken {
+	token := p.tokens[p.index]
+	if token.kind != tokenEOF {
+		p.index++
+	}
+	return token
+}
+
+// peekKeyword reports whether the next token is the keyword, keywords are case-insensitive.
+func (p *filterParser) peekKeyword(keyword string) bool {
+	token := p.peek()
+	return token.kind == tokenWord && strings.EqualFold(token.value, keyword)
+}
+
+func (p *filterParser) parseOr() (FilterExpression, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	expressions := []FilterExpression{first}
+	for p.peekKeyword("or") {
+		p.next()
+		expression, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		expressions = append(expressions, expression)
+	}
+
+	if len(expressions) == 1 {
+		return first, nil
+	}
+	return FilterOr{Expressions: expressions, Pos: first.Position()}, nil
+}
+
+func (p *filterParser) parseAnd() (FilterExpression, error) {
+	first, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	expressions := []FilterExpression{first}
+	for p.peekKeyword("and") {
+		p.next()
+		expression, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		expressions = append(expressions, expression)
+	}
+
+	if len(expressions) == 1 {
+		return first, nil
+	}
+	return FilterAnd{Expressions: expressions, Pos: first.Position()}, nil
+}
+
+func (p *filterParser) parsePrimary() (FilterExpression, error) {
+	token := p.next()
+
+	if token.kind == tokenLeftParen {
+		expression, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRightParen {
+			return nil, newFilterError(closing.pos, "expected ')' to close the '(' at position %d, found %s", token.pos, closing)
+		}
+		return expression, nil
+	}
+
+	if token.kind != tokenWord {
+		return nil, newFilterError(token.pos, "expected a field name, found %s", token)
+	}
+	return p.parseCondition(token)
+}
+
+func (p *filterParser) parseCondition(field filterToken) (FilterExpression, error) {
+	if p.peekKeyword("in") {
+		p.next()
+		return p.parseIn(field)
+	}
+	if p.peekKeyword("not") {
+		p.next()
+		if !p.peekKeyword("in") {
+			return nil, newFilterError(p.peek().pos, "expected 'in' after 'not', found %s", p.peek())
+		}
+		p.next()
+		in, err := p.parseIn(field)
+		if err != nil {
+			return nil, err
+		}
+		in.Negated = true
+		return in, nil
+	}
+	if p.peekKeyword("all") {
+		p.next()
+		in, err := p.parseIn(field)
+		if err != nil {
+			return nil, err
+		}
+		in.All = true
+		return in, nil
+	}
+
+	operator := p.next()
+	if operator.kind != tokenOperator || !filterComparisonOperators[operator.value] {
+		return nil, newFilterError(operator.pos, "expected an operator (=, !=, ~, >, >=, <, <=, in, not in, all) after '%s', found %s", field.value, operator)
+	}
+
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	return FilterComparison{Field: field.value, Operator: operator.value, Value: value, Pos: field.pos}, nil
+}
+
+func (p *filterParser) parseIn(field filterToken) (FilterIn, error) {
+	if open := p.next(); open.kind != tokenLeftParen {
+		return FilterIn{}, newFilterError(open.pos, "expected '(' to start the list of values, found %s", open)
+	}
+
+	values := make([]FilterValue, 0)
+	for {
+		value, err := p.parseValue()
+		if err != nil {
+			return FilterIn{}, err
+		}
+		values = append(values, value)
+
+		separator := p.next()
+		if separator.kind == tokenRightParen {
+			break
+		}
+		if separator.kind != tokenComma {
+			return FilterIn{}, newFilterError(separator.pos, "expected ',' or ')' in the list of values, found %s", separator)
+		}
+	}
+
+	return FilterIn{Field: field.value, Values: values, Pos: field.pos}, nil
+}
+
+func (p *filterParser) parseValue() (FilterValue, error) {
+	token := p.next()
+
+	switch token.kind {
+	case tokenString:
+		return FilterValue{Kind: FilterString, Raw: token.value, Quoted: true, Pos: token.pos}, nil
+	case tokenWord:
+		value := FilterValue{Kind: FilterString, Raw: token.value, Pos: token.pos}
+		if token.value == "true" || token.value == "false" {
+			value.Kind = FilterBool
+		}
+		return value, nil
+	}
+
+	return FilterValue{}, newFilterError(token.pos, "expected a value, found %s", token)
+}
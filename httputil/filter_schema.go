@@ -0,0 +1,92 @@
+package httputil
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"slices"
+	"strings"
+	"time"
+)
+
+type FilterFieldType int
+
+const (
+	FilterFieldString FilterFieldType = iota
+	FilterFieldBool
+	FilterFieldObjectID
+	FilterFieldDate
+	// FilterFieldTag is an array of lowercase strings, the values are lowercased and trimmed like the stored tags
+	FilterFieldTag
+)
+
+// operators that can be allowed for a filter field, "in", "not in" and "all" are the list operators
+const (
+	OpEqual          = "="
+	OpNotEqual       = "!="
+	OpContains       = "~"
+	OpGreater        = ">"
+	OpGreaterOrEqual = ">="
+	OpLess           = "<"
+	OpLessOrEqual    = "<="
+	OpIn             = "in"
+	OpNotIn          = "not in"
+	OpAll            = "all"
+)
+
+// the operators that make sense for each field type
+var (
+	StringOperators   = []string{OpEqual, OpNotEqual, OpContains, OpIn, OpNotIn}
+	BoolOperators     = []string{OpEqual, OpNotEqual}
+	ObjectIDOperators = []string{OpEqual, OpNotEqual, OpIn, OpNotIn}
+	DateOperators     = []string{OpEqual, OpNotEqual, OpGreater, OpGreaterOrEqual, OpLess, OpLessOrEqual}
+	// on an array field = and in match when any element matches, all when every value is an element
+	TagOperators = []string{OpEqual, OpNotEqual, OpIn, OpNotIn, OpAll}
+)
+
+// FilterField declares a field that clients are allowed to filter by.
+type FilterField struct {
+	// DocumentField is the name of the field in the database
+	DocumentField string
+	Type          FilterFieldType
+	Operators     []string
+}
+
+// FilterSchema maps the field names used in the filter query param to their declaration.
+// Fields that aren't in the schema can't be filtered by.
+type FilterSchema map[string]FilterField
+
+func (f FilterField) allows(operator string) bool {
+	return slices.Contains(f.Operators, operator)
+}
+
+// convert parses the filter value to the type of the field, so e.g. ids are compared as ObjectIDs and not as strings.
+func (f FilterField) convert(fieldName string, value FilterValue) (any, error) {
+	switch f.Type {
+	case FilterFieldBool:
+		if value.Kind != FilterBool {
+			return nil, newFilterError(value.Pos, "'%s' expects true or false, found '%s'", fieldName, value.Raw)
+		}
+		return value.Raw == "true", nil
+	case FilterFieldObjectID:
+		id, err := primitive.ObjectIDFromHex(value.Raw)
+		if err != nil {
+			return nil, newFilterError(value.Pos, "'%s' expects an id, found '%s'", fieldName, value.Raw)
+		}
+		return id, nil
+	case FilterFieldDate:
+		return parseFilterDate(fieldName, value)
+	case FilterFieldTag:
+		return strings.ToLower(strings.TrimSpace(value.Raw)), nil
+	}
+	return value.Raw, nil
+}
+
+// parseFilterDate accepts RFC 3339 timestamps and plain dates, which are midnight UTC.
+func parseFilterDate(fieldName string, value FilterValue) (time.Time, error) {
+	if date, err := time.Parse(time.RFC3339, value.Raw); err == nil {
+		return date, nil
+	}
+	if date, err := time.Parse(time.DateOnly, value.Raw); err == nil {
+		return date, nil
+	}
+	return time.Time{}, newFilterError(value.Pos, "'%s' expects a date like 2006-01-02 or 2006-01-02T15:04:05Z, found '%s'", fieldName, value.Raw)
+}
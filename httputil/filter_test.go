@@ -0,0 +1,162 @@
+package httputil_test
+
+import (
+	"gifmanager-backend/httputil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+	"time"
+)
+
+var testFilterSchema = httputil.FilterSchema{
+	"name":        {DocumentField: "name", Type: httputil.FilterFieldString, Operators: httputil.StringOperators},
+	"url":         {DocumentField: "url", Type: httputil.FilterFieldString, Operators: httputil.StringOperators},
+	"isFavourite": {DocumentField: "isFavourite", Type: httputil.FilterFieldBool, Operators: httputil.BoolOperators},
+	"categoryId":  {DocumentField: "categoryId", Type: httputil.FilterFieldObjectID, Operators: httputil.ObjectIDOperators},
+	"createdAt":   {DocumentField: "created_at", Type: httputil.FilterFieldDate, Operators: httputil.DateOperators},
+	"tags":        {DocumentField: "tags", Type: httputil.FilterFieldTag, Operators: httputil.TagOperators},
+}
+
+var (
+	firstCategoryID  = primitive.NewObjectID()
+	secondCategoryID = primitive.NewObjectID()
+)
+
+func compileFilter(filter string) (bson.M, error) {
+	expression, err := httputil.ParseFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	return httputil.CompileFilter(expression, testFilterSchema)
+}
+
+func TestCompileFilter(t *testing.T) {
+	testCases := []struct {
+		name     string
+		filter   string
+		expected bson.M
+	}{
+		{
+			name:     "hyphenated bare value",
+			filter:   "name=spider-man",
+			expected: bson.M{"name": bson.M{"$eq": "spider-man"}},
+		},
+		{
+			name:     "url",
+			filter:   "url=https://media.giphy.com/cat-1.gif",
+			expected: bson.M{"url": bson.M{"$eq": "https://media.giphy.com/cat-1.gif"}},
+		},
+		{
+			name:     "contains is case-insensitive and literal",
+			filter:   `name~"cat.gif"`,
+			expected: bson.M{"name": bson.M{"$regex": `cat\.gif`, "$options": "i"}},
+		},
+		{
+			name:     "escaped quotes",
+			filter:   `name="say \"hi\" \\o/"`,
+			expected: bson.M{"name": bson.M{"$eq": `say "hi" \o/`}},
+		},
+		{
+			name:     "values are converted to the type of the field",
+			filter:   `name!=true and createdAt>=2023-07-01`,
+			expected: bson.M{"$and": bson.A{bson.M{"name": bson.M{"$ne": "true"}}, bson.M{"created_at": bson.M{"$gte": time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)}}}},
+		},
+		{
+			name:     "ids are compared as object ids",
+			filter:   "categoryId in (" + firstCategoryID.Hex() + "," + secondCategoryID.Hex() + ")",
+			expected: bson.M{"categoryId": bson.M{"$in": bson.A{firstCategoryID, secondCategoryID}}},
+		},
+		{
+			name:     "tags are normalised",
+			filter:   `tags=" Cat "`,
+			expected: bson.M{"tags": bson.M{"$eq": "cat"}},
+		},
+		{
+			name:     "any of the tags",
+			filter:   "tags in (cat,Funny)",
+			expected: bson.M{"tags": bson.M{"$in": bson.A{"cat", "funny"}}},
+		},
+		{
+			name:     "all of the tags",
+			filter:   "tags ALL (cat,funny) and name~dog",
+			expected: bson.M{"$and": bson.A{bson.M{"tags": bson.M{"$all": bson.A{"cat", "funny"}}}, bson.M{"name": bson.M{"$regex": "dog", "$options": "i"}}}},
+		},
+		{
+			name:   "and binds stronger than or",
+			filter: `name~"cat" and isFavourite=true or categoryId = ` + firstCategoryID.Hex(),
+			expected: bson.M{"$or": bson.A{
+				bson.M{"$and": bson.A{
+					bson.M{"name": bson.M{"$regex": "cat", "$options": "i"}},
+					bson.M{"isFavourite": bson.M{"$eq": true}},
+				}},
+				bson.M{"categoryId": bson.M{"$eq": firstCategoryID}},
+			}},
+		},
+		{
+			name:   "parentheses",
+			filter: `name~cat AND (isFavourite=true OR categoryId NOT IN (` + firstCategoryID.Hex() + `))`,
+			expected: bson.M{"$and": bson.A{
+				bson.M{"name": bson.M{"$regex": "cat", "$options": "i"}},
+				bson.M{"$or": bson.A{
+					bson.M{"isFavourite": bson.M{"$eq": true}},
+					bson.M{"categoryId": bson.M{"$nin": bson.A{firstCategoryID}}},
+				}},
+			}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			// 1. ACT
+			filter, err := compileFilter(testCase.filter)
+
+			// 2. ASSERT
+			require.Nil(t, err)
+			assert.Equal(t, testCase.expected, filter)
+		})
+	}
+}
+
+func TestCompileFilter_MalformedFilters_ReturnPosition(t *testing.T) {
+	testCases := []struct {
+		filter      string
+		expectedPos int
+	}{
+		{filter: "", expectedPos: 1},
+		{filter: "name", expectedPos: 5},
+		{filter: "name=", expectedPos: 6},
+		{filter: `name="cat`, expectedPos: 6},
+		{filter: `name="c\at"`, expectedPos: 8},
+		{filter: "name=cat and", expectedPos: 13},
+		{filter: "name=cat isFavourite=true", expectedPos: 10},
+		{filter: "(name=cat", expectedPos: 10},
+		{filter: "categoryId in (a b)", expectedPos: 18},
+		{filter: "name!cat", expectedPos: 5},
+		{filter: "password=secret", expectedPos: 1},
+		{filter: "name=cat or userId=abc", expectedPos: 13},
+		{filter: "$where=1", expectedPos: 1},
+		{filter: "isFavourite~true", expectedPos: 1},
+		{filter: "name>cat", expectedPos: 1},
+		{filter: "isFavourite=yes", expectedPos: 13},
+		{filter: "categoryId=abc", expectedPos: 12},
+		{filter: "categoryId in (" + firstCategoryID.Hex() + ", abc)", expectedPos: 42},
+		{filter: "createdAt<yesterday", expectedPos: 11},
+		{filter: "name all (cat)", expectedPos: 1},
+		{filter: "tags all cat", expectedPos: 10},
+		{filter: "tags~cat", expectedPos: 1},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.filter, func(t *testing.T) {
+			// 1. ACT
+			_, err := compileFilter(testCase.filter)
+
+			// 2. ASSERT
+			var filterErr *httputil.FilterError
+			require.ErrorAs(t, err, &filterErr)
+			assert.Equal(t, testCase.expectedPos, filterErr.Pos, filterErr.Error())
+		})
+	}
+}
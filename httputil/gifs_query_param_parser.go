@@ -1,11 +1,22 @@
 package httputil
 
-import (
-	"fmt"
-	"go.mongodb.org/mongo-driver/bson"
-	"net/url"
-	"strconv"
-)
+// gifsFilterSchema are the fields gifs can be filtered by. The owner is never filterable, it is set by the handlers.
+var gifsFilterSchema = FilterSchema{
+	"name":        {DocumentField: "name", Type: FilterFieldString, Operators: StringOperators},
+	"url":         {DocumentField: "url", Type: FilterFieldString, Operators: StringOperators},
+	"isFavourite": {DocumentField: "isFavourite", Type: FilterFieldBool, Operators: BoolOperators},
+	"categoryId":  {DocumentField: "categoryId", Type: FilterFieldObjectID, Operators: ObjectIDOperators},
+	"tags":        {DocumentField: "tags", Type: FilterFieldTag, Operators: TagOperators},
+}
+
+// gifsSortFields are the fields gifs can be sorted by, mapped to their document fields
+var gifsSortFields = map[string]string{
+	"id":          "_id",
+	"name":        "name",
+	"url":         "url",
+	"isFavourite": "isFavourite",
+	"categoryId":  "categoryId",
+}
 
 type GifsQueryParamsParser struct {
 	baseQueryParamsParser
@@ -13,42 +24,6 @@ type GifsQueryParamsParser struct {
 
 func NewGifsApiQueryParamParser() *GifsQueryParamsParser {
 	return &GifsQueryParamsParser{
-		baseQueryParamsParser{},
-	}
-}
-
-func (p *GifsQueryParamsParser) LoadValues(values url.Values) {
-	p.baseQueryParamsParser.LoadValues(values)
-}
-
-func (p GifsQueryParamsParser) HasFilter() bool {
-	return p.baseQueryParamsParser.HasFilter()
-}
-
-func (p GifsQueryParamsParser) GetFilter() (interface{}, error) {
-	return p.baseQueryParamsParser.GetFilter(handleGifsApiFieldFilter)
-}
-
-func handleGifsApiFieldFilter(fieldName, operator, value string, filter bson.M) error {
-	switch fieldName {
-	case "name":
-		filter[fieldName] = bson.M{
-			operator: value, "$options": "i",
-		}
-		return nil
-	case "isFavourite":
-		boolValue, errBool := strconv.ParseBool(value)
-		if errBool != nil {
-			return fmt.Errorf("invalid filter value type for field 'isFavourite'")
-		}
-		filter[fieldName] = bson.M{
-			operator: boolValue,
-		}
-		return nil
-	}
-
-	filter[fieldName] = bson.M{
-		operator: value,
+		baseQueryParamsParser{filterSchema: gifsFilterSchema, sortFields: gifsSortFields},
 	}
-	return nil
 }
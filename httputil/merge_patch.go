@@ -0,0 +1,77 @@
+package httputil
+
+import (
+	"bytes"
+	"encoding/json"
+	"go.mongodb.org/mongo-driver/bson"
+	"mime"
+	"net/http"
+)
+
+const MergePatchContentType = "application/merge-patch+json"
+
+const CodeUnsupportedMediaType = "unsupported_media_type"
+
+var ErrUnsupportedMediaType = NewError(http.StatusUnsupportedMediaType, CodeUnsupportedMediaType, "the request body must be sent as "+MergePatchContentType)
+
+// Optional is a member of a JSON merge patch (RFC 7396). Set is false when the member is missing from the patch,
+// Null is true when the member is null, which removes the field from the document.
+type Optional[T any] struct {
+	Value T
+	Set   bool
+	Null  bool
+}
+
+// UnmarshalJSON is called for null as well, so a null member can be told apart from a missing one.
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if string(data) == "null" {
+		o.Null = true
+		return nil
+	}
+	return json.Unmarshal(data, &o.Value)
+}
+
+// IsValue reports whether the patch sets the member to a value other than null.
+func (o Optional[T]) IsValue() bool {
+	return o.Set && !o.Null
+}
+
+// AddToUpdate adds the member to the $set of the update or, when it is null, to its $unset.
+// Missing members leave the update unchanged.
+func (o Optional[T]) AddToUpdate(update bson.M, field string) {
+	if !o.Set {
+		return
+	}
+
+	operator, value := "$set", any(o.Value)
+	if o.Null {
+		operator, value = "$unset", ""
+	}
+	fields, ok := update[operator].(bson.M)
+	if !ok {
+		fields = bson.M{}
+		update[operator] = fields
+	}
+	fields[field] = value
+}
+
+// DecodeMergePatch decodes a merge patch into target, whose fields are Optional values named after the members.
+// The body must be a JSON object sent as application/merge-patch+json or application/json,
+// otherwise it is decoded and validated like DecodeJSON does.
+func DecodeMergePatch(writer http.ResponseWriter, request *http.Request, target any) error {
+	mediaType, _, err := mime.ParseMediaType(request.Header.Get("Content-Type"))
+	if err != nil || (mediaType != MergePatchContentType && mediaType != "application/json") {
+		return ErrUnsupportedMediaType
+	}
+
+	// null or an array would be accepted by a struct target, so the body is checked to be an object first
+	var raw json.RawMessage
+	if err := DecodeJSON(writer, request, &raw); err != nil {
+		return err
+	}
+	if !bytes.HasPrefix(bytes.TrimSpace(raw), []byte("{")) {
+		return ErrInvalidJSON.WithMessagef("a merge patch must be a JSON object")
+	}
+	return decodeAndValidate(bytes.NewReader(raw), target)
+}
@@ -0,0 +1,75 @@
+package httputil_test
+
+import (
+	"errors"
+	"gifmanager-backend/httputil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type testPatch struct {
+	Name        httputil.Optional[string] `json:"name"`
+	IsFavourite httputil.Optional[bool]   `json:"isFavourite"`
+	Likes       httputil.Optional[int]    `json:"likes"`
+}
+
+func decodePatch(contentType, body string) (testPatch, error) {
+	request := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(body))
+	request.Header.Set("Content-Type", contentType)
+	var target testPatch
+	err := httputil.DecodeMergePatch(httptest.NewRecorder(), request, &target)
+	return target, err
+}
+
+func TestDecodeMergePatch_TranslatesMembersToSetAndUnset(t *testing.T) {
+	// 1. ACT
+	patch, err := decodePatch(httputil.MergePatchContentType, `{"name": "cat", "isFavourite": null}`)
+
+	// 2. ASSERT
+	require.Nil(t, err)
+	assert.True(t, patch.Name.IsValue())
+	assert.True(t, patch.IsFavourite.Null)
+	assert.False(t, patch.Likes.Set)
+
+	update := bson.M{}
+	patch.Name.AddToUpdate(update, "name")
+	patch.IsFavourite.AddToUpdate(update, "isFavourite")
+	patch.Likes.AddToUpdate(update, "likes")
+	assert.Equal(t, bson.M{
+		"$set":   bson.M{"name": "cat"},
+		"$unset": bson.M{"isFavourite": ""},
+	}, update)
+}
+
+func TestDecodeMergePatch_RejectsInvalidPatches(t *testing.T) {
+	testCases := []struct {
+		name           string
+		contentType    string
+		body           string
+		expectedStatus int
+	}{
+		{"json patch", "application/json-patch+json", `[{"op": "remove", "path": "/name"}]`, http.StatusUnsupportedMediaType},
+		{"missing content type", "", `{"name": "cat"}`, http.StatusUnsupportedMediaType},
+		{"null", httputil.MergePatchContentType, `null`, http.StatusBadRequest},
+		{"array", "application/json", `[]`, http.StatusBadRequest},
+		{"unknown member", httputil.MergePatchContentType, `{"userId": "abc"}`, http.StatusBadRequest},
+		{"wrong type", httputil.MergePatchContentType, `{"likes": "many"}`, http.StatusBadRequest},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			// 1. ACT
+			_, err := decodePatch(testCase.contentType, testCase.body)
+
+			// 2. ASSERT
+			var httpErr *httputil.Error
+			require.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, testCase.expectedStatus, httpErr.Status)
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package httputil
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 500
+	// MaxPage keeps the number of documents skipped, and the page of the next link, from overflowing
+	MaxPage = math.MaxInt / MaxPageSize
+)
+
+// Pagination is the page requested with the page and pageSize query params. Pages start at 1.
+type Pagination struct {
+	Page     int
+	PageSize int
+}
+
+func (p Pagination) Skip() int64 {
+	return int64(p.Page-1) * int64(p.PageSize)
+}
+
+// HasNext reports whether there are documents after this page when total documents match the query.
+// There is no page after MaxPage, since it couldn't be requested.
+func (p Pagination) HasNext(total int64) bool {
+	return p.Page < MaxPage && int64(p.Page)*int64(p.PageSize) < total
+}
+
+// WritePaginationHeaders sets the X-Total-Count header and a Link header with the next and previous pages,
+// so the response body can stay a plain array.
+func WritePaginationHeaders(writer http.ResponseWriter, requestURL *url.URL, pagination Pagination, total int64) {
+	writer.Header().Set("X-Total-Count", strconv.FormatInt(total, 10))
+
+	links := make([]string, 0, 2)
+	if pagination.HasNext(total) {
+		links = append(links, pageLink(requestURL, pagination.Page+1, pagination.PageSize, "next"))
+	}
+	if pagination.Page > 1 {
+		links = append(links, pageLink(requestURL, pagination.Page-1, pagination.PageSize, "prev"))
+	}
+	if len(links) > 0 {
+		writer.Header().Set("Link", strings.Join(links, ", "))
+	}
+}
+
+func pageLink(requestURL *url.URL, page, pageSize int, rel string) string {
+	values := requestURL.Query()
+	values.Set("page", strconv.Itoa(page))
+	values.Set("pageSize", strconv.Itoa(pageSize))
+
+	link := url.URL{Path: requestURL.Path, RawQuery: values.Encode()}
+	return fmt.Sprintf(`<%s>; rel="%s"`, link.String(), rel)
+}
@@ -1,12 +1,25 @@
 package httputil
 
 import (
+	"gifmanager-backend/dal"
+	"go.mongodb.org/mongo-driver/bson"
 	"net/url"
 )
 
+// QueryParamsParser parses the query params of a request. The parsers are shared by every request of an API,
+// so they keep no state, the parsed query belongs to the request.
 type QueryParamsParser interface {
-	LoadValues(values url.Values)
+	Parse(values url.Values) (ParsedQuery, error)
+}
+
+// ParsedQuery is the filter, pagination and sorts of a request.
+type ParsedQuery struct {
+	// Filter is nil when the request has no filter
+	Filter     bson.M
+	Pagination Pagination
+	Sorts      dal.Sorts
+}
 
-	HasFilter() bool
-	GetFilter() (interface{}, error)
+func (q ParsedQuery) HasFilter() bool {
+	return q.Filter != nil
 }
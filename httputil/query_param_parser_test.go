@@ -0,0 +1,85 @@
+package httputil_test
+
+import (
+	"gifmanager-backend/dal"
+	"gifmanager-backend/httputil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"math"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+func TestGifsQueryParamsParser_Parse_WithoutParams_ExpectedDefaults(t *testing.T) {
+	// 1.ARRANGE
+	parser := httputil.NewGifsApiQueryParamParser()
+
+	// 2.ACT
+	query, err := parser.Parse(url.Values{})
+
+	// 3.ASSERT
+	require.Nil(t, err)
+	assert.False(t, query.HasFilter())
+	assert.Equal(t, httputil.Pagination{Page: 1, PageSize: httputil.DefaultPageSize}, query.Pagination)
+	assert.Equal(t, dal.Sorts{{FieldName: "_id", Ascending: true}}, query.Sorts)
+}
+
+func TestGifsQueryParamsParser_Parse_QueriesDontShareState(t *testing.T) {
+	// 1.ARRANGE
+	parser := httputil.NewGifsApiQueryParamParser()
+	first := url.Values{"filter": {"isFavourite = true"}, "page": {"2"}, "sort": {"-name"}}
+	second := url.Values{"page": {"5"}, "pageSize": {"10"}}
+
+	// 2.ACT
+	firstQuery, errFirst := parser.Parse(first)
+	secondQuery, errSecond := parser.Parse(second)
+
+	// 3.ASSERT
+	require.Nil(t, errFirst)
+	require.Nil(t, errSecond)
+	assert.Equal(t, bson.M{"isFavourite": bson.M{"$eq": true}}, firstQuery.Filter)
+	assert.Equal(t, 2, firstQuery.Pagination.Page)
+	assert.Equal(t, dal.Sorts{{FieldName: "name", Ascending: false}, {FieldName: "_id", Ascending: true}}, firstQuery.Sorts)
+	assert.False(t, secondQuery.HasFilter())
+	assert.Equal(t, httputil.Pagination{Page: 5, PageSize: 10}, secondQuery.Pagination)
+}
+
+func TestGifsQueryParamsParser_Parse_InvalidParams_ExpectedErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		values url.Values
+	}{
+		{"filter", url.Values{"filter": {"owner = 1"}}},
+		{"page", url.Values{"page": {"0"}}},
+		{"huge page", url.Values{"page": {"184467440737095518"}, "pageSize": {"50"}}},
+		{"page after the last one", url.Values{"page": {strconv.Itoa(httputil.MaxPage + 1)}}},
+		{"pageSize", url.Values{"pageSize": {"many"}}},
+		{"sort", url.Values{"sort": {"userId"}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// 2.ACT
+			_, err := httputil.NewGifsApiQueryParamParser().Parse(test.values)
+
+			// 3.ASSERT
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestGifsQueryParamsParser_Parse_LastPage_ExpectedSkipWithoutOverflow(t *testing.T) {
+	// 1.ARRANGE
+	values := url.Values{"page": {strconv.Itoa(httputil.MaxPage)}, "pageSize": {strconv.Itoa(httputil.MaxPageSize)}}
+
+	// 2.ACT
+	query, err := httputil.NewGifsApiQueryParamParser().Parse(values)
+
+	// 3.ASSERT
+	require.Nil(t, err)
+	assert.Equal(t, int64(httputil.MaxPage-1)*httputil.MaxPageSize, query.Pagination.Skip())
+	assert.Positive(t, query.Pagination.Skip())
+	assert.False(t, query.Pagination.HasNext(math.MaxInt64))
+}
@@ -1,14 +1,106 @@
 package httputil
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 )
 
-func WriteHttpError(writer http.ResponseWriter, statusCode int, errorMessage string) {
-	writer.WriteHeader(statusCode)
-	_, errWrite := writer.Write([]byte(errorMessage))
-	if errWrite != nil {
-		fmt.Println(errWrite.Error())
+const RequestIDHeader = "X-Request-ID"
+
+// error codes shared by all apis, the apis declare their own codes next to their errors
+const (
+	CodeInvalidJSON      = "invalid_json"
+	CodeInvalidID        = "invalid_id"
+	CodeInvalidFilter    = "invalid_filter"
+	CodeInvalidQuery     = "invalid_query"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeInternal         = "internal_error"
+)
+
+var (
+	ErrInvalidJSON      = NewError(http.StatusBadRequest, CodeInvalidJSON, "the request body is not valid JSON")
+	ErrInvalidID        = NewError(http.StatusBadRequest, CodeInvalidID, "invalid id")
+	ErrInvalidFilter    = NewError(http.StatusBadRequest, CodeInvalidFilter, "invalid filter")
+	ErrInvalidQuery     = NewError(http.StatusBadRequest, CodeInvalidQuery, "invalid query parameter")
+	ErrNotFound         = NewError(http.StatusNotFound, CodeNotFound, "the requested resource does not exist")
+	ErrMethodNotAllowed = NewError(http.StatusMethodNotAllowed, CodeMethodNotAllowed, "the method is not allowed for the requested resource")
+	ErrInternal         = NewError(http.StatusInternalServerError, CodeInternal, "unexpected error")
+)
+
+// ErrorResponse is the body of every error response.
+type ErrorResponse struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Details   any    `json:"details,omitempty"`
+	RequestID string `json:"requestId,omitempty"`
+}
+
+// Error is an error that is returned to the client with its status and machine-readable code.
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+	Details any
+}
+
+func NewError(status int, code, message string) *Error {
+	return &Error{
+		Status:  status,
+		Code:    code,
+		Message: message,
+	}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// WithMessagef returns a copy of the error with a more specific message.
+func (e *Error) WithMessagef(format string, args ...any) *Error {
+	copied := *e
+	copied.Message = fmt.Sprintf(format, args...)
+	return &copied
+}
+
+// WithDetails returns a copy of the error with details for the client, e.g. the position of a filter error.
+func (e *Error) WithDetails(details any) *Error {
+	copied := *e
+	copied.Details = details
+	return &copied
+}
+
+// WriteError writes the error as a JSON ErrorResponse. Errors that aren't an *Error or a *FilterError are
+// internal errors, their message is logged but never sent to the client.
+func WriteError(writer http.ResponseWriter, err error) {
+	var httpErr *Error
+	var filterErr *FilterError
+
+	switch {
+	case errors.As(err, &httpErr):
+	case errors.As(err, &filterErr):
+		httpErr = ErrInvalidFilter.
+			WithMessagef("%s", filterErr.Error()).
+			WithDetails(map[string]int{"position": filterErr.Pos})
+	default:
+		fmt.Println(err.Error())
+		httpErr = ErrInternal
+	}
+
+	response := ErrorResponse{
+		Code:    httpErr.Code,
+		Message: httpErr.Message,
+		Details: httpErr.Details,
+		// the request ID middleware sets the header before the handlers run
+		RequestID: writer.Header().Get(RequestIDHeader),
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.Header().Set("X-Content-Type-Options", "nosniff")
+	writer.WriteHeader(httpErr.Status)
+	if errEncode := json.NewEncoder(writer).Encode(response); errEncode != nil {
+		fmt.Println(errEncode.Error())
 	}
 }
@@ -0,0 +1,84 @@
+package httputil
+
+import (
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+const CodeValidationFailed = "validation_failed"
+
+var ErrValidationFailed = NewError(http.StatusUnprocessableEntity, CodeValidationFailed, "the request is invalid")
+
+// Validator is implemented by request bodies, DecodeJSON validates them after decoding.
+type Validator interface {
+	Validate() ValidationErrors
+}
+
+// FieldError describes what is wrong with one field of the request, Field is the JSON name, e.g. contacts[2].
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors collects the errors of all fields, so the client can fix them at once.
+type ValidationErrors []FieldError
+
+func (v ValidationErrors) Error() string {
+	messages := make([]string, 0, len(v))
+	for _, fieldErr := range v {
+		messages = append(messages, fmt.Sprintf("%s: %s", fieldErr.Field, fieldErr.Message))
+	}
+	return strings.Join(messages, ", ")
+}
+
+func (v *ValidationErrors) Add(field, format string, args ...any) {
+	*v = append(*v, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Length checks that the trimmed value has between min and max characters.
+func (v *ValidationErrors) Length(field, value string, min, max int) {
+	length := utf8.RuneCountInString(strings.TrimSpace(value))
+	switch {
+	case length == 0 && min > 0:
+		v.Add(field, "is required")
+	case length < min:
+		v.Add(field, "must be at least %d characters long", min)
+	case length > max:
+		v.Add(field, "must be at most %d characters long", max)
+	}
+}
+
+// URL checks that the value is an absolute http or https URL of at most max characters.
+func (v *ValidationErrors) URL(field, value string, max int) {
+	if value == "" {
+		v.Add(field, "is required")
+		return
+	}
+	if len(value) > max {
+		v.Add(field, "must be at most %d characters long", max)
+		return
+	}
+
+	parsed, err := url.Parse(value)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		v.Add(field, "must be an http or https URL")
+	}
+}
+
+// ObjectID checks that the id is set.
+func (v *ValidationErrors) ObjectID(field string, id primitive.ObjectID) {
+	if id.IsZero() {
+		v.Add(field, "is required")
+	}
+}
+
+// ObjectIDHex checks that the value is the hex representation of an ObjectID.
+func (v *ValidationErrors) ObjectIDHex(field, value string) {
+	if _, err := primitive.ObjectIDFromHex(value); err != nil {
+		v.Add(field, "must be an id")
+	}
+}
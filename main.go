@@ -2,36 +2,279 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"flag"
 	"fmt"
 	"gifmanager-backend/categories"
+	"gifmanager-backend/config"
 	"gifmanager-backend/dal"
 	"gifmanager-backend/gifs"
 	"gifmanager-backend/groups"
 	"gifmanager-backend/httputil"
+	"gifmanager-backend/migrations"
 	"gifmanager-backend/server"
+	"gifmanager-backend/shares"
+	"gifmanager-backend/users"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 func main() {
+	os.Exit(run())
+}
+
+// run starts the server or runs a subcommand and returns the exit code, main only exits after the deferred cleanup ran.
+func run() int {
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: gifmanager-backend [flags] [recount | indexes [-check] | migrate [flags] [up | down | status]]")
+		fmt.Fprintln(flag.CommandLine.Output(), "  recount\trebuilds the gif count of every category from the gifs and exits")
+		fmt.Fprintln(flag.CommandLine.Output(), "  indexes\tcreates the missing indexes, reports the indexes that differ from dal.Indexes and exits,")
+		fmt.Fprintln(flag.CommandLine.Output(), "         \twith 1 when there is drift; -check only reports, missing indexes count as drift")
+		fmt.Fprintln(flag.CommandLine.Output(), "  migrate\tapplies the pending migrations (up), reverts the last ones (down) or lists them (status) and exits,")
+		fmt.Fprintln(flag.CommandLine.Output(), "         \tsee migrate -h")
+		flag.PrintDefaults()
+	}
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:], os.LookupEnv)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	level, _ := cfg.SlogLevel()
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
+
+	// the subcommands are validated before connecting, their usage errors exit without anything to clean up
+	var checkIndexes bool
+	var migrate migrateOptions
+	switch flag.Arg(0) {
+	case "", "recount":
+	case "indexes":
+		checkIndexes = parseIndexesArgs(flag.Args()[1:])
+	case "migrate":
+		migrate = parseMigrateArgs(flag.Args()[1:])
+	default:
+		flag.Usage()
+		return 2
+	}
+
 	ctx := context.Background()
-	mongoDal, err := dal.NewMongoDal(ctx, "mongodb://localhost:27017", dal.DbName)
+	mongoDal, err := newDal(ctx, cfg.Mongo)
 	if err != nil {
 		panic(err)
 	}
+	// the mongo client is closed after the server drained the requests, which may still use it
 	defer func() {
-		if err := mongoDal.Disconnect(ctx); err != nil {
-			fmt.Println(err)
+		disconnectCtx, cancel := context.WithTimeout(ctx, cfg.Mongo.ConnectTimeout)
+		defer cancel()
+		if err := mongoDal.Disconnect(disconnectCtx); err != nil {
+			slog.Error("error disconnecting from mongo", "error", err)
 		}
 	}()
 
+	switch flag.Arg(0) {
+	case "recount":
+		categoriesWithGifs, err := categories.RecountGifs(ctx, mongoDal)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("recounted the gifs, %d categories have gifs\n", categoriesWithGifs)
+		return 0
+	case "indexes":
+		hasDrift, err := runIndexes(ctx, mongoDal, checkIndexes)
+		if err != nil {
+			panic(err)
+		}
+		if hasDrift {
+			return 1
+		}
+		return 0
+	case "migrate":
+		if err := runMigrate(ctx, mongoDal, migrate); err != nil {
+			panic(err)
+		}
+		return 0
+	}
+
+	// the server starts despite drift, the indexes that differ still work, they just aren't the declared ones
+	report, err := dal.EnsureIndexes(ctx, mongoDal, dal.Indexes)
+	if err != nil {
+		panic(err)
+	}
+	printIndexReport(report)
+	if !cfg.Mongo.InMemory {
+		warnAboutPendingMigrations(ctx, mongoDal)
+	}
+
 	parser := httputil.NewGifsApiQueryParamParser()
 	apiGif := gifs.NewGifApi(mongoDal, parser)
 
 	apiGroup := groups.NewGroupApi(mongoDal)
 	apiCategory := categories.NewApi(mongoDal, parser)
-	s := server.NewServer(mongoDal, apiGif, apiGroup, apiCategory)
+	apiShare := shares.NewShareApi(mongoDal)
+	tokens := users.NewTokenManager(tokenSecret(cfg.Auth), cfg.Auth.TokenTTL, cfg.Auth.RefreshTTL)
+	s := server.NewServer(cfg.Server, mongoDal, tokens, apiGif, apiGroup, apiCategory, apiShare)
+
+	// the first SIGINT or SIGTERM drains the requests in flight, a second one kills the server
+	runCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	context.AfterFunc(runCtx, stop)
+
+	slog.Info("http server running", "address", cfg.Server.Address, "tls", cfg.Server.TLS())
+	if err := s.Run(runCtx); err != nil {
+		slog.Error("http server stopped", "error", err)
+		return 1
+	}
+	slog.Info("http server stopped")
+	return 0
+}
+
+func newDal(ctx context.Context, cfg config.Mongo) (dal.DAL, error) {
+	if cfg.InMemory {
+		return dal.NewMemoryDal(), nil
+	}
+	connectCtx, cancel := context.WithTimeout(ctx, cfg.ConnectTimeout)
+	defer cancel()
+	mongoDal, err := dal.NewMongoDal(connectCtx, cfg.URI, cfg.Database)
+	if err != nil {
+		return nil, err
+	}
+	if !mongoDal.SupportsTransactions() {
+		slog.Warn("mongo is not a replica set, gifs and category counters are written without transactions")
+	}
+	return mongoDal, nil
+}
+
+// parseIndexesArgs parses the flags of the indexes subcommand, it reports whether only the drift is checked.
+func parseIndexesArgs(args []string) bool {
+	flags := flag.NewFlagSet("indexes", flag.ExitOnError)
+	check := flags.Bool("check", false, "only report the drift, don't create the missing indexes")
+	flags.Parse(args)
+	return *check
+}
+
+// runIndexes is the indexes subcommand, it reports whether the indexes drifted from the registry.
+func runIndexes(ctx context.Context, d dal.DAL, check bool) (bool, error) {
+	ensure := dal.EnsureIndexes
+	if check {
+		ensure = dal.CheckIndexes
+	}
+	report, err := ensure(ctx, d, dal.Indexes)
+	if err != nil {
+		return false, err
+	}
+
+	printIndexReport(report)
+	if !report.HasDrift() {
+		fmt.Println("the indexes match the registry")
+	}
+	return report.HasDrift(), nil
+}
+
+func printIndexReport(report dal.IndexReport) {
+	for _, created := range report.Created {
+		fmt.Printf("created index %s\n", created)
+	}
+	for _, drift := range report.Drift {
+		fmt.Printf("index drift: %s\n", drift)
+	}
+}
+
+// migrateOptions are the parsed arguments of the migrate subcommand.
+type migrateOptions struct {
+	action string
+	dryRun bool
+	to     int
+	steps  int
+}
+
+// parseMigrateArgs parses the flags and the action of the migrate subcommand, it exits on an unknown action.
+func parseMigrateArgs(args []string) migrateOptions {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "print the writes of the migrations instead of making them")
+	to := flags.Int("to", 0, "up: the version to migrate up to, the latest by default")
+	steps := flags.Int("steps", 1, "down: the number of migrations to revert")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: gifmanager-backend migrate [flags] [up | down | status]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	switch flags.Arg(0) {
+	case "", "up", "down", "status":
+	default:
+		flags.Usage()
+		os.Exit(2)
+	}
+	return migrateOptions{action: flags.Arg(0), dryRun: *dryRun, to: *to, steps: *steps}
+}
+
+// runMigrate is the migrate subcommand.
+func runMigrate(ctx context.Context, d dal.DAL, options migrateOptions) error {
+	migrator := migrations.NewMigrator(d, migrations.All)
+	if options.dryRun {
+		migrator.DryRun(os.Stdout)
+	}
+
+	// a dry run prints every migration it runs itself
+	switch options.action {
+	case "", "up":
+		applied, err := migrator.Up(ctx, options.to)
+		for _, migration := range applied {
+			if !options.dryRun {
+				fmt.Printf("applied %d %s\n", migration.Version, migration.Description)
+			}
+		}
+		return err
+	case "down":
+		reverted, err := migrator.Down(ctx, options.steps)
+		for _, migration := range reverted {
+			if !options.dryRun {
+				fmt.Printf("reverted %d %s\n", migration.Version, migration.Description)
+			}
+		}
+		return err
+	default:
+		// status, parseMigrateArgs rejected the other actions
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = "applied " + status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%d %s: %s\n", status.Version, status.Description, applied)
+		}
+		return nil
+	}
+}
+
+// warnAboutPendingMigrations only warns, the migrations are run by whoever runs the database, e.g. after a dry run.
+func warnAboutPendingMigrations(ctx context.Context, d dal.DAL) {
+	pending, err := migrations.NewMigrator(d, migrations.All).Pending(ctx)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if len(pending) > 0 {
+		fmt.Printf("%d migrations are pending, run the migrate subcommand to apply them\n", len(pending))
+	}
+}
+
+// tokenSecret returns the key the session tokens are signed with. Without a configured secret a random key is used,
+// which means every token is invalidated when the server restarts.
+func tokenSecret(cfg config.Auth) []byte {
+	if cfg.TokenSecret != "" {
+		return []byte(cfg.TokenSecret)
+	}
 
-	fmt.Println("HTTP SERVER SUCCESSFULLY RUNNING ON PORT 8888")
-	if err := s.Run("localhost:8888"); err != nil {
+	slog.Warn("no token secret is configured, using a random secret")
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
 		panic(err)
 	}
+	return secret
 }
@@ -0,0 +1,109 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"gifmanager-backend/dal"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"io"
+)
+
+// dryRunDal reads from the wrapped DAL and prints the writes instead of making them.
+// The updates report the documents they would match as matched and modified.
+type dryRunDal struct {
+	dal.DAL
+	out io.Writer
+}
+
+func newDryRunDal(d dal.DAL, out io.Writer) *dryRunDal {
+	return &dryRunDal{DAL: d, out: out}
+}
+
+func (d *dryRunDal) Insert(ctx context.Context, collection string, documents []any) (*dal.InsertResult, error) {
+	d.printf("would insert %d documents into %s", len(documents), collection)
+	return &dal.InsertResult{InsertedDocumentsCount: len(documents)}, nil
+}
+
+func (d *dryRunDal) Delete(ctx context.Context, collection string, filter any) (*dal.DeleteResult, error) {
+	count, err := d.DAL.Count(ctx, collection, filter)
+	if err != nil {
+		return nil, err
+	}
+	d.printf("would delete %d documents from %s matching %s", count, collection, toJSON(filter))
+	return &dal.DeleteResult{DeletedCount: count}, nil
+}
+
+func (d *dryRunDal) FindAndDeleteByID(ctx context.Context, collection string, id string, document any) error {
+	objID, _ := primitive.ObjectIDFromHex(id)
+	return d.FindAndDelete(ctx, collection, bson.M{"_id": objID}, document)
+}
+
+func (d *dryRunDal) FindAndDelete(ctx context.Context, collection string, filter any, document any) error {
+	var found []bson.M
+	if err := d.DAL.Find(ctx, collection, *dal.NewFindArguments().WithFilter(filter).WithLimit(1), &found); err != nil {
+		return err
+	}
+	d.printf("would delete %d documents from %s matching %s", len(found), collection, toJSON(filter))
+	if len(found) == 0 {
+		return nil
+	}
+	raw, err := bson.Marshal(found[0])
+	if err != nil {
+		return err
+	}
+	return bson.Unmarshal(raw, document)
+}
+
+func (d *dryRunDal) Update(ctx context.Context, collection string, filter any, update any, optionFuncs ...dal.UpdateOptionsFunc) (*dal.UpdateResult, error) {
+	count, err := d.DAL.Count(ctx, collection, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := dal.UpdateOptions{}
+	for _, optFunc := range optionFuncs {
+		optFunc(&opts)
+	}
+	if !opts.Multi && count > 1 {
+		count = 1
+	}
+	d.printf("would update %d documents in %s matching %s with %s", count, collection, toJSON(filter), toJSON(update))
+	return &dal.UpdateResult{MatchedCount: count, ModifiedCount: count}, nil
+}
+
+func (d *dryRunDal) UpdateByID(ctx context.Context, collection string, id string, update any, optionFuncs ...dal.UpdateOptionsFunc) (*dal.UpdateResult, error) {
+	objID, _ := primitive.ObjectIDFromHex(id)
+	return d.Update(ctx, collection, bson.M{"_id": objID}, update, optionFuncs...)
+}
+
+func (d *dryRunDal) EnsureIndex(ctx context.Context, collection string, index dal.Index) error {
+	d.printf("would create index %s in %s", index.Name, collection)
+	return nil
+}
+
+func (d *dryRunDal) DropIndex(ctx context.Context, collection string, name string) error {
+	d.printf("would drop index %s in %s", name, collection)
+	return nil
+}
+
+// WithTransaction runs fn without a transaction, nothing is written that could be rolled back.
+func (d *dryRunDal) WithTransaction(ctx context.Context, fn func(txCtx context.Context) error) error {
+	return fn(ctx)
+}
+
+func (d *dryRunDal) printf(format string, args ...any) {
+	fmt.Fprintf(d.out, "  "+format+"\n", args...)
+}
+
+// toJSON prints filters and updates as relaxed extended JSON, the way the mongo shell shows them.
+func toJSON(value any) string {
+	if value == nil {
+		value = bson.M{}
+	}
+	bts, err := bson.MarshalExtJSON(value, false, false)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(bts)
+}
@@ -0,0 +1,128 @@
+package migrations
+
+import (
+	"context"
+	"gifmanager-backend/dal"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// All are the migrations of the gif manager, in the order they are applied. A migration is never changed once it was
+// released, the documents it reads are described by its own types, since the models keep changing.
+var All = []Migration{
+	{
+		Version:     1,
+		Description: "rename the owner of the groups from user_id to userId, like in the other collections",
+		Up:          renameGroupOwnerUp,
+		Down:        renameGroupOwnerDown,
+	},
+	{
+		Version:     2,
+		Description: "turn the contacts of the groups into members",
+		Up:          contactsToMembersUp,
+		Down:        contactsToMembersDown,
+	},
+}
+
+func renameGroupOwnerUp(ctx context.Context, d dal.DAL) error {
+	return renameField(ctx, d, dal.CollGroups, "user_id", "userId", "user_id_1")
+}
+
+func renameGroupOwnerDown(ctx context.Context, d dal.DAL) error {
+	return renameField(ctx, d, dal.CollGroups, "userId", "user_id", "userId_1")
+}
+
+// renameField renames the field in every document of the collection and drops the index of the old field,
+// the index of the new field is created by dal.EnsureIndexes.
+func renameField(ctx context.Context, d dal.DAL, collection, from, to, fromIndex string) error {
+	filter := bson.M{from: bson.M{"$exists": true}}
+	update := bson.M{"$rename": bson.M{from: to}}
+	if _, err := d.Update(ctx, collection, filter, update, dal.UpdateAllMatching); err != nil {
+		return err
+	}
+	return dropIndexIfExists(ctx, d, collection, fromIndex)
+}
+
+func dropIndexIfExists(ctx context.Context, d dal.DAL, collection, name string) error {
+	indexes, err := d.ListIndexes(ctx, collection)
+	if err != nil {
+		return err
+	}
+	for _, index := range indexes {
+		if index.Name == name {
+			return d.DropIndex(ctx, collection, name)
+		}
+	}
+	return nil
+}
+
+// memberRole is the role the contacts get, the roles didn't exist when groups had contacts.
+const memberRole = "member"
+
+// contactsGroup is a group as it was stored before members replaced contacts, the contacts are user IDs in hex.
+type contactsGroup struct {
+	ID       primitive.ObjectID `bson:"_id"`
+	UserId   primitive.ObjectID `bson:"userId"`
+	Contacts []string           `bson:"contacts"`
+	Members  []groupMember      `bson:"members"`
+}
+
+type groupMember struct {
+	UserId primitive.ObjectID `bson:"userId"`
+	Role   string             `bson:"role"`
+}
+
+// contactsToMembersUp adds the contacts to the members and removes the contacts. Contacts that are not valid user IDs
+// are dropped, as are repetitions and the owner, who is never a member.
+func contactsToMembersUp(ctx context.Context, d dal.DAL) error {
+	var found []contactsGroup
+	filter := bson.M{"contacts": bson.M{"$exists": true}}
+	if err := d.Find(ctx, dal.CollGroups, *dal.NewFindArguments().WithFilter(filter), &found); err != nil {
+		return err
+	}
+
+	for _, group := range found {
+		members := make([]groupMember, 0, len(group.Members)+len(group.Contacts))
+		seen := map[primitive.ObjectID]bool{group.UserId: true}
+		for _, member := range group.Members {
+			seen[member.UserId] = true
+			members = append(members, member)
+		}
+		for _, contact := range group.Contacts {
+			userID, err := primitive.ObjectIDFromHex(contact)
+			if err != nil || seen[userID] {
+				continue
+			}
+			seen[userID] = true
+			members = append(members, groupMember{UserId: userID, Role: memberRole})
+		}
+
+		update := bson.M{"$set": bson.M{"members": members}, "$unset": bson.M{"contacts": ""}}
+		if _, err := d.UpdateByID(ctx, dal.CollGroups, group.ID.Hex(), update); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// contactsToMembersDown turns the members back into contacts, their roles are lost.
+func contactsToMembersDown(ctx context.Context, d dal.DAL) error {
+	var found []contactsGroup
+	filter := bson.M{"members": bson.M{"$exists": true}}
+	if err := d.Find(ctx, dal.CollGroups, *dal.NewFindArguments().WithFilter(filter), &found); err != nil {
+		return err
+	}
+
+	for _, group := range found {
+		contacts := make([]string, 0, len(group.Members))
+		for _, member := range group.Members {
+			contacts = append(contacts, member.UserId.Hex())
+		}
+
+		update := bson.M{"$set": bson.M{"contacts": contacts}, "$unset": bson.M{"members": ""}}
+		if _, err := d.UpdateByID(ctx, dal.CollGroups, group.ID.Hex(), update); err != nil {
+			return err
+		}
+	}
+	return nil
+}
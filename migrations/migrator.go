@@ -0,0 +1,201 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"gifmanager-backend/dal"
+	"go.mongodb.org/mongo-driver/bson"
+	"io"
+	"time"
+)
+
+// Migration changes the stored documents from one schema version to the next. Up and Down must be idempotent: a
+// migration is recorded only after it succeeded and migrations don't run in a transaction, since mongo can't change
+// indexes in one, so a migration that failed halfway is simply run again.
+// Neither may depend on its own writes, e.g. loop until nothing is left to update, since a dry run doesn't make them.
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context, d dal.DAL) error
+	Down        func(ctx context.Context, d dal.DAL) error
+}
+
+// Record is stored in the migrations collection for every applied migration, the _id is the version.
+type Record struct {
+	Version     int       `bson:"_id"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"appliedAt"`
+}
+
+// Status tells whether a migration was applied, AppliedAt is nil for pending migrations.
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Migrator applies and reverts the migrations in the order of their versions.
+type Migrator struct {
+	dal        dal.DAL
+	migrations []Migration
+	dryRun     io.Writer
+}
+
+func NewMigrator(d dal.DAL, migrations []Migration) *Migrator {
+	return &Migrator{
+		dal:        d,
+		migrations: migrations,
+	}
+}
+
+// DryRun makes the migrator print the writes of the migrations to out instead of making them,
+// the migrations are not recorded either.
+func (m *Migrator) DryRun(out io.Writer) *Migrator {
+	m.dryRun = out
+	return m
+}
+
+// Status lists every migration, the applied ones with the time they were applied.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Migration: migration}
+		if record, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &record.AppliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Pending returns the migrations that were not applied yet.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make([]Migration, 0)
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending = append(pending, status.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// Up applies the pending migrations up to and including the target version, every pending migration when target is 0.
+// It returns the migrations it applied, it stops at the first one that fails.
+func (m *Migrator) Up(ctx context.Context, target int) ([]Migration, error) {
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make([]Migration, 0)
+	for _, migration := range pending {
+		if target > 0 && migration.Version > target {
+			break
+		}
+		if err := m.run(ctx, migration, migration.Up); err != nil {
+			return applied, err
+		}
+		if err := m.record(ctx, migration); err != nil {
+			return applied, err
+		}
+		applied = append(applied, migration)
+	}
+	return applied, nil
+}
+
+// Down reverts the given number of applied migrations, the most recent first, and returns the migrations it reverted.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reverted := make([]Migration, 0)
+	for i := len(statuses) - 1; i >= 0 && len(reverted) < steps; i-- {
+		if statuses[i].AppliedAt == nil {
+			continue
+		}
+		migration := statuses[i].Migration
+		if err := m.run(ctx, migration, migration.Down); err != nil {
+			return reverted, err
+		}
+		if err := m.unrecord(ctx, migration); err != nil {
+			return reverted, err
+		}
+		reverted = append(reverted, migration)
+	}
+	return reverted, nil
+}
+
+func (m *Migrator) run(ctx context.Context, migration Migration, step func(ctx context.Context, d dal.DAL) error) error {
+	d := m.dal
+	if m.dryRun != nil {
+		fmt.Fprintf(m.dryRun, "%d %s\n", migration.Version, migration.Description)
+		d = newDryRunDal(m.dal, m.dryRun)
+	}
+
+	if err := step(ctx, d); err != nil {
+		return fmt.Errorf("error running migration %d %s: %w", migration.Version, migration.Description, err)
+	}
+	return nil
+}
+
+func (m *Migrator) record(ctx context.Context, migration Migration) error {
+	if m.dryRun != nil {
+		return nil
+	}
+
+	record := Record{Version: migration.Version, Description: migration.Description, AppliedAt: time.Now().UTC()}
+	if _, err := m.dal.Insert(ctx, dal.CollMigrations, []any{record}); err != nil {
+		return fmt.Errorf("error recording migration %d: %w", migration.Version, err)
+	}
+	return nil
+}
+
+func (m *Migrator) unrecord(ctx context.Context, migration Migration) error {
+	if m.dryRun != nil {
+		return nil
+	}
+
+	if _, err := m.dal.Delete(ctx, dal.CollMigrations, bson.M{"_id": migration.Version}); err != nil {
+		return fmt.Errorf("error removing the record of migration %d: %w", migration.Version, err)
+	}
+	return nil
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int]Record, error) {
+	var records []Record
+	if err := m.dal.Find(ctx, dal.CollMigrations, *dal.NewFindArguments(), &records); err != nil {
+		return nil, fmt.Errorf("error finding the applied migrations: %w", err)
+	}
+
+	applied := make(map[int]Record, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// validate checks that the versions of the migrations are positive and increasing.
+func (m *Migrator) validate() error {
+	previous := 0
+	for _, migration := range m.migrations {
+		if migration.Version <= previous {
+			return fmt.Errorf("migration %d %s must have a version greater than %d", migration.Version, migration.Description, previous)
+		}
+		previous = migration.Version
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package migrations_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"gifmanager-backend/dal"
+	"gifmanager-backend/groups"
+	"gifmanager-backend/migrations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+)
+
+// insertLegacyGroup stores a group the way it was stored before the migrations, with user_id and contacts
+func insertLegacyGroup(t *testing.T, d dal.DAL, ownerID primitive.ObjectID, contacts ...string) primitive.ObjectID {
+	groupID := primitive.NewObjectID()
+	_, err := d.Insert(context.Background(), dal.CollGroups, []any{bson.M{
+		"_id":      groupID,
+		"name":     t.Name(),
+		"user_id":  ownerID,
+		"contacts": contacts,
+	}})
+	require.Nil(t, err)
+	require.Nil(t, d.EnsureIndex(context.Background(), dal.CollGroups, dal.Index{
+		Name: "user_id_1",
+		Keys: []dal.IndexKey{{FieldName: "user_id", Ascending: true}},
+	}))
+	return groupID
+}
+
+func findRawGroup(t *testing.T, d dal.DAL, groupID primitive.ObjectID) bson.M {
+	var group bson.M
+	require.Nil(t, d.FindByID(context.Background(), dal.CollGroups, groupID.Hex(), &group))
+	return group
+}
+
+func TestMigrator_Up_ExpectedLegacyGroupsMigrated(t *testing.T) {
+	// 1.ARRANGE
+	memoryDal := dal.NewMemoryDal()
+	ownerID := primitive.NewObjectID()
+	contactID := primitive.NewObjectID()
+	groupID := insertLegacyGroup(t, memoryDal, ownerID, contactID.Hex(), "not an id", contactID.Hex(), ownerID.Hex())
+	migrator := migrations.NewMigrator(memoryDal, migrations.All)
+
+	// 2.ACT
+	applied, err := migrator.Up(context.Background(), 0)
+
+	// 3.ASSERT
+	require.Nil(t, err)
+	assert.Len(t, applied, len(migrations.All))
+
+	var group groups.Group
+	require.Nil(t, memoryDal.FindByID(context.Background(), dal.CollGroups, groupID.Hex(), &group))
+	assert.Equal(t, ownerID, group.UserId)
+	assert.Equal(t, []groups.Member{{UserId: contactID, Role: groups.RoleMember}}, group.Members)
+
+	raw := findRawGroup(t, memoryDal, groupID)
+	assert.NotContains(t, raw, "user_id")
+	assert.NotContains(t, raw, "contacts")
+
+	indexes, errIndexes := memoryDal.ListIndexes(context.Background(), dal.CollGroups)
+	require.Nil(t, errIndexes)
+	for _, index := range indexes {
+		assert.NotEqual(t, "user_id_1", index.Name)
+	}
+
+	pending, errPending := migrator.Pending(context.Background())
+	require.Nil(t, errPending)
+	assert.Empty(t, pending)
+}
+
+func TestMigrator_Up_Twice_ExpectedNothingApplied(t *testing.T) {
+	// 1.ARRANGE
+	memoryDal := dal.NewMemoryDal()
+	migrator := migrations.NewMigrator(memoryDal, migrations.All)
+	_, errFirst := migrator.Up(context.Background(), 0)
+	require.Nil(t, errFirst)
+
+	// 2.ACT
+	applied, err := migrator.Up(context.Background(), 0)
+
+	// 3.ASSERT
+	require.Nil(t, err)
+	assert.Empty(t, applied)
+
+	count, errCount := memoryDal.Count(context.Background(), dal.CollMigrations, bson.M{})
+	require.Nil(t, errCount)
+	assert.Equal(t, int64(len(migrations.All)), count)
+}
+
+func TestMigrator_Down_ExpectedLegacyGroupsRestored(t *testing.T) {
+	// 1.ARRANGE
+	memoryDal := dal.NewMemoryDal()
+	ownerID := primitive.NewObjectID()
+	contactID := primitive.NewObjectID()
+	groupID := insertLegacyGroup(t, memoryDal, ownerID, contactID.Hex())
+	migrator := migrations.NewMigrator(memoryDal, migrations.All)
+	_, errUp := migrator.Up(context.Background(), 0)
+	require.Nil(t, errUp)
+
+	// 2.ACT
+	reverted, err := migrator.Down(context.Background(), len(migrations.All))
+
+	// 3.ASSERT
+	require.Nil(t, err)
+	require.Len(t, reverted, 2)
+	assert.Equal(t, 2, reverted[0].Version)
+	assert.Equal(t, 1, reverted[1].Version)
+
+	raw := findRawGroup(t, memoryDal, groupID)
+	assert.Equal(t, ownerID, raw["user_id"])
+	assert.Equal(t, bson.A{contactID.Hex()}, raw["contacts"])
+	assert.NotContains(t, raw, "userId")
+	assert.NotContains(t, raw, "members")
+
+	pending, errPending := migrator.Pending(context.Background())
+	require.Nil(t, errPending)
+	assert.Len(t, pending, len(migrations.All))
+}
+
+func TestMigrator_DryRun_ExpectedWritesPrintedAndNothingChanged(t *testing.T) {
+	// 1.ARRANGE
+	memoryDal := dal.NewMemoryDal()
+	groupID := insertLegacyGroup(t, memoryDal, primitive.NewObjectID(), primitive.NewObjectID().Hex())
+	before := findRawGroup(t, memoryDal, groupID)
+	var out bytes.Buffer
+	migrator := migrations.NewMigrator(memoryDal, migrations.All).DryRun(&out)
+
+	// 2.ACT
+	applied, err := migrator.Up(context.Background(), 0)
+
+	// 3.ASSERT
+	require.Nil(t, err)
+	assert.Len(t, applied, len(migrations.All))
+	assert.Contains(t, out.String(), `would update 1 documents in groups matching {"user_id":{"$exists":true}} with {"$rename":{"user_id":"userId"}}`)
+	assert.Contains(t, out.String(), "would drop index user_id_1 in groups")
+
+	assert.Equal(t, before, findRawGroup(t, memoryDal, groupID))
+	pending, errPending := migrator.Pending(context.Background())
+	require.Nil(t, errPending)
+	assert.Len(t, pending, len(migrations.All))
+}
+
+func TestMigrator_Up_ToVersionAndFailure(t *testing.T) {
+	errFailed := errors.New("failed")
+	var ran []int
+	step := func(version int, err error) func(ctx context.Context, d dal.DAL) error {
+		return func(ctx context.Context, d dal.DAL) error {
+			ran = append(ran, version)
+			return err
+		}
+	}
+	memoryDal := dal.NewMemoryDal()
+	migrator := migrations.NewMigrator(memoryDal, []migrations.Migration{
+		{Version: 1, Description: "first", Up: step(1, nil)},
+		{Version: 2, Description: "second", Up: step(2, nil)},
+		{Version: 5, Description: "failing", Up: step(5, errFailed)},
+	})
+
+	t.Run("up to a version", func(t *testing.T) {
+		// 2.ACT
+		applied, err := migrator.Up(context.Background(), 1)
+
+		// 3.ASSERT
+		require.Nil(t, err)
+		require.Len(t, applied, 1)
+		assert.Equal(t, []int{1}, ran)
+	})
+
+	t.Run("failing migration", func(t *testing.T) {
+		// 2.ACT
+		applied, err := migrator.Up(context.Background(), 0)
+
+		// 3.ASSERT
+		assert.True(t, errors.Is(err, errFailed))
+		require.Len(t, applied, 1)
+		assert.Equal(t, 2, applied[0].Version)
+		assert.Equal(t, []int{1, 2, 5}, ran)
+
+		pending, errPending := migrator.Pending(context.Background())
+		require.Nil(t, errPending)
+		require.Len(t, pending, 1)
+		assert.Equal(t, 5, pending[0].Version)
+	})
+}
+
+func TestMigrator_VersionsOutOfOrder_ExpectedError(t *testing.T) {
+	// 1.ARRANGE
+	migrator := migrations.NewMigrator(dal.NewMemoryDal(), []migrations.Migration{
+		{Version: 2, Description: "second"},
+		{Version: 1, Description: "first"},
+	})
+
+	// 2.ACT
+	_, err := migrator.Up(context.Background(), 0)
+
+	// 3.ASSERT
+	assert.NotNil(t, err)
+}
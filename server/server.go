@@ -2,37 +2,50 @@ package server
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"gifmanager-backend/config"
 	"gifmanager-backend/dal"
+	"gifmanager-backend/httputil"
 	"gifmanager-backend/users"
 	"github.com/gorilla/mux"
-	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log/slog"
+	"net"
 	"net/http"
 )
 
 type HttpServer interface {
-	Run(address string) error
+	Run(ctx context.Context) error
 }
 
 type Server struct {
 	Handler http.Handler
+	config  config.Server
 }
 
 type Api interface {
 	InitializeEndpoints(route *mux.Router)
 }
 
-func NewServer(mongoDal dal.DAL, apis ...Api) Server {
+func NewServer(cfg config.Server, mongoDal dal.DAL, tokens *users.TokenManager, apis ...Api) Server {
 	router := mux.NewRouter()
-	router.Use(corsMiddleware())
+	// the router's middlewares don't run for unmatched routes, so they are wrapped in the request ID middleware themselves
+	router.NotFoundHandler = requestIDMiddleware()(errorHandler(httputil.ErrNotFound))
+	router.MethodNotAllowedHandler = requestIDMiddleware()(errorHandler(httputil.ErrMethodNotAllowed))
+	router.Use(requestIDMiddleware())
+	router.Use(corsMiddleware(cfg.CORSOrigins))
 	router.Methods(http.MethodOptions).
 		HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {})
 
-	loginRouter := router.PathPrefix("/login").Subrouter()
-	loginRouter.Methods(http.MethodPost).Handler(http.HandlerFunc(users.NewLoginApi(mongoDal).LoginHandler))
+	loginApi := users.NewLoginApi(mongoDal, tokens)
+	loginApi.InitializePublicEndpoints(router)
 
 	mainRouter := router.PathPrefix("").Subrouter()
-	mainRouter.Use(authorizationMiddleware(mongoDal))
+	mainRouter.Use(authorizationMiddleware(mongoDal, tokens))
+	loginApi.InitializeEndpoints(mainRouter)
 
 	for _, api := range apis {
 		api.InitializeEndpoints(mainRouter)
@@ -40,68 +53,155 @@ func NewServer(mongoDal dal.DAL, apis ...Api) Server {
 
 	return Server{
 		Handler: router,
+		config:  cfg,
 	}
 }
 
-func (m Server) Run(address string) error {
-	return http.ListenAndServe(address, m.Handler)
+// Run serves the API on the configured address until the context is cancelled, see Serve.
+func (m Server) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", m.config.Address)
+	if err != nil {
+		return err
+	}
+	return m.Serve(ctx, listener)
+}
+
+// Serve serves the API on the listener until the context is cancelled, with HTTPS when a certificate is configured.
+// It then stops accepting connections and waits for the requests in flight, up to the shutdown timeout, so clients
+// don't see their requests fail during a deploy.
+func (m Server) Serve(ctx context.Context, listener net.Listener) error {
+	// the timeouts keep slow clients from holding connections open forever
+	httpServer := &http.Server{
+		Handler:        m.Handler,
+		ReadTimeout:    m.config.ReadTimeout,
+		WriteTimeout:   m.config.WriteTimeout,
+		IdleTimeout:    m.config.IdleTimeout,
+		MaxHeaderBytes: m.config.MaxHeaderBytes,
+	}
+
+	errServe := make(chan error, 1)
+	go func() {
+		if m.config.TLS() {
+			errServe <- httpServer.ServeTLS(listener, m.config.TLSCertFile, m.config.TLSKeyFile)
+		} else {
+			errServe <- httpServer.Serve(listener)
+		}
+	}()
+
+	select {
+	case err := <-errServe:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("shutting down, waiting for the requests in flight", "timeout", m.config.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), m.config.ShutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		// the requests still in flight after the timeout are cut off instead of keeping the process alive
+		httpServer.Close()
+		return fmt.Errorf("error waiting for the requests in flight: %w", err)
+	}
+	return nil
 }
 
-func authorizationMiddleware(mongoDal dal.DAL) mux.MiddlewareFunc {
+// authorizationMiddleware accepts requests with a valid bearer token and puts the user and session IDs in the request context.
+func authorizationMiddleware(mongoDal dal.DAL, tokens *users.TokenManager) mux.MiddlewareFunc {
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
 
-			userName, password, ok := request.BasicAuth()
+			token, ok := users.BearerToken(request.Header.Get("Authorization"))
 			if !ok {
-				writer.WriteHeader(http.StatusUnauthorized)
-				_, errWriter := writer.Write([]byte("missing authentication"))
-				if errWriter != nil {
-					fmt.Println(errWriter.Error())
-				}
+				httputil.WriteError(writer, users.ErrMissingAuthentication)
 				return
 			}
-			result := make([]users.User, 0)
 			ctx := request.Context()
-			findArguments := dal.FindArguments{Filter: bson.M{"username": userName}}
-			if err := mongoDal.Find(ctx, "users", findArguments, &result); err != nil {
-				writer.WriteHeader(http.StatusInternalServerError)
-				_, errWriter := writer.Write([]byte("unexpected error while trying to fetch the user"))
-				if errWriter != nil {
-					fmt.Println(errWriter.Error())
-				}
+			claims, err := users.ValidateToken(ctx, mongoDal, tokens, token)
+			if errors.Is(err, users.ErrInvalidToken) || errors.Is(err, users.ErrTokenExpired) {
+				httputil.WriteError(writer, err)
 				return
 			}
-			if len(result) == 0 {
-				writer.WriteHeader(http.StatusUnauthorized)
-				_, errWriter := writer.Write([]byte("user not found"))
-				if errWriter != nil {
-					fmt.Println(errWriter.Error())
-				}
+			if err != nil {
+				fmt.Println(err.Error())
+				httputil.WriteError(writer, users.ErrFindingSession)
 				return
 			}
 
-			if result[0].Password != password {
-				writer.WriteHeader(http.StatusUnauthorized)
-				_, errWriter := writer.Write([]byte("the password differs"))
-				if errWriter != nil {
-					fmt.Println(errWriter.Error())
-				}
-				return
+			userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+			ctx = context.WithValue(ctx, "userID", userID)
+			ctx = context.WithValue(ctx, "sessionID", claims.SessionID)
+			next.ServeHTTP(writer, request.WithContext(ctx))
+		})
+	}
+}
+
+// requestIDMiddleware tags every response with the X-Request-ID header, so errors reported by clients can be found in the logs.
+// A request ID sent by the client or a proxy is kept, otherwise a new one is generated.
+func requestIDMiddleware() mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			requestID := request.Header.Get(httputil.RequestIDHeader)
+			if !isValidRequestID(requestID) {
+				requestID = newRequestID()
 			}
 
-			request = request.WithContext(context.WithValue(ctx, "userID", result[0].ID))
+			writer.Header().Set(httputil.RequestIDHeader, requestID)
 			next.ServeHTTP(writer, request)
 		})
 	}
 }
 
-func corsMiddleware() mux.MiddlewareFunc {
+// isValidRequestID only accepts short IDs of letters, digits and dashes, so they can't be used to inject anything into the logs.
+func isValidRequestID(requestID string) bool {
+	if len(requestID) == 0 || len(requestID) > 64 {
+		return false
+	}
+	for _, char := range requestID {
+		if !(char >= 'a' && char <= 'z') && !(char >= 'A' && char <= 'Z') && !(char >= '0' && char <= '9') && char != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return primitive.NewObjectID().Hex()
+	}
+	return hex.EncodeToString(bytes)
+}
+
+func errorHandler(err *httputil.Error) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		httputil.WriteError(writer, err)
+	})
+}
+
+// corsMiddleware allows the origins to call the API from browsers. Unless every origin is allowed with *,
+// the origin of the request is echoed when it is allowed, and left out otherwise, so the browser blocks the response.
+func corsMiddleware(origins []string) mux.MiddlewareFunc {
+	allowed := make(map[string]bool, len(origins))
+	for _, origin := range origins {
+		allowed[origin] = true
+	}
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
-			writer.Header().Set("Access-Control-Allow-Origin", "*")
+			if allowed["*"] {
+				writer.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				// the response depends on the origin, so caches must not serve it to other origins
+				writer.Header().Add("Vary", "Origin")
+				if origin := request.Header.Get("Origin"); allowed[origin] {
+					writer.Header().Set("Access-Control-Allow-Origin", origin)
+				}
+			}
 			writer.Header().Set("Access-Control-Allow-Headers", "*")
 			writer.Header().Set("Access-Control-Allow-Methods", "*")
+			// without this the browsers hide the headers from the scripts
+			writer.Header().Set("Access-Control-Expose-Headers", "ETag, Link, X-Total-Count, "+httputil.RequestIDHeader)
 			next.ServeHTTP(writer, request)
 		})
 	}
@@ -0,0 +1,433 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"gifmanager-backend/config"
+	"gifmanager-backend/dal"
+	"gifmanager-backend/gifs"
+	"gifmanager-backend/httputil"
+	"gifmanager-backend/users"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestServer(t *testing.T) Server {
+	return newTestServerWithConfig(t, config.Default().Server)
+}
+
+func newTestServerWithConfig(t *testing.T, cfg config.Server) Server {
+	memoryDal := dal.NewMemoryDal()
+	_, errIndexes := dal.EnsureIndexes(context.Background(), memoryDal, dal.Indexes)
+	require.Nil(t, errIndexes)
+	tokens := users.NewTokenManager([]byte("secret"), time.Minute, time.Hour)
+	return NewServer(cfg, memoryDal, tokens, gifs.NewGifApi(memoryDal, httputil.NewGifsApiQueryParamParser()))
+}
+
+func serve(s Server, method, path, token string, body any) *httptest.ResponseRecorder {
+	bts, _ := json.Marshal(body)
+	request := httptest.NewRequest(method, path, bytes.NewReader(bts))
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	recorder := httptest.NewRecorder()
+	s.Handler.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func login(t *testing.T, s Server) users.LoginResponse {
+	registerRecorder := serve(s, http.MethodPost, "/register", "", users.RegisterRequest{UserName: "user@example.com", Password: "secret123"})
+	require.Equal(t, http.StatusCreated, registerRecorder.Code)
+
+	recorder := serve(s, http.MethodPost, "/login", "", users.LoginRequest{UserName: "user@example.com", Password: "secret123"})
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var response users.LoginResponse
+	require.Nil(t, json.NewDecoder(recorder.Body).Decode(&response))
+	return response
+}
+
+func TestServer_TokenLifecycle(t *testing.T) {
+	// 1. ARRANGE
+	s := newTestServer(t)
+	loginResponse := login(t, s)
+	require.NotEmpty(t, loginResponse.Token)
+	assert.Equal(t, "user@example.com", loginResponse.User.UserName)
+
+	// 2. ACT & 3. ASSERT
+	// the token grants access to the protected endpoints
+	assert.Equal(t, http.StatusOK, serve(s, http.MethodGet, "/gifs", loginResponse.Token, nil).Code)
+
+	// refreshing returns a new valid token and rotates the refresh token
+	refreshRecorder := serve(s, http.MethodPost, "/refresh", "", users.RefreshRequest{RefreshToken: loginResponse.RefreshToken})
+	require.Equal(t, http.StatusOK, refreshRecorder.Code)
+	var refreshResponse users.TokenResponse
+	require.Nil(t, json.NewDecoder(refreshRecorder.Body).Decode(&refreshResponse))
+	assert.NotEqual(t, loginResponse.RefreshToken, refreshResponse.RefreshToken)
+	assert.Equal(t, loginResponse.RefreshExpiresAt, refreshResponse.RefreshExpiresAt)
+	assert.Equal(t, http.StatusOK, serve(s, http.MethodGet, "/gifs", refreshResponse.Token, nil).Code)
+
+	// the tokens issued before the refresh are no longer accepted
+	assert.Equal(t, http.StatusUnauthorized, serve(s, http.MethodGet, "/gifs", loginResponse.Token, nil).Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(s, http.MethodPost, "/refresh", "", users.RefreshRequest{RefreshToken: loginResponse.RefreshToken}).Code)
+
+	// logging out revokes the session and every token issued for it
+	assert.Equal(t, http.StatusNoContent, serve(s, http.MethodPost, "/logout", refreshResponse.Token, nil).Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(s, http.MethodGet, "/gifs", refreshResponse.Token, nil).Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(s, http.MethodPost, "/refresh", "", users.RefreshRequest{RefreshToken: refreshResponse.RefreshToken}).Code)
+}
+
+func TestServer_Refresh_RejectsAccessTokensAndForgedRefreshTokens(t *testing.T) {
+	// 1. ARRANGE
+	s := newTestServer(t)
+	loginResponse := login(t, s)
+	sessionID, _, _ := strings.Cut(loginResponse.RefreshToken, ".")
+
+	testCases := []string{
+		// a leaked bearer token can't be used to mint new tokens
+		loginResponse.Token,
+		sessionID + ".forged",
+		"not-a-refresh-token",
+		"",
+	}
+
+	for _, refreshToken := range testCases {
+		// 2. ACT
+		recorder := serve(s, http.MethodPost, "/refresh", loginResponse.Token, users.RefreshRequest{RefreshToken: refreshToken})
+
+		// 3. ASSERT
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code, refreshToken)
+	}
+
+	// the session can still be refreshed with its refresh token
+	assert.Equal(t, http.StatusOK, serve(s, http.MethodPost, "/refresh", "", users.RefreshRequest{RefreshToken: loginResponse.RefreshToken}).Code)
+}
+
+func TestServer_RejectsMissingOrBasicAuthentication(t *testing.T) {
+	s := newTestServer(t)
+
+	assert.Equal(t, http.StatusUnauthorized, serve(s, http.MethodGet, "/gifs", "", nil).Code)
+
+	request := httptest.NewRequest(http.MethodGet, "/gifs", nil)
+	request.SetBasicAuth("user@example.com", "secret123")
+	recorder := httptest.NewRecorder()
+	s.Handler.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
+
+func TestServer_Register(t *testing.T) {
+	s := newTestServer(t)
+	request := users.RegisterRequest{UserName: "user@example.com", Password: "secret123"}
+
+	assert.Equal(t, http.StatusCreated, serve(s, http.MethodPost, "/register", "", request).Code)
+	assert.Equal(t, http.StatusConflict, serve(s, http.MethodPost, "/register", "", request).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(s, http.MethodPost, "/register", "", users.RegisterRequest{UserName: "not-an-email", Password: "secret123"}).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(s, http.MethodPost, "/register", "", users.RegisterRequest{UserName: "other@example.com", Password: "short"}).Code)
+}
+
+func TestServer_Login_RejectsUnknownUsersAndWrongPasswords(t *testing.T) {
+	s := newTestServer(t)
+	login(t, s)
+
+	// logging in no longer creates accounts
+	unknownUser := users.LoginRequest{UserName: "typo@example.com", Password: "secret123"}
+	assert.Equal(t, http.StatusUnauthorized, serve(s, http.MethodPost, "/login", "", unknownUser).Code)
+
+	wrongPassword := users.LoginRequest{UserName: "user@example.com", Password: "secret124"}
+	assert.Equal(t, http.StatusUnauthorized, serve(s, http.MethodPost, "/login", "", wrongPassword).Code)
+}
+
+func decodeErrorResponse(t *testing.T, recorder *httptest.ResponseRecorder) httputil.ErrorResponse {
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+
+	var response httputil.ErrorResponse
+	require.Nil(t, json.NewDecoder(recorder.Body).Decode(&response))
+	return response
+}
+
+func TestServer_ErrorsAreJSONWithCodeAndRequestID(t *testing.T) {
+	// 1. ARRANGE
+	s := newTestServer(t)
+	loginResponse := login(t, s)
+
+	testCases := []struct {
+		name           string
+		method         string
+		path           string
+		token          string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{"missing token", http.MethodGet, "/gifs", "", http.StatusUnauthorized, users.CodeMissingAuthentication},
+		{"invalid token", http.MethodGet, "/gifs", "not-a-token", http.StatusUnauthorized, users.CodeInvalidToken},
+		{"invalid id", http.MethodDelete, "/gifs/abc", loginResponse.Token, http.StatusBadRequest, httputil.CodeInvalidID},
+		{"unknown gif", http.MethodDelete, "/gifs/5f1f1f1f1f1f1f1f1f1f1f1f", loginResponse.Token, http.StatusNotFound, gifs.CodeGifNotFound},
+		{"invalid filter", http.MethodGet, "/gifs?filter=name", loginResponse.Token, http.StatusBadRequest, httputil.CodeInvalidFilter},
+		{"unknown route", http.MethodGet, "/unknown", loginResponse.Token, http.StatusNotFound, httputil.CodeNotFound},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			// 2. ACT
+			recorder := serve(s, testCase.method, testCase.path, testCase.token, nil)
+
+			// 3. ASSERT
+			require.Equal(t, testCase.expectedStatus, recorder.Code)
+			response := decodeErrorResponse(t, recorder)
+			assert.Equal(t, testCase.expectedCode, response.Code)
+			assert.NotEmpty(t, response.Message)
+			assert.NotEmpty(t, response.RequestID)
+			assert.Equal(t, recorder.Header().Get(httputil.RequestIDHeader), response.RequestID)
+		})
+	}
+}
+
+func TestServer_KeepsValidRequestIDs(t *testing.T) {
+	// 1. ARRANGE
+	s := newTestServer(t)
+	request := httptest.NewRequest(http.MethodGet, "/gifs", nil)
+	request.Header.Set(httputil.RequestIDHeader, "abc-123")
+	recorder := httptest.NewRecorder()
+
+	// 2. ACT
+	s.Handler.ServeHTTP(recorder, request)
+
+	// 3. ASSERT
+	assert.Equal(t, "abc-123", decodeErrorResponse(t, recorder).RequestID)
+}
+
+func TestServer_InvalidFilter_ReturnsPosition(t *testing.T) {
+	// 1. ARRANGE
+	s := newTestServer(t)
+	loginResponse := login(t, s)
+
+	// 2. ACT
+	recorder := serve(s, http.MethodGet, "/gifs?filter=name%3D", loginResponse.Token, nil)
+
+	// 3. ASSERT
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	response := decodeErrorResponse(t, recorder)
+	assert.Equal(t, map[string]any{"position": float64(6)}, response.Details)
+}
+
+func TestServer_CORS_AllowsEveryOriginByDefault(t *testing.T) {
+	// 1. ARRANGE
+	s := newTestServer(t)
+	request := httptest.NewRequest(http.MethodOptions, "/gifs", nil)
+	request.Header.Set("Origin", "https://example.com")
+	recorder := httptest.NewRecorder()
+
+	// 2. ACT
+	s.Handler.ServeHTTP(recorder, request)
+
+	// 3. ASSERT
+	assert.Equal(t, "*", recorder.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestServer_CORS_OnlyAllowsConfiguredOrigins(t *testing.T) {
+	cfg := config.Default().Server
+	cfg.CORSOrigins = []string{"https://gifs.example.com"}
+	s := newTestServerWithConfig(t, cfg)
+
+	tests := []struct {
+		origin         string
+		expectedOrigin string
+	}{
+		{"https://gifs.example.com", "https://gifs.example.com"},
+		{"https://evil.example.com", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.origin, func(t *testing.T) {
+			// 1. ARRANGE
+			request := httptest.NewRequest(http.MethodOptions, "/gifs", nil)
+			request.Header.Set("Origin", test.origin)
+			recorder := httptest.NewRecorder()
+
+			// 2. ACT
+			s.Handler.ServeHTTP(recorder, request)
+
+			// 3. ASSERT
+			assert.Equal(t, test.expectedOrigin, recorder.Header().Get("Access-Control-Allow-Origin"))
+			assert.Equal(t, "Origin", recorder.Header().Get("Vary"))
+		})
+	}
+}
+
+// startServer serves the handler on a free port until the returned cancel function is called, the channel receives
+// the result of Serve
+func startServer(t *testing.T, cfg config.Server, handler http.Handler) (string, context.CancelFunc, chan error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	errServe := make(chan error, 1)
+	go func() {
+		errServe <- Server{Handler: handler, config: cfg}.Serve(ctx, listener)
+	}()
+	return listener.Addr().String(), cancel, errServe
+}
+
+func TestServer_Serve_DrainsRequestsInFlightOnShutdown(t *testing.T) {
+	// 1. ARRANGE
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		close(started)
+		<-release
+		writer.WriteHeader(http.StatusNoContent)
+	})
+	address, cancel, errServe := startServer(t, config.Default().Server, handler)
+
+	response := make(chan *http.Response, 1)
+	go func() {
+		resp, err := http.Get("http://" + address)
+		if err == nil {
+			resp.Body.Close()
+		}
+		response <- resp
+	}()
+	<-started
+
+	// 2. ACT
+	cancel()
+
+	// 3. ASSERT
+	// the server waits for the request before it stops
+	select {
+	case err := <-errServe:
+		t.Fatalf("the server stopped before the request finished: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	_, errDial := net.Dial("tcp", address)
+	assert.NotNil(t, errDial, "the server still accepts connections")
+
+	close(release)
+	resp := <-response
+	require.NotNil(t, resp)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Nil(t, <-errServe)
+}
+
+func TestServer_Serve_ClosesRequestsInFlightAfterShutdownTimeout(t *testing.T) {
+	// 1. ARRANGE
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	handler := http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		close(started)
+		<-release
+	})
+	cfg := config.Default().Server
+	cfg.ShutdownTimeout = 50 * time.Millisecond
+	address, cancel, errServe := startServer(t, cfg, handler)
+
+	errRequest := make(chan error, 1)
+	go func() {
+		resp, err := http.Get("http://" + address)
+		if err == nil {
+			resp.Body.Close()
+		}
+		errRequest <- err
+	}()
+	<-started
+
+	// 2. ACT
+	cancel()
+
+	// 3. ASSERT
+	assert.ErrorIs(t, <-errServe, context.DeadlineExceeded)
+	select {
+	case err := <-errRequest:
+		assert.NotNil(t, err, "the request in flight wasn't cut off")
+	case <-time.After(time.Second):
+		t.Fatal("the connection of the request in flight is still open")
+	}
+}
+
+func TestServer_Serve_RejectsOversizedHeaders(t *testing.T) {
+	// 1. ARRANGE
+	cfg := config.Default().Server
+	cfg.MaxHeaderBytes = 1 << 10
+	address, _, _ := startServer(t, cfg, newTestServer(t).Handler)
+
+	request, err := http.NewRequest(http.MethodGet, "http://"+address+"/gifs", nil)
+	require.Nil(t, err)
+	request.Header.Set("X-Padding", strings.Repeat("a", 8<<10))
+
+	// 2. ACT
+	resp, err := http.DefaultClient.Do(request)
+
+	// 3. ASSERT
+	require.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusRequestHeaderFieldsTooLarge, resp.StatusCode)
+}
+
+func TestServer_Serve_TLS(t *testing.T) {
+	// 1. ARRANGE
+	cfg := config.Default().Server
+	cfg.TLSCertFile, cfg.TLSKeyFile = writeSelfSignedCertificate(t)
+	address, _, _ := startServer(t, cfg, newTestServer(t).Handler)
+
+	certificate, err := os.ReadFile(cfg.TLSCertFile)
+	require.Nil(t, err)
+	roots := x509.NewCertPool()
+	require.True(t, roots.AppendCertsFromPEM(certificate))
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+
+	// 2. ACT
+	resp, err := client.Get("https://" + address + "/gifs")
+
+	// 3. ASSERT
+	require.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+// writeSelfSignedCertificate writes a certificate for 127.0.0.1 and its key as PEM files
+func writeSelfSignedCertificate(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	certificate, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.Nil(t, err)
+	privateKey, err := x509.MarshalECPrivateKey(key)
+	require.Nil(t, err)
+
+	certFile := filepath.Join(t.TempDir(), "cert.pem")
+	keyFile := filepath.Join(t.TempDir(), "key.pem")
+	require.Nil(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate}), 0o600))
+	require.Nil(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: privateKey}), 0o600))
+	return certFile, keyFile
+}
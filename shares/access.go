@@ -0,0 +1,77 @@
+package shares
+
+import (
+	"context"
+	"gifmanager-backend/authz"
+	"gifmanager-backend/dal"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SharedGifsFilter returns a filter matching the gifs of other users that were shared, one by one or with their category,
+// with a group the user in the context belongs to. It returns nil when nothing is shared with the user.
+// The members of a group can only read what is shared with it, the gifs stay owned by the user who shared them.
+func SharedGifsFilter(ctx context.Context, d dal.DAL) (bson.M, error) {
+	groupIDs, err := memberGroupIDs(ctx, d)
+	if err != nil || len(groupIDs) == 0 {
+		return nil, err
+	}
+
+	userID := authz.UserID(ctx)
+	filter := bson.M{
+		GroupIDField:     bson.M{"$in": groupIDs},
+		authz.OwnerField: bson.M{"$ne": userID},
+	}
+	shares := make(Shares, 0)
+	if err := d.Find(ctx, dal.CollShares, *dal.NewFindArguments().WithFilter(filter), &shares); err != nil {
+		return nil, err
+	}
+
+	gifIDs, categoryIDs := bson.A{}, bson.A{}
+	for _, share := range shares {
+		if !share.GifId.IsZero() {
+			gifIDs = append(gifIDs, share.GifId)
+		}
+		if !share.CategoryId.IsZero() {
+			categoryIDs = append(categoryIDs, share.CategoryId)
+		}
+	}
+
+	// a gif can only be in a category of its owner, so the category is enough to find the shared gifs
+	shared := bson.A{}
+	if len(gifIDs) > 0 {
+		shared = append(shared, bson.M{"_id": bson.M{"$in": gifIDs}})
+	}
+	if len(categoryIDs) > 0 {
+		shared = append(shared, bson.M{"categoryId": bson.M{"$in": categoryIDs}})
+	}
+	if len(shared) == 0 {
+		return nil, nil
+	}
+	return bson.M{"$or": shared, authz.OwnerField: bson.M{"$ne": userID}}, nil
+}
+
+// memberGroupIDs returns the IDs of the groups the user in the context owns or is a member of.
+func memberGroupIDs(ctx context.Context, d dal.DAL) (bson.A, error) {
+	var groups []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	findArgs := dal.NewFindArguments().
+		WithFilter(authz.MemberOfGroups(ctx)).
+		WithProjection(dal.Projections{{FieldName: "_id"}})
+	if err := d.Find(ctx, dal.CollGroups, *findArgs, &groups); err != nil {
+		return nil, err
+	}
+
+	ids := make(bson.A, 0, len(groups))
+	for _, group := range groups {
+		ids = append(ids, group.ID)
+	}
+	return ids, nil
+}
+
+// Unshare removes the shares of the documents matching the filter, e.g. bson.M{GifIDField: gifID} when the gif is deleted.
+func Unshare(ctx context.Context, d dal.DAL, filter bson.M) error {
+	_, err := d.Delete(ctx, dal.CollShares, filter)
+	return err
+}
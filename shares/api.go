@@ -0,0 +1,151 @@
+package shares
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gifmanager-backend/authz"
+	"gifmanager-backend/dal"
+	"gifmanager-backend/httputil"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"time"
+)
+
+type Api struct {
+	Dal dal.DAL
+}
+
+func NewShareApi(dal dal.DAL) *Api {
+	return &Api{
+		Dal: dal,
+	}
+}
+
+func (api Api) InitializeEndpoints(route *mux.Router) {
+	route.
+		Path("/shares").
+		Methods(http.MethodPost).
+		Handler(http.HandlerFunc(api.CreateShareHandler))
+	route.
+		Path("/shares/{id}").
+		Methods(http.MethodDelete).
+		Handler(http.HandlerFunc(api.DeleteShareHandler))
+
+	route.
+		Path("/shares").
+		Methods(http.MethodGet).
+		Handler(http.HandlerFunc(api.GetSharesHandler))
+}
+
+// CreateShareHandler shares a gif or a category of the caller with a group the caller belongs to.
+func (api Api) CreateShareHandler(writer http.ResponseWriter, request *http.Request) {
+	ctx := request.Context()
+
+	var shareRequest ShareRequest
+	if decodeErr := httputil.DecodeJSON(writer, request, &shareRequest); decodeErr != nil {
+		httputil.WriteError(writer, decodeErr)
+		return
+	}
+
+	if err := api.checkShareable(ctx, shareRequest); err != nil {
+		httputil.WriteError(writer, err)
+		return
+	}
+
+	share := shareRequest.ToModel()
+	share.ID = primitive.NewObjectID()
+	share.UserId = authz.UserID(ctx)
+	share.CreatedAt = time.Now().UTC().Truncate(time.Millisecond)
+	if _, errInsert := api.Dal.Insert(ctx, dal.CollShares, []any{share}); errInsert != nil {
+		if errors.Is(errInsert, dal.ErrDuplicateKey) {
+			httputil.WriteError(writer, ErrShareExists)
+			return
+		}
+		fmt.Println(errInsert.Error())
+		httputil.WriteError(writer, ErrInsertingShare)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusCreated)
+	if errEncode := json.NewEncoder(writer).Encode(share.ToDto()); errEncode != nil {
+		fmt.Println(errEncode.Error())
+	}
+}
+
+// checkShareable checks that the caller belongs to the group and owns what is shared.
+// Groups, gifs and categories of other users are reported as missing.
+func (api Api) checkShareable(ctx context.Context, shareRequest ShareRequest) error {
+	groupFilter := bson.M{"$and": bson.A{bson.M{"_id": shareRequest.GroupId}, authz.MemberOfGroups(ctx)}}
+	if err := api.checkExists(ctx, dal.CollGroups, groupFilter, ErrGroupDoesNotExist); err != nil {
+		return err
+	}
+
+	if !shareRequest.GifId.IsZero() {
+		return api.checkExists(ctx, dal.CollGifs, authz.OwnedByID(ctx, authz.OwnerField, shareRequest.GifId), ErrGifDoesNotExist)
+	}
+	return api.checkExists(ctx, dal.CollCategories, authz.OwnedByID(ctx, authz.OwnerField, shareRequest.CategoryId), ErrCategoryDoesNotExist)
+}
+
+func (api Api) checkExists(ctx context.Context, collection string, filter bson.M, errMissing error) error {
+	count, err := api.Dal.Count(ctx, collection, filter)
+	if err != nil {
+		fmt.Println(err.Error())
+		return ErrFindingShares
+	}
+	if count == 0 {
+		return errMissing
+	}
+	return nil
+}
+
+// GetSharesHandler returns the shares the caller created, newest first.
+func (api Api) GetSharesHandler(writer http.ResponseWriter, request *http.Request) {
+	ctx := request.Context()
+
+	findArgs := dal.NewFindArguments().
+		WithFilter(authz.OwnedBy(ctx, authz.OwnerField, bson.M{})).
+		WithSorts(dal.Sorts{{FieldName: "createdAt", Ascending: false}, {FieldName: "_id", Ascending: false}})
+
+	shares := make(Shares, 0)
+	if err := api.Dal.Find(ctx, dal.CollShares, *findArgs, &shares); err != nil {
+		fmt.Println(err.Error())
+		httputil.WriteError(writer, ErrFindingShares)
+		return
+	}
+
+	if err := httputil.WriteJSONWithETag(writer, request, shares.ToDto()); err != nil {
+		fmt.Println(err.Error())
+		httputil.WriteError(writer, ErrEncodingShares)
+	}
+}
+
+// DeleteShareHandler stops sharing, only the user who created the share can delete it.
+func (api Api) DeleteShareHandler(writer http.ResponseWriter, request *http.Request) {
+	ctx := request.Context()
+	id := mux.Vars(request)["id"]
+
+	shareID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		httputil.WriteError(writer, httputil.ErrInvalidID.WithMessagef(ErrInvalidIDFmt, id))
+		return
+	}
+
+	result, err := api.Dal.Delete(ctx, dal.CollShares, authz.OwnedByID(ctx, authz.OwnerField, shareID))
+	if err != nil {
+		fmt.Println(err.Error())
+		httputil.WriteError(writer, ErrDeletingShare)
+		return
+	}
+
+	if result.DeletedCount == 0 {
+		httputil.WriteError(writer, ErrShareNotFound.WithMessagef(ErrShareNotFoundFmt, id))
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
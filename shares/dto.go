@@ -0,0 +1,39 @@
+package shares
+
+import (
+	"gifmanager-backend/httputil"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// ShareRequest shares either a gif or a category with a group.
+type ShareRequest struct {
+	GroupId    primitive.ObjectID `json:"groupId"`
+	GifId      primitive.ObjectID `json:"gifId"`
+	CategoryId primitive.ObjectID `json:"categoryId"`
+}
+
+func (s ShareRequest) Validate() httputil.ValidationErrors {
+	var errs httputil.ValidationErrors
+	errs.ObjectID("groupId", s.GroupId)
+	if s.GifId.IsZero() == s.CategoryId.IsZero() {
+		errs.Add("gifId", "exactly one of gifId and categoryId is required")
+	}
+	return errs
+}
+
+func (s ShareRequest) ToModel() Share {
+	return Share{
+		GroupId:    s.GroupId,
+		GifId:      s.GifId,
+		CategoryId: s.CategoryId,
+	}
+}
+
+type ShareDto struct {
+	ID         string    `json:"id"`
+	GroupID    string    `json:"groupId"`
+	GifID      string    `json:"gifId,omitempty"`
+	CategoryID string    `json:"categoryId,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
@@ -0,0 +1,36 @@
+package shares
+
+import (
+	"gifmanager-backend/httputil"
+	"net/http"
+)
+
+const (
+	ErrInvalidIDFmt     = "invalid id specified: %s"
+	ErrShareNotFoundFmt = "share with id %s does not exist"
+)
+
+const (
+	CodeShareNotFound = "share_not_found"
+	CodeShareExists   = "share_exists"
+)
+
+var (
+	ErrInsertingShare = httputil.NewError(http.StatusInternalServerError, httputil.CodeInternal, "error encountered on inserting the share")
+	ErrEncodingShares = httputil.NewError(http.StatusInternalServerError, httputil.CodeInternal, "error encountered on encoding the shares")
+	ErrFindingShares  = httputil.NewError(http.StatusInternalServerError, httputil.CodeInternal, "error encountered on retrieving the shares")
+	ErrDeletingShare  = httputil.NewError(http.StatusInternalServerError, httputil.CodeInternal, "error encountered while deleting the share")
+
+	ErrShareNotFound = httputil.NewError(http.StatusNotFound, CodeShareNotFound, "share does not exist")
+	ErrShareExists   = httputil.NewError(http.StatusConflict, CodeShareExists, "it is already shared with the group")
+
+	ErrGroupDoesNotExist = httputil.ErrValidationFailed.WithDetails(httputil.ValidationErrors{
+		{Field: "groupId", Message: "does not exist"},
+	})
+	ErrGifDoesNotExist = httputil.ErrValidationFailed.WithDetails(httputil.ValidationErrors{
+		{Field: "gifId", Message: "does not exist"},
+	})
+	ErrCategoryDoesNotExist = httputil.ErrValidationFailed.WithDetails(httputil.ValidationErrors{
+		{Field: "categoryId", Message: "does not exist"},
+	})
+)
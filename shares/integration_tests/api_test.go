@@ -0,0 +1,264 @@
+package integration_tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"gifmanager-backend/categories"
+	"gifmanager-backend/dal"
+	"gifmanager-backend/gifs"
+	"gifmanager-backend/groups"
+	"gifmanager-backend/httputil"
+	"gifmanager-backend/shares"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// insertGroup stores a group owned by ownerID with the given members and removes it after the test
+func insertGroup(t *testing.T, ownerID primitive.ObjectID, members ...primitive.ObjectID) groups.Group {
+	group := groups.Group{
+		ID:      primitive.NewObjectID(),
+		Name:    t.Name(),
+		UserId:  ownerID,
+		Members: []groups.Member{},
+	}
+	for _, member := range members {
+		group.Members = append(group.Members, groups.Member{UserId: member, Role: groups.RoleMember})
+	}
+	_, errInsert := mongoDal.Insert(context.Background(), dal.CollGroups, []any{group})
+	require.Nil(t, errInsert)
+
+	t.Cleanup(func() {
+		mongoDal.Delete(context.Background(), dal.CollGroups, bson.M{"_id": group.ID})
+	})
+	return group
+}
+
+// insertGif stores a gif owned by ownerID and removes it, and the shares of its owner, after the test
+func insertGif(t *testing.T, ownerID primitive.ObjectID) gifs.Gif {
+	gif := gifs.Gif{
+		ID:      primitive.NewObjectID(),
+		Name:    t.Name(),
+		URL:     "https://media.giphy.com/gif.gif",
+		UserId:  ownerID,
+		Version: 1,
+	}
+	_, errInsert := mongoDal.Insert(context.Background(), dal.CollGifs, []any{gif})
+	require.Nil(t, errInsert)
+
+	t.Cleanup(func() {
+		mongoDal.Delete(context.Background(), dal.CollGifs, bson.M{"_id": gif.ID})
+		mongoDal.Delete(context.Background(), dal.CollShares, bson.M{"userId": ownerID})
+	})
+	return gif
+}
+
+func newShareRequest(method string, userID primitive.ObjectID, shareID primitive.ObjectID, body any) *http.Request {
+	bts, _ := json.Marshal(body)
+	request := httptest.NewRequest(method, "/shares", bytes.NewReader(bts))
+	request = request.WithContext(context.WithValue(context.Background(), "userID", userID))
+	return mux.SetURLVars(request, map[string]string{
+		"id": shareID.Hex(),
+	})
+}
+
+func decodeValidationErrors(t *testing.T, responseRecorder *httptest.ResponseRecorder) httputil.ValidationErrors {
+	var errorResponse struct {
+		Details httputil.ValidationErrors `json:"details"`
+	}
+	require.Nil(t, json.Unmarshal(responseRecorder.Body.Bytes(), &errorResponse))
+	return errorResponse.Details
+}
+
+func TestCreateShareHandler_Gif_ExpectedCreated(t *testing.T) {
+	// 1.ARRANGE
+	ownerID := primitive.NewObjectID()
+	group := insertGroup(t, ownerID, primitive.NewObjectID())
+	gif := insertGif(t, ownerID)
+	request := newShareRequest(http.MethodPost, ownerID, primitive.NilObjectID, shares.ShareRequest{GroupId: group.ID, GifId: gif.ID})
+	responseRecorder := httptest.NewRecorder()
+	api := shares.NewShareApi(mongoDal)
+
+	// 2.ACT
+	api.CreateShareHandler(responseRecorder, request)
+
+	// 3.ASSERT
+	require.Equal(t, http.StatusCreated, responseRecorder.Code)
+
+	var shareDto shares.ShareDto
+	require.Nil(t, json.Unmarshal(responseRecorder.Body.Bytes(), &shareDto))
+	assert.Equal(t, group.ID.Hex(), shareDto.GroupID)
+	assert.Equal(t, gif.ID.Hex(), shareDto.GifID)
+	assert.Empty(t, shareDto.CategoryID)
+
+	var dbShare shares.Share
+	require.Nil(t, mongoDal.FindByID(context.Background(), dal.CollShares, shareDto.ID, &dbShare))
+	assert.Equal(t, ownerID, dbShare.UserId)
+	assert.Equal(t, gif.ID, dbShare.GifId)
+}
+
+func TestCreateShareHandler_MemberSharesWithGroup_ExpectedCreated(t *testing.T) {
+	// 1.ARRANGE
+	memberID := primitive.NewObjectID()
+	group := insertGroup(t, primitive.NewObjectID(), memberID)
+	category := categories.Category{ID: primitive.NewObjectID(), Name: t.Name(), UserId: memberID, Version: 1}
+	_, errInsert := mongoDal.Insert(context.Background(), dal.CollCategories, []any{category})
+	require.Nil(t, errInsert)
+	t.Cleanup(func() {
+		mongoDal.Delete(context.Background(), dal.CollCategories, bson.M{"_id": category.ID})
+		mongoDal.Delete(context.Background(), dal.CollShares, bson.M{"userId": memberID})
+	})
+
+	request := newShareRequest(http.MethodPost, memberID, primitive.NilObjectID, shares.ShareRequest{GroupId: group.ID, CategoryId: category.ID})
+	responseRecorder := httptest.NewRecorder()
+	api := shares.NewShareApi(mongoDal)
+
+	// 2.ACT
+	api.CreateShareHandler(responseRecorder, request)
+
+	// 3.ASSERT
+	require.Equal(t, http.StatusCreated, responseRecorder.Code)
+
+	var shareDto shares.ShareDto
+	require.Nil(t, json.Unmarshal(responseRecorder.Body.Bytes(), &shareDto))
+	assert.Equal(t, category.ID.Hex(), shareDto.CategoryID)
+	assert.Empty(t, shareDto.GifID)
+}
+
+func TestCreateShareHandler_Twice_ExpectedConflict(t *testing.T) {
+	// 1.ARRANGE
+	ownerID := primitive.NewObjectID()
+	group := insertGroup(t, ownerID)
+	gif := insertGif(t, ownerID)
+	body := shares.ShareRequest{GroupId: group.ID, GifId: gif.ID}
+	api := shares.NewShareApi(mongoDal)
+
+	firstRecorder := httptest.NewRecorder()
+	api.CreateShareHandler(firstRecorder, newShareRequest(http.MethodPost, ownerID, primitive.NilObjectID, body))
+	require.Equal(t, http.StatusCreated, firstRecorder.Code)
+
+	responseRecorder := httptest.NewRecorder()
+
+	// 2.ACT
+	api.CreateShareHandler(responseRecorder, newShareRequest(http.MethodPost, ownerID, primitive.NilObjectID, body))
+
+	// 3.ASSERT
+	assert.Equal(t, http.StatusConflict, responseRecorder.Code)
+	assert.Contains(t, responseRecorder.Body.String(), shares.CodeShareExists)
+
+	count, err := mongoDal.Count(context.Background(), dal.CollShares, bson.M{"gifId": gif.ID})
+	require.Nil(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestCreateShareHandler_InvalidRequests_ExpectedErrors(t *testing.T) {
+	ownerID := primitive.NewObjectID()
+	ownGroup := insertGroup(t, ownerID)
+	foreignGroup := insertGroup(t, primitive.NewObjectID())
+	ownGif := insertGif(t, ownerID)
+	foreignGif := insertGif(t, primitive.NewObjectID())
+
+	tests := []struct {
+		name          string
+		body          shares.ShareRequest
+		expectedField string
+	}{
+		{"missing group", shares.ShareRequest{GifId: ownGif.ID}, "groupId"},
+		{"gif and category", shares.ShareRequest{GroupId: ownGroup.ID, GifId: ownGif.ID, CategoryId: primitive.NewObjectID()}, "gifId"},
+		{"neither gif nor category", shares.ShareRequest{GroupId: ownGroup.ID}, "gifId"},
+		{"group the user doesn't belong to", shares.ShareRequest{GroupId: foreignGroup.ID, GifId: ownGif.ID}, "groupId"},
+		{"gif of another user", shares.ShareRequest{GroupId: ownGroup.ID, GifId: foreignGif.ID}, "gifId"},
+		{"category of another user", shares.ShareRequest{GroupId: ownGroup.ID, CategoryId: primitive.NewObjectID()}, "categoryId"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// 1.ARRANGE
+			responseRecorder := httptest.NewRecorder()
+			api := shares.NewShareApi(mongoDal)
+
+			// 2.ACT
+			api.CreateShareHandler(responseRecorder, newShareRequest(http.MethodPost, ownerID, primitive.NilObjectID, test.body))
+
+			// 3.ASSERT
+			assert.Equal(t, http.StatusUnprocessableEntity, responseRecorder.Code)
+			details := decodeValidationErrors(t, responseRecorder)
+			require.Len(t, details, 1)
+			assert.Equal(t, test.expectedField, details[0].Field)
+		})
+	}
+
+	count, err := mongoDal.Count(context.Background(), dal.CollShares, bson.M{"userId": ownerID})
+	require.Nil(t, err)
+	assert.Zero(t, count)
+}
+
+func TestGetSharesHandler_ExpectedOnlyOwnShares(t *testing.T) {
+	// 1.ARRANGE
+	ownerID := primitive.NewObjectID()
+	otherID := primitive.NewObjectID()
+	group := insertGroup(t, ownerID, otherID)
+	ownShare := shares.Share{ID: primitive.NewObjectID(), UserId: ownerID, GroupId: group.ID, GifId: insertGif(t, ownerID).ID}
+	otherShare := shares.Share{ID: primitive.NewObjectID(), UserId: otherID, GroupId: group.ID, GifId: insertGif(t, otherID).ID}
+	_, errInsert := mongoDal.Insert(context.Background(), dal.CollShares, []any{ownShare, otherShare})
+	require.Nil(t, errInsert)
+
+	request := newShareRequest(http.MethodGet, ownerID, primitive.NilObjectID, nil)
+	responseRecorder := httptest.NewRecorder()
+	api := shares.NewShareApi(mongoDal)
+
+	// 2.ACT
+	api.GetSharesHandler(responseRecorder, request)
+
+	// 3.ASSERT
+	require.Equal(t, http.StatusOK, responseRecorder.Code)
+
+	var shareDtos []shares.ShareDto
+	require.Nil(t, json.Unmarshal(responseRecorder.Body.Bytes(), &shareDtos))
+	require.Len(t, shareDtos, 1)
+	assert.Equal(t, ownShare.ID.Hex(), shareDtos[0].ID)
+}
+
+func TestDeleteShareHandler(t *testing.T) {
+	ownerID := primitive.NewObjectID()
+	group := insertGroup(t, ownerID)
+	share := shares.Share{ID: primitive.NewObjectID(), UserId: ownerID, GroupId: group.ID, GifId: insertGif(t, ownerID).ID}
+	_, errInsert := mongoDal.Insert(context.Background(), dal.CollShares, []any{share})
+	require.Nil(t, errInsert)
+
+	t.Run("another user", func(t *testing.T) {
+		// 1.ARRANGE
+		responseRecorder := httptest.NewRecorder()
+		api := shares.NewShareApi(mongoDal)
+
+		// 2.ACT
+		api.DeleteShareHandler(responseRecorder, newShareRequest(http.MethodDelete, primitive.NewObjectID(), share.ID, nil))
+
+		// 3.ASSERT
+		assert.Equal(t, http.StatusNotFound, responseRecorder.Code)
+		count, err := mongoDal.Count(context.Background(), dal.CollShares, bson.M{"_id": share.ID})
+		require.Nil(t, err)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("owner", func(t *testing.T) {
+		// 1.ARRANGE
+		responseRecorder := httptest.NewRecorder()
+		api := shares.NewShareApi(mongoDal)
+
+		// 2.ACT
+		api.DeleteShareHandler(responseRecorder, newShareRequest(http.MethodDelete, ownerID, share.ID, nil))
+
+		// 3.ASSERT
+		assert.Equal(t, http.StatusNoContent, responseRecorder.Code)
+		count, err := mongoDal.Count(context.Background(), dal.CollShares, bson.M{"_id": share.ID})
+		require.Nil(t, err)
+		assert.Zero(t, count)
+	})
+}
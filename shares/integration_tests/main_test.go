@@ -0,0 +1,63 @@
+package integration_tests
+
+import (
+	"context"
+	"gifmanager-backend/config"
+	"gifmanager-backend/dal"
+	"os"
+	"testing"
+)
+
+var mongoDal dal.DAL
+
+// In Go, TestMain is a special function that can be included in test files.
+// It allows for custom setup and teardown logic that should run once before and after running any test functions in the same package.
+func TestMain(m *testing.M) {
+	// the setUp function is called before running the tests
+	tearDown := setUp()
+	// m.Run() runs the tests
+	code := m.Run()
+	// tearDown is a function that we are calling after the tests finish
+	// its job is to release resources that are no longer in use
+	// in our case it will just disconnect from the database
+	tearDown()
+
+	os.Exit(code)
+}
+
+func setUp() func() {
+
+	// when MONGO_URI is not set the tests run against the in-memory DAL, so no database is needed
+	cfg, err := config.LoadForTests(os.LookupEnv)
+	if err != nil {
+		panic(err)
+	}
+	if cfg.Mongo.InMemory {
+		mongoDal = dal.NewMemoryDal()
+		ensureIndexes()
+		return func() {}
+	}
+
+	// connect to the mongo but not to the gif-manager database
+	// connect to a new database which we are going to use for the tests
+	mongoDal, err = dal.NewMongoDal(context.Background(), cfg.Mongo.URI, cfg.Mongo.Database)
+	if err != nil {
+		panic(err)
+	}
+	ensureIndexes()
+
+	// return a tearDown() func
+	return func() {
+		// disconnect from the database
+		if err := mongoDal.Disconnect(context.Background()); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// ensureIndexes creates the indexes of dal.Indexes, the duplicate shares are rejected by the unique index of the shares
+func ensureIndexes() {
+	if _, err := dal.EnsureIndexes(context.Background(), mongoDal, dal.Indexes); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,48 @@
+package shares
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// bson field names of a share, the other packages use them to remove the shares of the documents they delete
+const (
+	GroupIDField    = "groupId"
+	GifIDField      = "gifId"
+	CategoryIDField = "categoryId"
+)
+
+// Share makes a gif, or every gif of a category, visible to the members of a group. Only one of GifId and CategoryId is set.
+type Share struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"`
+	UserId     primitive.ObjectID `bson:"userId"`
+	GroupId    primitive.ObjectID `bson:"groupId"`
+	GifId      primitive.ObjectID `bson:"gifId,omitempty"`
+	CategoryId primitive.ObjectID `bson:"categoryId,omitempty"`
+	CreatedAt  time.Time          `bson:"createdAt"`
+}
+
+type Shares []Share
+
+func (s Share) ToDto() ShareDto {
+	dto := ShareDto{
+		ID:        s.ID.Hex(),
+		GroupID:   s.GroupId.Hex(),
+		CreatedAt: s.CreatedAt,
+	}
+	if !s.GifId.IsZero() {
+		dto.GifID = s.GifId.Hex()
+	}
+	if !s.CategoryId.IsZero() {
+		dto.CategoryID = s.CategoryId.Hex()
+	}
+	return dto
+}
+
+func (s Shares) ToDto() []ShareDto {
+	dtos := make([]ShareDto, 0, len(s))
+	for _, share := range s {
+		dtos = append(dtos, share.ToDto())
+	}
+	return dtos
+}
@@ -3,6 +3,7 @@ package users
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"gifmanager-backend/dal"
 	"gifmanager-backend/httputil"
@@ -11,96 +12,302 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"net/http"
 	"regexp"
+	"time"
 )
 
 var emailRegex = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
 
 type Api struct {
-	Dal dal.DAL
+	Dal    dal.DAL
+	Tokens *TokenManager
 }
 
-func NewLoginApi(dal dal.DAL) *Api {
+func NewLoginApi(dal dal.DAL, tokens *TokenManager) *Api {
 	return &Api{
-		Dal: dal,
+		Dal:    dal,
+		Tokens: tokens,
 	}
 }
 
-func (api Api) InitializeEndpoints(route *mux.Router) {
+// InitializePublicEndpoints registers the endpoints that don't require a valid token.
+func (api Api) InitializePublicEndpoints(route *mux.Router) {
+	route.
+		Path("/register").
+		Methods(http.MethodPost).
+		Handler(http.HandlerFunc(api.RegisterHandler))
 	route.
 		Path("/login").
 		Methods(http.MethodPost).
 		Handler(http.HandlerFunc(api.LoginHandler))
+	route.
+		Path("/refresh").
+		Methods(http.MethodPost).
+		Handler(http.HandlerFunc(api.RefreshHandler))
+}
+
+func (api Api) InitializeEndpoints(route *mux.Router) {
+	route.
+		Path("/logout").
+		Methods(http.MethodPost).
+		Handler(http.HandlerFunc(api.LogoutHandler))
+}
+
+// RegisterHandler creates a new user. Usernames are unique, which is enforced by the username_unique index of dal.Indexes.
+func (api Api) RegisterHandler(writer http.ResponseWriter, request *http.Request) {
+	ctx := request.Context()
+
+	var registerRequest RegisterRequest
+	if decodeErr := httputil.DecodeJSON(writer, request, &registerRequest); decodeErr != nil {
+		httputil.WriteError(writer, decodeErr)
+		return
+	}
+	if !isValidEmail(registerRequest.UserName) {
+		httputil.WriteError(writer, ErrInvalidEmail)
+		return
+	}
+
+	if !isValidPassword(registerRequest.Password) {
+		httputil.WriteError(writer, ErrInvalidPassword)
+		return
+	}
+
+	hash, err := HashPassword(registerRequest.Password)
+	if err != nil {
+		fmt.Println(err.Error())
+		httputil.WriteError(writer, ErrCreatingUser)
+		return
+	}
+
+	newUser := User{
+		ID:       primitive.NewObjectID(),
+		UserName: registerRequest.UserName,
+		Password: hash,
+	}
+	if _, err := api.Dal.Insert(ctx, dal.CollUsers, []any{newUser}); err != nil {
+		if errors.Is(err, dal.ErrDuplicateKey) {
+			httputil.WriteError(writer, ErrUserExists)
+			return
+		}
+		fmt.Println(err.Error())
+		httputil.WriteError(writer, ErrCreatingUser)
+		return
+	}
 
+	writer.WriteHeader(http.StatusCreated)
+	if errEncode := json.NewEncoder(writer).Encode(newUser.ToDTO()); errEncode != nil {
+		fmt.Println(errEncode.Error())
+	}
 }
 
 // Handle decoding of the login request
-// Validate email and password
-// Authenticate user
-// Omit the password in the response
-// Encode the user as JSON and write to the response
+// Validate email
+// Authenticate user, unknown users and wrong passwords are rejected the same way
+// Start a new session and issue a bearer token and a refresh token for it
+// Encode the tokens and the user (without the password) as JSON and write to the response
 
 func (api Api) LoginHandler(writer http.ResponseWriter, request *http.Request) {
 
 	var loginRequest LoginRequest
-	if decodeErr := json.NewDecoder(request.Body).Decode(&loginRequest); decodeErr != nil {
-		httputil.WriteHttpError(writer, http.StatusBadRequest, fmt.Sprintf("error while decoding the request: %s", decodeErr.Error()))
+	if decodeErr := httputil.DecodeJSON(writer, request, &loginRequest); decodeErr != nil {
+		httputil.WriteError(writer, decodeErr)
 		return
 	}
 	if !isValidEmail(loginRequest.UserName) {
-		httputil.WriteHttpError(writer, http.StatusBadRequest, fmt.Sprintf("invalid email address"))
+		httputil.WriteError(writer, ErrInvalidEmail)
 		return
 	}
 
-	if !isValidPassword(loginRequest.Password) {
-		httputil.WriteHttpError(writer, http.StatusBadRequest, fmt.Sprintf("invalid password. it should be at least 8 characters long and contain both a letter and a digit."))
+	user, err := CheckCredentials(request.Context(), api.Dal, loginRequest.UserName, loginRequest.Password)
+	if err != nil {
+		if !errors.Is(err, ErrInvalidCredentials) {
+			fmt.Println(err.Error())
+		}
+		httputil.WriteError(writer, ErrInvalidCredentials)
 		return
 	}
 
-	user, err := api.authenticateUser(loginRequest.UserName, loginRequest.Password)
+	sessionID := primitive.NewObjectID()
+	refreshToken, refreshTokenHash, err := newRefreshToken(sessionID)
 	if err != nil {
-		httputil.WriteHttpError(writer, http.StatusUnauthorized, fmt.Sprintf("authentication failed"))
+		fmt.Println(err.Error())
+		httputil.WriteError(writer, ErrCreatingSession)
 		return
 	}
-	userDTO := user.ToDTO()
 
-	if errEncode := json.NewEncoder(writer).Encode(userDTO); errEncode != nil {
-		writer.WriteHeader(http.StatusInternalServerError)
-		_, errWrite := writer.Write([]byte(fmt.Sprintf("error encoding user: %s", errEncode.Error())))
-		if errWrite != nil {
-			fmt.Println(errWrite.Error())
-		}
+	// mongo stores dates in milliseconds, so the login and the refreshes return the same RefreshExpiresAt
+	now := time.Now().Truncate(time.Millisecond)
+	session := Session{
+		ID:               sessionID,
+		UserID:           user.ID,
+		CreatedAt:        now,
+		RefreshExpiresAt: now.Add(api.Tokens.RefreshTTL),
+		RefreshTokenHash: refreshTokenHash,
+	}
+	if _, err := api.Dal.Insert(request.Context(), dal.CollSessions, []any{session}); err != nil {
+		fmt.Println(err.Error())
+		httputil.WriteError(writer, ErrCreatingSession)
+		return
+	}
+
+	tokens, err := api.issueTokens(session, refreshToken)
+	if err != nil {
+		fmt.Println(err.Error())
+		httputil.WriteError(writer, ErrIssuingToken)
+		return
+	}
+
+	response := LoginResponse{
+		TokenResponse: tokens,
+		User:          user.ToDTO(),
+	}
+	if errEncode := json.NewEncoder(writer).Encode(response); errEncode != nil {
+		fmt.Println(errEncode.Error())
+		httputil.WriteError(writer, ErrEncodingLoginResponses)
 		return
 	}
 
 	writer.WriteHeader(http.StatusOK)
 }
 
-func (api Api) authenticateUser(userName, password string) (*User, error) {
-	var result []User
+// RefreshHandler exchanges the refresh token of a session for a new bearer token and a new refresh token, until the
+// session's RefreshExpiresAt. The refresh token is rotated, so it can only be used once, and the session moves to a new
+// generation, so the bearer tokens issued before are no longer accepted.
+func (api Api) RefreshHandler(writer http.ResponseWriter, request *http.Request) {
+	ctx := request.Context()
 
-	ctx := context.Background()
+	var refreshRequest RefreshRequest
+	if decodeErr := httputil.DecodeJSON(writer, request, &refreshRequest); decodeErr != nil {
+		httputil.WriteError(writer, decodeErr)
+		return
+	}
+	sessionID, ok := parseRefreshToken(refreshRequest.RefreshToken)
+	if !ok {
+		httputil.WriteError(writer, ErrInvalidToken)
+		return
+	}
+
+	session, err := findSession(ctx, api.Dal, sessionID.Hex())
+	if errors.Is(err, ErrInvalidToken) {
+		httputil.WriteError(writer, ErrSessionExpired)
+		return
+	}
+	if err != nil {
+		fmt.Println(err.Error())
+		httputil.WriteError(writer, ErrFindingSession)
+		return
+	}
+	if !matchesRefreshToken(*session, refreshRequest.RefreshToken) || time.Now().After(session.RefreshExpiresAt) {
+		httputil.WriteError(writer, ErrSessionExpired)
+		return
+	}
+
+	rotatedToken, rotatedTokenHash, err := newRefreshToken(session.ID)
+	if err != nil {
+		fmt.Println(err.Error())
+		httputil.WriteError(writer, ErrIssuingToken)
+		return
+	}
+
+	// the filter on the old hash makes sure a refresh token can't be used twice, even by concurrent requests
+	filter := bson.M{"_id": session.ID, "refreshTokenHash": session.RefreshTokenHash}
+	update := bson.M{"$set": bson.M{"refreshTokenHash": rotatedTokenHash}, "$inc": bson.M{"generation": 1}}
+	result, err := api.Dal.Update(ctx, dal.CollSessions, filter, update)
+	if err != nil {
+		fmt.Println(err.Error())
+		httputil.WriteError(writer, ErrIssuingToken)
+		return
+	}
+	if result.MatchedCount == 0 {
+		httputil.WriteError(writer, ErrSessionExpired)
+		return
+	}
+	session.RefreshTokenHash = rotatedTokenHash
+	session.Generation++
+
+	response, err := api.issueTokens(*session, rotatedToken)
+	if err != nil {
+		fmt.Println(err.Error())
+		httputil.WriteError(writer, ErrIssuingToken)
+		return
+	}
+	if errEncode := json.NewEncoder(writer).Encode(response); errEncode != nil {
+		fmt.Println(errEncode.Error())
+		httputil.WriteError(writer, ErrEncodingLoginResponses)
+		return
+	}
+}
+
+// issueTokens issues a bearer token for the current generation of the session and returns it with the refresh token.
+func (api Api) issueTokens(session Session, refreshToken string) (TokenResponse, error) {
+	token, claims, err := api.Tokens.Issue(session.ID.Hex(), session.UserID.Hex(), session.Generation)
+	if err != nil {
+		return TokenResponse{}, err
+	}
+	return TokenResponse{
+		Token:            token,
+		ExpiresAt:        time.Unix(claims.ExpiresAt, 0).UTC(),
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: session.RefreshExpiresAt.UTC(),
+	}, nil
+}
+
+// LogoutHandler revokes the session of the token the request was authenticated with,
+// so neither the token nor any token refreshed from it is accepted anymore.
+func (api Api) LogoutHandler(writer http.ResponseWriter, request *http.Request) {
+	ctx := request.Context()
+	sessionID, _ := primitive.ObjectIDFromHex(ctx.Value("sessionID").(string))
+
+	if _, err := api.Dal.Delete(ctx, dal.CollSessions, bson.M{"_id": sessionID}); err != nil {
+		fmt.Println(err.Error())
+		httputil.WriteError(writer, ErrRevokingSession)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// CheckCredentials finds the user by username and verifies the password.
+// It returns ErrInvalidCredentials when the user doesn't exist or the password is wrong.
+func CheckCredentials(ctx context.Context, d dal.DAL, userName, password string) (*User, error) {
+	var result []User
 
 	findArguments := dal.FindArguments{Filter: bson.M{"username": userName}}
-	if err := api.Dal.Find(ctx, dal.CollUsers, findArguments, &result); err != nil {
+	if err := d.Find(ctx, dal.CollUsers, findArguments, &result); err != nil {
 		return nil, err
 	}
-	// If either of these conditions is true, a new user is created. The password for the new user is hashed using bcrypt,
-	//a new unique ObjectID is generated for the user, and the user is inserted into the database. The newly created user is then returned.
 
 	if len(result) == 0 {
-		newUser := User{
-			ID:       primitive.NewObjectID(),
-			UserName: userName,
-			Password: password,
+		VerifyPassword(dummyPasswordHash, password)
+		return nil, ErrInvalidCredentials
+	}
+
+	return verifyCredentials(ctx, d, &result[0], password)
+}
+
+// verifyCredentials checks the password of the user and upgrades the stored password
+// to the current hash format when it is plaintext or outdated.
+func verifyCredentials(ctx context.Context, d dal.DAL, user *User, password string) (*User, error) {
+	ok, needsRehash := VerifyPassword(user.Password, password)
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+
+	if needsRehash {
+		hash, err := HashPassword(password)
+		if err != nil {
+			return nil, err
 		}
 
-		if _, err := api.Dal.Insert(ctx, dal.CollUsers, []interface{}{&newUser}); err != nil {
+		// the filter on the old value makes sure a concurrent password change isn't overwritten
+		filter := bson.M{"_id": user.ID, "password": user.Password}
+		update := bson.M{"$set": bson.M{"password": hash}}
+		if _, err := d.Update(ctx, dal.CollUsers, filter, update); err != nil {
 			return nil, err
 		}
-		return &newUser, nil
+		user.Password = hash
 	}
 
-	return &result[0], nil
+	return user, nil
 }
 
 func isValidEmail(email string) bool {
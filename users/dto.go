@@ -1,6 +1,31 @@
 package users
 
+import "time"
+
 type LoginRequest struct {
 	UserName string `json:"userName"`
 	Password string `json:"password"`
 }
+
+type RegisterRequest struct {
+	UserName string `json:"userName"`
+	Password string `json:"password"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+type LoginResponse struct {
+	TokenResponse
+	User UserDTO `json:"user"`
+}
+
+// TokenResponse is the bearer token and the refresh token that replaces it once it expired. Every refresh token
+// can only be used once.
+type TokenResponse struct {
+	Token            string    `json:"token"`
+	ExpiresAt        time.Time `json:"expiresAt"`
+	RefreshToken     string    `json:"refreshToken"`
+	RefreshExpiresAt time.Time `json:"refreshExpiresAt"`
+}
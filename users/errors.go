@@ -0,0 +1,36 @@
+package users
+
+import (
+	"gifmanager-backend/httputil"
+	"net/http"
+)
+
+const (
+	CodeInvalidEmail          = "invalid_email"
+	CodeInvalidPassword       = "invalid_password"
+	CodeUserExists            = "user_exists"
+	CodeInvalidCredentials    = "invalid_credentials"
+	CodeMissingAuthentication = "missing_authentication"
+	CodeInvalidToken          = "invalid_token"
+	CodeTokenExpired          = "token_expired"
+	CodeSessionExpired        = "session_expired"
+)
+
+var (
+	ErrInvalidEmail    = httputil.NewError(http.StatusBadRequest, CodeInvalidEmail, "invalid email address")
+	ErrInvalidPassword = httputil.NewError(http.StatusBadRequest, CodeInvalidPassword, "invalid password. it should be at least 8 characters long and contain both a letter and a digit.")
+	ErrUserExists      = httputil.NewError(http.StatusConflict, CodeUserExists, "a user with this email address already exists")
+
+	// ErrInvalidCredentials is returned for unknown users and wrong passwords alike
+	ErrInvalidCredentials     = httputil.NewError(http.StatusUnauthorized, CodeInvalidCredentials, "authentication failed")
+	ErrMissingAuthentication  = httputil.NewError(http.StatusUnauthorized, CodeMissingAuthentication, "missing authentication")
+	ErrInvalidToken           = httputil.NewError(http.StatusUnauthorized, CodeInvalidToken, "invalid token")
+	ErrTokenExpired           = httputil.NewError(http.StatusUnauthorized, CodeTokenExpired, "token expired")
+	ErrSessionExpired         = httputil.NewError(http.StatusUnauthorized, CodeSessionExpired, "the session has expired, please log in again")
+	ErrCreatingUser           = httputil.NewError(http.StatusInternalServerError, httputil.CodeInternal, "error while creating the user")
+	ErrCreatingSession        = httputil.NewError(http.StatusInternalServerError, httputil.CodeInternal, "error while creating the session")
+	ErrFindingSession         = httputil.NewError(http.StatusInternalServerError, httputil.CodeInternal, "error while fetching the session")
+	ErrRevokingSession        = httputil.NewError(http.StatusInternalServerError, httputil.CodeInternal, "error while revoking the session")
+	ErrIssuingToken           = httputil.NewError(http.StatusInternalServerError, httputil.CodeInternal, "error while issuing the token")
+	ErrEncodingLoginResponses = httputil.NewError(http.StatusInternalServerError, httputil.CodeInternal, "error while encoding the response")
+)
@@ -0,0 +1,59 @@
+package users
+
+import (
+	"context"
+	"gifmanager-backend/dal"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// the password hash never leaves this package, so the lookups don't read it
+var withoutPassword = dal.Projections{{FieldName: "password", ShouldExclude: true}}
+
+// FindByUserNames returns the users with the given usernames, which are the email addresses the users registered with.
+// Usernames without a user are missing from the result.
+func FindByUserNames(ctx context.Context, d dal.DAL, userNames []string) (map[string]User, error) {
+	names := make(bson.A, 0, len(userNames))
+	for _, userName := range userNames {
+		names = append(names, userName)
+	}
+
+	found, err := findUsers(ctx, d, bson.M{"username": bson.M{"$in": names}})
+	if err != nil {
+		return nil, err
+	}
+
+	byUserName := make(map[string]User, len(found))
+	for _, user := range found {
+		byUserName[user.UserName] = user
+	}
+	return byUserName, nil
+}
+
+// FindByIDs returns the users with the given IDs, IDs without a user are missing from the result.
+func FindByIDs(ctx context.Context, d dal.DAL, ids []primitive.ObjectID) (map[primitive.ObjectID]User, error) {
+	userIDs := make(bson.A, 0, len(ids))
+	for _, id := range ids {
+		userIDs = append(userIDs, id)
+	}
+
+	found, err := findUsers(ctx, d, bson.M{"_id": bson.M{"$in": userIDs}})
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[primitive.ObjectID]User, len(found))
+	for _, user := range found {
+		byID[user.ID] = user
+	}
+	return byID, nil
+}
+
+func findUsers(ctx context.Context, d dal.DAL, filter bson.M) ([]User, error) {
+	found := make([]User, 0)
+	findArgs := dal.NewFindArguments().WithFilter(filter).WithProjection(withoutPassword)
+	if err := d.Find(ctx, dal.CollUsers, *findArgs, &found); err != nil {
+		return nil, err
+	}
+	return found, nil
+}
@@ -4,8 +4,8 @@ import "go.mongodb.org/mongo-driver/bson/primitive"
 
 type User struct {
 	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
-	UserName string             `bson:"username"json:"username"`
-	Password string             `bson:"password" json:"password"`
+	UserName string             `bson:"username" json:"username"`
+	Password string             `bson:"password" json:"password"` // versioned hash created by HashPassword
 }
 type UserDTO struct {
 	ID       primitive.ObjectID `json:"id,omitempty"`
@@ -0,0 +1,45 @@
+package users
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+)
+
+// Stored passwords have the format "<version>:<hash>". Version v1 is a bcrypt hash, the cost is part of the hash itself.
+// Rows without a version prefix are legacy plaintext passwords and are upgraded on the next successful login.
+const (
+	passwordHashVersion = "v1"
+	passwordHashPrefix  = passwordHashVersion + ":"
+)
+
+// PasswordCost is the bcrypt cost used for new hashes. Hashes with a lower cost are rehashed on the next successful login.
+var PasswordCost = bcrypt.DefaultCost
+
+// dummyPasswordHash is compared against when the user doesn't exist, so unknown usernames take as long as wrong passwords.
+var dummyPasswordHash, _ = HashPassword("dummy-password-1")
+
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), PasswordCost)
+	if err != nil {
+		return "", fmt.Errorf("error hashing the password: %w", err)
+	}
+	return passwordHashPrefix + string(hash), nil
+}
+
+// VerifyPassword compares password with the stored value in constant time.
+// needsRehash is true when the password matches but the stored value is plaintext or uses an outdated version or cost.
+func VerifyPassword(stored, password string) (ok bool, needsRehash bool) {
+	hash, isHashed := strings.CutPrefix(stored, passwordHashPrefix)
+	if !isHashed {
+		return subtle.ConstantTimeCompare([]byte(stored), []byte(password)) == 1, true
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+		return false, false
+	}
+
+	cost, err := bcrypt.Cost([]byte(hash))
+	return true, err != nil || cost < PasswordCost
+}
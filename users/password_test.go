@@ -0,0 +1,58 @@
+package users_test
+
+import (
+	"context"
+	"gifmanager-backend/dal"
+	"gifmanager-backend/users"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"strings"
+	"testing"
+)
+
+func TestVerifyPassword(t *testing.T) {
+	hash, err := users.HashPassword("secret123")
+	require.Nil(t, err)
+	assert.True(t, strings.HasPrefix(hash, "v1:"))
+
+	ok, needsRehash := users.VerifyPassword(hash, "secret123")
+	assert.True(t, ok)
+	assert.False(t, needsRehash)
+
+	ok, _ = users.VerifyPassword(hash, "secret124")
+	assert.False(t, ok)
+
+	// legacy rows store the password in plain text
+	ok, needsRehash = users.VerifyPassword("secret123", "secret123")
+	assert.True(t, ok)
+	assert.True(t, needsRehash)
+}
+
+func TestCheckCredentials_UpgradesPlaintextPassword(t *testing.T) {
+	// 1. ARRANGE
+	memoryDal := dal.NewMemoryDal()
+	user := users.User{
+		ID:       primitive.NewObjectID(),
+		UserName: "user@example.com",
+		Password: "secret123",
+	}
+	_, errInsert := memoryDal.Insert(context.Background(), dal.CollUsers, []any{user})
+	require.Nil(t, errInsert)
+
+	// 2. ACT
+	_, errWrongPassword := users.CheckCredentials(context.Background(), memoryDal, user.UserName, "secret124")
+	authenticated, err := users.CheckCredentials(context.Background(), memoryDal, user.UserName, "secret123")
+
+	// 3. ASSERT
+	assert.Equal(t, users.ErrInvalidCredentials, errWrongPassword)
+	require.Nil(t, err)
+	assert.Equal(t, user.ID, authenticated.ID)
+
+	var dbUser users.User
+	require.Nil(t, memoryDal.FindByID(context.Background(), dal.CollUsers, user.ID.Hex(), &dbUser))
+	assert.True(t, strings.HasPrefix(dbUser.Password, "v1:"))
+	ok, needsRehash := users.VerifyPassword(dbUser.Password, "secret123")
+	assert.True(t, ok)
+	assert.False(t, needsRehash)
+}
@@ -0,0 +1,45 @@
+package users
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"strings"
+)
+
+// newRefreshToken returns a random refresh token for the session and the hash that is stored instead of it.
+// The token starts with the session ID, so the session can be found without an index on the hash.
+func newRefreshToken(sessionID primitive.ObjectID) (string, string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", fmt.Errorf("error generating the refresh token: %w", err)
+	}
+
+	token := sessionID.Hex() + "." + base64.RawURLEncoding.EncodeToString(secret)
+	return token, hashRefreshToken(token), nil
+}
+
+// parseRefreshToken returns the ID of the session the refresh token was issued for.
+func parseRefreshToken(token string) (primitive.ObjectID, bool) {
+	sessionID, _, found := strings.Cut(token, ".")
+	if !found {
+		return primitive.NilObjectID, false
+	}
+	id, err := primitive.ObjectIDFromHex(sessionID)
+	return id, err == nil
+}
+
+// hashRefreshToken hashes the token with SHA-256, the tokens are random, so they don't need a slow hash like passwords.
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// matchesRefreshToken compares the token with the hash of the session in constant time.
+func matchesRefreshToken(session Session, token string) bool {
+	return subtle.ConstantTimeCompare([]byte(session.RefreshTokenHash), []byte(hashRefreshToken(token))) == 1
+}
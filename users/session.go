@@ -0,0 +1,53 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"gifmanager-backend/dal"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+// Session is created on login and deleted on logout, or by the refreshExpiresAt_ttl index once it expired. Tokens are
+// only accepted while their session exists and only those of its current generation, every refresh starts a new one.
+// The session can be refreshed until RefreshExpiresAt with the refresh token whose hash it stores.
+type Session struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty"`
+	UserID           primitive.ObjectID `bson:"userId"`
+	CreatedAt        time.Time          `bson:"createdAt"`
+	RefreshExpiresAt time.Time          `bson:"refreshExpiresAt"`
+	RefreshTokenHash string             `bson:"refreshTokenHash"`
+	Generation       int                `bson:"generation"`
+}
+
+// ValidateToken verifies the token and checks that its session hasn't been revoked.
+// It only reads the sessions collection, never the users collection.
+func ValidateToken(ctx context.Context, d dal.DAL, tokens *TokenManager, token string) (TokenClaims, error) {
+	claims, err := tokens.Parse(token)
+	if err != nil {
+		return TokenClaims{}, err
+	}
+
+	session, err := findSession(ctx, d, claims.SessionID)
+	if err != nil {
+		return TokenClaims{}, err
+	}
+	if session.UserID.Hex() != claims.UserID || session.Generation != claims.Generation {
+		return TokenClaims{}, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// findSession returns the session or ErrInvalidToken when it was revoked.
+func findSession(ctx context.Context, d dal.DAL, sessionID string) (*Session, error) {
+	var session Session
+	err := d.FindByID(ctx, dal.CollSessions, sessionID, &session)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
@@ -0,0 +1,108 @@
+package users
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultTokenTTL   = 15 * time.Minute
+	DefaultRefreshTTL = 7 * 24 * time.Hour
+)
+
+// jwtHeader is the only header the TokenManager issues and accepts.
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// TokenClaims is the payload of the bearer tokens issued on login.
+type TokenClaims struct {
+	SessionID string `json:"sid"`
+	UserID    string `json:"sub"`
+	// Generation is the generation of the session the token was issued in, see Session
+	Generation int   `json:"gen"`
+	IssuedAt   int64 `json:"iat"`
+	ExpiresAt  int64 `json:"exp"`
+}
+
+// TokenManager issues and validates HMAC-SHA256 signed JWTs.
+type TokenManager struct {
+	secret     []byte
+	TTL        time.Duration
+	RefreshTTL time.Duration
+	now        func() time.Time
+}
+
+func NewTokenManager(secret []byte, ttl time.Duration, refreshTTL time.Duration) *TokenManager {
+	return &TokenManager{
+		secret:     secret,
+		TTL:        ttl,
+		RefreshTTL: refreshTTL,
+		now:        time.Now,
+	}
+}
+
+// Issue signs a new token for the generation of the session that expires after the manager's TTL.
+func (m TokenManager) Issue(sessionID, userID string, generation int) (string, TokenClaims, error) {
+	now := m.now()
+	claims := TokenClaims{
+		SessionID:  sessionID,
+		UserID:     userID,
+		Generation: generation,
+		IssuedAt:   now.Unix(),
+		ExpiresAt:  now.Add(m.TTL).Unix(),
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", TokenClaims{}, fmt.Errorf("error encoding the token claims: %w", err)
+	}
+
+	unsigned := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + m.sign(unsigned), claims, nil
+}
+
+// Parse verifies the token's signature and expiry and returns its claims.
+// When the signature is valid but the token has expired, the claims are returned together with ErrTokenExpired.
+func (m TokenManager) Parse(token string) (TokenClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != jwtHeader {
+		return TokenClaims{}, ErrInvalidToken
+	}
+
+	expectedSignature := m.sign(parts[0] + "." + parts[1])
+	if !hmac.Equal([]byte(parts[2]), []byte(expectedSignature)) {
+		return TokenClaims{}, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return TokenClaims{}, ErrInvalidToken
+	}
+
+	var claims TokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return TokenClaims{}, ErrInvalidToken
+	}
+
+	if m.now().Unix() >= claims.ExpiresAt {
+		return claims, ErrTokenExpired
+	}
+	return claims, nil
+}
+
+func (m TokenManager) sign(unsigned string) string {
+	mac := hmac.New(sha256.New, m.secret)
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// BearerToken extracts the token from an "Authorization: Bearer <token>" header value.
+func BearerToken(authorization string) (string, bool) {
+	token, ok := strings.CutPrefix(authorization, "Bearer ")
+	token = strings.TrimSpace(token)
+	return token, ok && token != ""
+}
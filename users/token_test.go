@@ -0,0 +1,42 @@
+package users_test
+
+import (
+	"gifmanager-backend/users"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestTokenManager_IssueAndParse(t *testing.T) {
+	tokens := users.NewTokenManager([]byte("secret"), time.Minute, time.Hour)
+
+	token, issued, err := tokens.Issue("session-id", "user-id", 0)
+	require.Nil(t, err)
+
+	claims, err := tokens.Parse(token)
+	require.Nil(t, err)
+	assert.Equal(t, issued, claims)
+	assert.Equal(t, "session-id", claims.SessionID)
+	assert.Equal(t, "user-id", claims.UserID)
+}
+
+func TestTokenManager_Parse_RejectsForeignAndExpiredTokens(t *testing.T) {
+	tokens := users.NewTokenManager([]byte("secret"), time.Minute, time.Hour)
+	otherTokens := users.NewTokenManager([]byte("other-secret"), time.Minute, time.Hour)
+	expiredTokens := users.NewTokenManager([]byte("secret"), -time.Minute, time.Hour)
+
+	foreignToken, _, err := otherTokens.Issue("session-id", "user-id", 0)
+	require.Nil(t, err)
+	_, err = tokens.Parse(foreignToken)
+	assert.Equal(t, users.ErrInvalidToken, err)
+
+	_, err = tokens.Parse("not.a.token")
+	assert.Equal(t, users.ErrInvalidToken, err)
+
+	expiredToken, _, err := expiredTokens.Issue("session-id", "user-id", 0)
+	require.Nil(t, err)
+	claims, err := tokens.Parse(expiredToken)
+	assert.Equal(t, users.ErrTokenExpired, err)
+	assert.Equal(t, "session-id", claims.SessionID)
+}